@@ -21,15 +21,20 @@ func main() {
 		tailer.WithTail("/var/log/syslog", tailer.WithSyntaxColoring("syslog")),
 	)
 	defer terminal.Close()
+
+	// Bind first, an address in use is reported with what to do about it
+	ln, err := tailer.Listen("127.0.0.1:8080")
+	if err != nil {
+		log.Fatal(err)
+	}
 	server := &http.Server{
-		Addr:    "127.0.0.1:8080",
 		Handler: terminal.Handler("/"),
 	}
 
 	// Start server in goroutine
 	go func() {
-		log.Println("Server starting on :8080")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Println("Server starting on", ln.Addr())
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
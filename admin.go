@@ -0,0 +1,68 @@
+package tailer
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// TailState is the state of a tail reported by AdminHandler
+type TailState struct {
+	Alias  string `json:"alias"`
+	Paused bool   `json:"paused"`
+}
+
+// AdminHandler returns the handler of the operations on the running terminal,
+// apart from Handler so that it can be mounted behind the authentication
+// of the operators only. It routes on the end of the path:
+//
+//	POST .../pause?file=<alias>   pauses reading the file, see PauseTail
+//	POST .../resume?file=<alias>  resumes it, see ResumeTail
+//	GET  .../tails                the tails and whether they are paused
+//
+// Without file, pause and resume apply to all the tails. They respond the tails
+// as GET .../tails does, an unknown alias 404 Not Found.
+func (t Terminal) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var paused bool
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/pause"):
+			paused = true
+		case strings.HasSuffix(r.URL.Path, "/resume"):
+		case strings.HasSuffix(r.URL.Path, "/tails"):
+			t.serveTailStates(w)
+			return
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		aliases := r.URL.Query()["file"]
+		if len(aliases) == 0 {
+			for _, to := range t.tails {
+				aliases = append(aliases, to.Alias)
+			}
+		}
+		for _, alias := range aliases {
+			if err := t.setPaused(alias, paused); err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+		}
+		t.serveTailStates(w)
+	})
+}
+
+func (t Terminal) serveTailStates(w http.ResponseWriter) {
+	states := make([]TailState, len(t.tails))
+	for i, to := range t.tails {
+		states[i] = TailState{Alias: to.Alias, Paused: t.TailPaused(to.Alias)}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(states)
+}
@@ -0,0 +1,104 @@
+package tailer
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// WithAutoColoring colors the lines with the profile of Profiles matching the
+// format of the file, guessed by DetectProfile from its first lines when the
+// tail starts, instead of naming it with WithSyntaxHighlighting. The profile
+// replaces the coloring of the tail like WithColoringProfile, which takes
+// precedence. The ready event reports it (see WithReadyEvent). Only regular
+// files are sampled, the other sources keep their coloring.
+func WithAutoColoring() Option {
+	return func(t *Tail) {
+		t.autoColoring = true
+	}
+}
+
+// autoColoringSample is the number of first lines of the file given to DetectProfile
+const autoColoringSample = 20
+
+// DetectProfile guesses the format of the lines of a log and returns the name
+// of its profile of Profiles: "json" for JSON lines, "syslog", "access" for the
+// common and combined log formats of web servers, "slog-text" for key=value
+// lines with a level or a message, and "level", the keyword coloring,
+// unless most of the non-empty lines are of the same format.
+func DetectProfile(lines []string) string {
+	counts := map[string]int{}
+	n := 0
+	for _, line := range lines {
+		line = strings.TrimSpace(StripAnsiCodes(line))
+		if line == "" {
+			continue
+		}
+		n++
+		if format := lineFormat(line); format != "" {
+			counts[format]++
+		}
+	}
+	for format, count := range counts {
+		if count*2 > n {
+			return format
+		}
+	}
+	return "level"
+}
+
+// accessLogPattern matches the common and combined log formats,
+// e.g. 127.0.0.1 - bob [15/Oct/2026:12:00:00 +0000] "GET / HTTP/1.1" 200 512
+var accessLogPattern = regexp.MustCompile(`^(\S+)( \S+ \S+ )(\[[^\]]+\])( ")([A-Z]+)( [^"]*" )(\d{3})\b`)
+
+// syslogHeaderPattern matches the host and process following the time of a syslog line
+var syslogHeaderPattern = regexp.MustCompile(`^\S+\s+[^\s:\[]+(?:\[\d+\])?:(\s|$)`)
+
+// lineFormat returns the profile name of the format of the line, empty if unknown
+func lineFormat(line string) string {
+	switch {
+	case strings.HasPrefix(line, "{") && json.Valid([]byte(line)):
+		return "json"
+	case accessLogPattern.MatchString(line):
+		return "access"
+	case isSyslogLine(line):
+		return "syslog"
+	case len(slogKeyValuePattern.FindAllStringIndex(line, 2)) == 2 &&
+		(strings.Contains(line, "level=") || strings.Contains(line, "msg=")):
+		return "slog-text"
+	}
+	return ""
+}
+
+// isSyslogLine reports whether the line starts with a time, classic or
+// ISO 8601, followed by a host and a process
+func isSyslogLine(line string) bool {
+	end := len(syslogTimeRegexp.FindString(line))
+	if end == 0 {
+		if loc := isoTimeRegexp.FindStringIndex(line); loc != nil && loc[0] == 0 {
+			end = loc[1]
+		}
+	}
+	return end > 0 && syslogHeaderPattern.MatchString(strings.TrimLeft(line[end:], " "))
+}
+
+// detectColoring applies the profile of the first lines of the open file, see WithAutoColoring
+func (tail *Tail) detectColoring() {
+	sample := make([]byte, binarySampleSize)
+	n, _ := tail.file.ReadAt(sample, 0)
+	lines := strings.Split(string(bytes.Trim(sample[:n], "\x00")), "\n")
+	if n == len(sample) {
+		// the last line may be cut
+		lines = lines[:len(lines)-1]
+	}
+	profile := DetectProfile(lines[:min(len(lines), autoColoringSample)])
+
+	tail.rulesMu.Lock()
+	defer tail.rulesMu.Unlock()
+	tail.autoProfile = profile
+	if tail.coloring == "" {
+		tail.coloring = profile
+		tail.plugins = tail.recolor(tail.plugins)
+	}
+}
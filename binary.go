@@ -0,0 +1,137 @@
+package tailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrBinaryFile is returned by Start for a file that looks binary, see WithBinaryMode
+var ErrBinaryFile = errors.New("not a text file")
+
+// BinaryMode is how a tail handles a file that looks binary, see WithBinaryMode
+type BinaryMode int
+
+const (
+	BinaryReject  BinaryMode = iota // Start fails with ErrBinaryFile
+	BinaryHexdump                   // the bytes are sent as rows of hexdump -C
+)
+
+// WithBinaryMode sets how the tail handles a file that looks binary, see IsBinary,
+// judged on the first bytes of the file when the tail starts, without the zeros
+// of a sparse or preallocated log around them.
+// BinaryReject, the default, makes Start fail with ErrBinaryFile, so that a wrong
+// path does not fill the web terminal with control characters.
+// BinaryHexdump sends the bytes as rows of 16 with their offset, like hexdump -C,
+// the last rows of WithLast and then the appended bytes.
+func WithBinaryMode(mode BinaryMode) Option {
+	return func(t *Tail) {
+		t.binaryMode = mode
+	}
+}
+
+// binarySampleSize is the size of the start of the file read by IsBinary on Start
+const binarySampleSize = 8192
+
+// IsBinary reports whether the sample, the first bytes of a file, looks binary:
+// it contains a NUL byte, or more than 30% of it are control characters,
+// other than tabs, line endings and escape sequences, or invalid UTF-8.
+func IsBinary(sample []byte) bool {
+	garbage := 0
+	for i := 0; i < len(sample); {
+		c := sample[i]
+		switch {
+		case c == 0:
+			return true
+		case c >= utf8.RuneSelf:
+			if !utf8.FullRune(sample[i:]) {
+				// cut by the end of the sample
+				i = len(sample)
+				continue
+			}
+			r, size := utf8.DecodeRune(sample[i:])
+			if r == utf8.RuneError && size == 1 {
+				garbage++
+			}
+			i += size
+			continue
+		case c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f' && c != '\b' && c != 0x1b, c == 0x7f:
+			garbage++
+		}
+		i++
+	}
+	return garbage*10 > len(sample)*3
+}
+
+// sniffBinary reports whether the open file looks binary
+func (tail *Tail) sniffBinary() (bool, error) {
+	sample := make([]byte, binarySampleSize)
+	n, err := tail.file.ReadAt(sample, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("failed to read: %w", err)
+	}
+	// the zeros of a sparse or preallocated log around its text are not binary
+	return IsBinary(bytes.Trim(sample[:n], "\x00")), nil
+}
+
+// hexdumpRowSize is the number of bytes of a row of the hexdump
+const hexdumpRowSize = 16
+
+// seekHexdump positions the tail at the row of the last n rows of the file
+func (tail *Tail) seekHexdump(n int) error {
+	start := max(tail.lastSize-int64(n*hexdumpRowSize), 0)
+	start -= start % hexdumpRowSize
+	if _, err := tail.file.Seek(start, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek: %w", err)
+	}
+	tail.lastPos = start
+	return nil
+}
+
+// readHexdump reads the new bytes of the file as rows of the hexdump,
+// the last row of the bytes read may be short
+func (tail *Tail) readHexdump() {
+	buf := make([]byte, max(tail.readBufSize-tail.readBufSize%hexdumpRowSize, hexdumpRowSize))
+	for {
+		n, err := io.ReadFull(tail.file, buf)
+		for i := 0; i < n; i += hexdumpRowSize {
+			row := buf[i:min(i+hexdumpRowSize, n)]
+			if !tail.emit(HexdumpRow(tail.lastPos, row), tail.lastPos) {
+				return
+			}
+			tail.lastPos += int64(len(row))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// HexdumpRow formats up to 16 bytes at the offset like a row of hexdump -C,
+// e.g. "00000010  48 65 6c 6c 6f 0a 00 00  ...  |Hello...|"
+func HexdumpRow(offset int64, row []byte) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%08x ", offset)
+	for i := 0; i < hexdumpRowSize; i++ {
+		if i%8 == 0 {
+			sb.WriteByte(' ')
+		}
+		if i < len(row) {
+			fmt.Fprintf(&sb, "%02x ", row[i])
+		} else {
+			sb.WriteString("   ")
+		}
+	}
+	sb.WriteString(" |")
+	for _, c := range row {
+		if c < 0x20 || c >= 0x7f {
+			c = '.'
+		}
+		sb.WriteByte(c)
+	}
+	sb.WriteByte('|')
+	return sb.String()
+}
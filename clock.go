@@ -0,0 +1,67 @@
+package tailer
+
+import "time"
+
+// clock is the time source of the read loop and the streams, so that the tests
+// can advance the time of polls and flushes instead of sleeping, see withClock
+type clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) ticker
+	After(d time.Duration) <-chan time.Time
+}
+
+// ticker is the part of time.Ticker used by the read loop and the streams
+type ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock is the clock of the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) ticker {
+	return realTicker{t: time.NewTicker(d)}
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (rt realTicker) C() <-chan time.Time {
+	return rt.t.C
+}
+
+func (rt realTicker) Stop() {
+	rt.t.Stop()
+}
+
+// withClock replaces the time source of the tail, for the tests
+func withClock(c clock) Option {
+	return func(t *Tail) {
+		t.clock = c
+	}
+}
+
+// withTerminalClock replaces the time source of the streams of the terminal
+// and of the tails they start, for the tests
+func withTerminalClock(c clock) TerminalOption {
+	return func(to *Terminal) {
+		to.clock = c
+	}
+}
+
+// clock returns the time source of the streams
+func (h Handler) clock() clock {
+	if h.Terminal.clock == nil {
+		return realClock{}
+	}
+	return h.Terminal.clock
+}
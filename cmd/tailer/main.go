@@ -0,0 +1,124 @@
+// Command tailer serves the web terminal of the tailer package for log files.
+//
+//	tailer -addr :8080 -file /var/log/syslog -theme ubuntu -coloring syslog
+//	tailer -addr :8080 -config terminal.json
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/OutOfBedlam/tailer"
+)
+
+// files is a repeatable -file flag
+type files []string
+
+func (f *files) String() string { return strings.Join(*f, ",") }
+
+func (f *files) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+func main() {
+	var files files
+	addr := flag.String("addr", "127.0.0.1:8080", "address to listen on")
+	iface := flag.String("interface", "", "network interface to listen on, e.g. eth0, replaces the host of -addr")
+	flag.Var(&files, "file", "log file to tail, repeat for multiple files")
+	config := flag.String("config", "", "JSON terminal config file, replaces -file, -theme, -coloring, -font-size, -last and -last-bytes")
+	theme := flag.String("theme", "default", "terminal theme: "+strings.Join(tailer.ThemeNames(), ", "))
+	coloring := flag.String("coloring", "", "comma separated syntax coloring: "+strings.Join(tailer.ProfileNames(), ", "))
+	fontSize := flag.Int("font-size", 12, "terminal font size")
+	last := flag.Int("last", 10, "number of lines to show from the end of the files")
+	lastBytes := flag.Int64("last-bytes", 0, "show the lines of the last bytes of the files instead of -last, like tail -c")
+	certFile := flag.String("cert", "", "TLS certificate file, serves HTTPS with -key")
+	keyFile := flag.String("key", "", "TLS key file")
+	flag.Parse()
+
+	var terminal tailer.Terminal
+	if *config != "" {
+		f, err := os.Open(*config)
+		if err != nil {
+			log.Fatalf("Failed to open config: %v", err)
+		}
+		terminal, err = tailer.NewTerminalFromConfig(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", *config, err)
+		}
+		files = []string{*config}
+	} else {
+		if len(files) == 0 {
+			fmt.Fprintln(os.Stderr, "at least one -file or -config is required")
+			flag.Usage()
+			os.Exit(2)
+		}
+		termTheme, ok := tailer.Themes[*theme]
+		if !ok {
+			log.Fatalf("unknown theme %q", *theme)
+		}
+
+		tailOpts := []tailer.Option{tailer.WithLast(*last)}
+		if *lastBytes > 0 {
+			tailOpts = append(tailOpts, tailer.WithTailBytes(*lastBytes))
+		}
+		if *coloring != "" {
+			tailOpts = append(tailOpts, tailer.WithSyntaxHighlighting(strings.Split(*coloring, ",")...))
+		}
+		termOpts := []tailer.TerminalOption{
+			tailer.WithFontSize(*fontSize),
+			tailer.WithTheme(termTheme),
+		}
+		for _, file := range files {
+			termOpts = append(termOpts, tailer.WithTail(file, tailOpts...))
+		}
+		terminal = tailer.NewTerminal(termOpts...)
+	}
+
+	var listenOpts []tailer.ServerOption
+	if *iface != "" {
+		listenOpts = append(listenOpts, tailer.WithInterface(*iface))
+	}
+	ln, err := tailer.Listen(*addr, listenOpts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server := &http.Server{
+		Handler: terminal.Handler("/"),
+	}
+
+	go func() {
+		log.Printf("Serving %s on %s", strings.Join(files, ", "), ln.Addr())
+		var err error
+		if *certFile != "" {
+			err = server.ServeTLS(ln, *certFile, *keyFile)
+		} else {
+			err = server.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	// end the open streams first, Shutdown waits for them
+	terminal.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Fatalf("Server forced to shutdown: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package tailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// RestartPolicy tells whether a command followed by WithCommand is run again when it exits
+type RestartPolicy int
+
+const (
+	RestartNever     RestartPolicy = iota // the tail receives no more lines, until Stop
+	RestartOnFailure                      // run again if it exits with an error or a non-zero status
+	RestartAlways                         // run again whenever it exits
+)
+
+// DefaultRestartDelay is the delay before a command is run again, see WithRestart
+const DefaultRestartDelay = time.Second
+
+// commandState is the command followed by WithCommand
+type commandState struct {
+	ctx  context.Context
+	name string
+	args []string
+	mu   sync.Mutex // guards cmd and the file of the tail while a command runs
+	cmd  *exec.Cmd  // the running process, nil between runs
+}
+
+// NewCommand creates a Tail following the combined stdout and stderr of the command,
+// labeled with its name, e.g. NewCommand("ping", "example.com").
+// Use New with WithCommand or WithCommandContext to set other options,
+// such as WithRestart.
+func NewCommand(name string, args ...string) ITail {
+	return New(name, WithLabel(name), WithCommand(name, args...))
+}
+
+// startCommand checks that the command can be run, so that Start reports it
+func (tail *Tail) startCommand() error {
+	if _, err := exec.LookPath(tail.command.name); err != nil {
+		return fmt.Errorf("failed to start command: %w", err)
+	}
+	tail.wg.Add(1)
+	go tail.runCommand()
+	return nil
+}
+
+// runCommand runs the command and sends its lines, then runs it again
+// as the restart policy tells, until Stop or the context is done.
+// The exit error of the last run is reported by Stats.
+func (tail *Tail) runCommand() {
+	defer tail.wg.Done()
+	cs := tail.command
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-cs.ctx.Done():
+			tail.interruptCommand()
+		case <-done:
+		}
+	}()
+
+	for {
+		err := tail.runCommandOnce()
+		if tail.isStopped() || cs.ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			msg := err.Error()
+			tail.statErr.Store(&msg)
+		} else {
+			tail.statErr.Store(nil)
+		}
+		if tail.restart == RestartNever || (tail.restart == RestartOnFailure && err == nil) {
+			break
+		}
+		// the next run checks whether the tail was stopped meanwhile
+		select {
+		case <-tail.stopChan:
+		case <-cs.ctx.Done():
+		case <-time.After(tail.restartDelay):
+		}
+	}
+	tail.sendTrailing()
+}
+
+// runCommandOnce runs the command until it exits and its output is read,
+// the output of a run ends with its last line even without a newline
+func (tail *Tail) runCommandOnce() error {
+	cs := tail.command
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create pipe: %w", err)
+	}
+	cmd := exec.Command(cs.name, cs.args...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	setProcessGroup(cmd)
+
+	cs.mu.Lock()
+	if tail.isStopped() || cs.ctx.Err() != nil {
+		cs.mu.Unlock()
+		r.Close()
+		w.Close()
+		return nil
+	}
+	err = cmd.Start()
+	// the pipe is at its end once the process and its children closed it
+	w.Close()
+	if err != nil {
+		cs.mu.Unlock()
+		r.Close()
+		return fmt.Errorf("failed to start command: %w", err)
+	}
+	cs.cmd = cmd
+	tail.file = r
+	cs.mu.Unlock()
+
+	// blocks until the output is closed or interruptCommand closes the pipe
+	tail.readLines()
+	err = cmd.Wait()
+
+	cs.mu.Lock()
+	cs.cmd = nil
+	tail.file = nil
+	cs.mu.Unlock()
+	r.Close()
+
+	if tail.flushPartial() {
+		tail.flushPending()
+	}
+	return err
+}
+
+// interruptCommand kills the running process with its children
+// and closes its output, unblocking runCommandOnce
+func (tail *Tail) interruptCommand() {
+	cs := tail.command
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.cmd != nil {
+		killProcess(cs.cmd)
+		tail.file.Close()
+	}
+}
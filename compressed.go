@@ -0,0 +1,75 @@
+package tailer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// compressedState tracks a gzip compressed file followed by WithCompressedPoll
+type compressedState struct {
+	lines   int // number of lines sent
+	size    int64
+	modTime time.Time
+}
+
+// readCompressed decompresses the whole file and sends the lines
+// beyond those already sent. If lastN is not negative, only the
+// last lastN lines are sent, which is used on Start().
+func (tail *Tail) readCompressed(lastN int) error {
+	file, err := openFileShared(tail.filepath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	st := tail.compressed
+	if lastN < 0 && stat.Size() == st.size && stat.ModTime().Equal(st.modTime) {
+		return nil
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to decompress: %w", err)
+	}
+	defer gz.Close()
+
+	var lines []string
+	br := bufio.NewReader(gz)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			// an incomplete last line or a truncated stream is read on the next poll
+			break
+		}
+		line = strings.TrimSuffix(line, "\n")
+		lines = append(lines, strings.TrimSuffix(line, "\r"))
+	}
+
+	var newLines []string
+	switch {
+	case lastN >= 0:
+		newLines = lines[max(0, len(lines)-lastN):]
+	case len(lines) < st.lines:
+		// replaced by another file
+		newLines = lines
+	default:
+		newLines = lines[st.lines:]
+	}
+	st.lines = len(lines)
+	st.size = stat.Size()
+	st.modTime = stat.ModTime()
+
+	for _, line := range newLines {
+		if !tail.emit(line, -1) {
+			return nil
+		}
+	}
+	return nil
+}
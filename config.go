@@ -0,0 +1,119 @@
+package tailer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Config describes a terminal and its tails, see NewTerminalFromConfig.
+// The zero values keep the defaults of NewTerminal.
+type Config struct {
+	FontSize   int          `json:"fontSize,omitempty"`
+	FontFamily string       `json:"fontFamily,omitempty"`
+	Scrollback int          `json:"scrollback,omitempty"`
+	Theme      string       `json:"theme,omitempty"` // name of Themes
+	BasePath   string       `json:"basePath,omitempty"`
+	Tails      []TailConfig `json:"tails"`
+}
+
+// TailConfig describes a tail of a Config
+type TailConfig struct {
+	Path      string     `json:"path"`
+	Label     string     `json:"label,omitempty"`    // the base name of the path if empty
+	Coloring  []string   `json:"coloring,omitempty"` // names of Profiles
+	Patterns  [][]string `json:"patterns,omitempty"` // groups of WithPattern
+	Last      int        `json:"last,omitempty"`
+	LastBytes int64      `json:"lastBytes,omitempty"` // WithTailBytes, replaces last
+}
+
+// NewTerminalFromConfig returns a terminal configured by the JSON Config read from r,
+// built with the same options as NewTerminal. Unknown fields, themes and
+// coloring profiles, invalid patterns and tails without a path are errors.
+//
+//	{"fontSize": 14, "theme": "dracula", "tails": [{"path": "/var/log/syslog", "coloring": ["syslog"]}]}
+func NewTerminalFromConfig(r io.Reader) (Terminal, error) {
+	var cfg Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Terminal{}, fmt.Errorf("invalid config: %w", err)
+	}
+	opts, err := cfg.options()
+	if err != nil {
+		return Terminal{}, err
+	}
+	return NewTerminal(opts...), nil
+}
+
+// options returns the terminal options of the config
+func (cfg Config) options() ([]TerminalOption, error) {
+	var opts []TerminalOption
+	if cfg.FontSize > 0 {
+		opts = append(opts, WithFontSize(cfg.FontSize))
+	}
+	if cfg.FontFamily != "" {
+		opts = append(opts, WithFontFamily(cfg.FontFamily))
+	}
+	if cfg.Scrollback > 0 {
+		opts = append(opts, WithScrollback(cfg.Scrollback))
+	}
+	if cfg.Theme != "" {
+		theme, ok := Themes[cfg.Theme]
+		if !ok {
+			return nil, fmt.Errorf("unknown theme %q, expected one of %s", cfg.Theme, strings.Join(ThemeNames(), ", "))
+		}
+		opts = append(opts, WithTheme(theme))
+	}
+	if cfg.BasePath != "" {
+		opts = append(opts, WithBasePath(cfg.BasePath))
+	}
+	if len(cfg.Tails) == 0 {
+		return nil, fmt.Errorf("no tails configured")
+	}
+	for i, tc := range cfg.Tails {
+		tailOpts, err := tc.options()
+		if err != nil {
+			return nil, fmt.Errorf("tails[%d]: %w", i, err)
+		}
+		if tc.Label != "" {
+			opts = append(opts, WithTailLabel(tc.Label, tc.Path, tailOpts...))
+		} else {
+			opts = append(opts, WithTail(tc.Path, tailOpts...))
+		}
+	}
+	return opts, nil
+}
+
+// options returns the tail options of the config
+func (tc TailConfig) options() ([]Option, error) {
+	if tc.Path == "" {
+		return nil, fmt.Errorf("missing path")
+	}
+	var opts []Option
+	for _, syntax := range tc.Coloring {
+		if _, ok := Profiles[strings.ToLower(syntax)]; !ok {
+			return nil, fmt.Errorf("unknown coloring %q, expected one of %s", syntax, strings.Join(ProfileNames(), ", "))
+		}
+	}
+	if len(tc.Coloring) > 0 {
+		opts = append(opts, WithSyntaxHighlighting(tc.Coloring...))
+	}
+	for _, group := range tc.Patterns {
+		for _, pattern := range group {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+		opts = append(opts, WithPattern(group...))
+	}
+	if tc.Last > 0 {
+		opts = append(opts, WithLast(tc.Last))
+	}
+	if tc.LastBytes > 0 {
+		opts = append(opts, WithTailBytes(tc.LastBytes))
+	}
+	return opts, nil
+}
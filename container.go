@@ -0,0 +1,75 @@
+package tailer
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// containerReopenPolls is the number of polls after which the path is reopened in container mode
+var containerReopenPolls = 10
+
+// containerHeadSize is the number of first bytes compared to tell a new file from the same one
+const containerHeadSize = 256
+
+// containerRotated tells whether the path is another file than the open one
+// in container mode. The path is reopened when the inode, the size or the
+// modification time of the open file and the path disagree, and every
+// containerReopenPolls polls. If their first bytes match it is the same file,
+// which continues at the same position on the new descriptor.
+func (tail *Tail) containerRotated(stat os.FileInfo, inodeChanged bool) bool {
+	tail.containerPolls++
+	suspect := inodeChanged || tail.containerPolls >= containerReopenPolls
+	if !suspect {
+		open, err := tail.file.Stat()
+		suspect = err != nil || open.Size() != stat.Size() || !open.ModTime().Equal(stat.ModTime())
+	}
+	if !suspect {
+		return false
+	}
+	tail.containerPolls = 0
+
+	file, err := openFileShared(tail.filepath)
+	if err != nil {
+		// the next poll fails to stat the path or finds it again
+		return inodeChanged
+	}
+	if !sameHead(tail.file, file) {
+		file.Close()
+		return true
+	}
+	// a truncation of the same file is detected by its size as usual
+	tail.file.Close()
+	tail.file = file
+	tail.lastInode = getInode(stat)
+	return false
+}
+
+// sameHead tells whether a and b start with the same bytes. An empty file
+// is not the same as one with content, and if a can not be read anymore,
+// e.g. a stale handle, b is taken as the same file.
+func sameHead(a, b *os.File) bool {
+	headA, err := readHead(a)
+	if err != nil {
+		return true
+	}
+	headB, err := readHead(b)
+	if err != nil {
+		return false
+	}
+	if (len(headA) == 0) != (len(headB) == 0) {
+		return false
+	}
+	n := min(len(headA), len(headB))
+	return bytes.Equal(headA[:n], headB[:n])
+}
+
+// readHead reads the first containerHeadSize bytes of the file, or less if it is shorter
+func readHead(f *os.File) ([]byte, error) {
+	buf := make([]byte, containerHeadSize)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf[:n], nil
+}
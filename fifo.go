@@ -0,0 +1,85 @@
+package tailer
+
+import (
+	"os"
+	"time"
+)
+
+// isFIFO reports whether the path is a named pipe
+func isFIFO(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && stat.Mode()&os.ModeNamedPipe != 0
+}
+
+// runFIFO follows a named pipe with blocking reads instead of polling.
+// A pipe has no offsets, nothing to rotate or truncate:
+// the lines are read as they are written, and when the writer closes the pipe
+// its partial last line is complete and the next writer is awaited.
+func (tail *Tail) runFIFO() {
+	defer tail.wg.Done()
+	for !tail.isStopped() {
+		// blocks until a writer opens the pipe
+		file, err := openFileShared(tail.filepath)
+		if err != nil {
+			select {
+			case <-tail.stopChan:
+				return
+			case <-time.After(tail.pollInterval):
+				continue
+			}
+		}
+		tail.fifoMu.Lock()
+		tail.file = file
+		tail.fifoMu.Unlock()
+
+		if !tail.isStopped() {
+			// blocks until the writer closes the pipe or Stop closes the file
+			tail.readLines()
+		}
+
+		tail.fifoMu.Lock()
+		tail.file = nil
+		tail.fifoMu.Unlock()
+		file.Close()
+
+		if tail.isStopped() {
+			break
+		}
+		// the writer closed the pipe, its last record is complete
+		if tail.flushPartial() {
+			tail.flushPending()
+		}
+	}
+	tail.sendTrailing()
+}
+
+// interruptFIFO unblocks runFIFO on Stop, closing the pipe being read
+// or opening and closing the pipe for writing if it waits for a writer.
+func (tail *Tail) interruptFIFO() {
+	tail.fifoMu.Lock()
+	file := tail.file
+	tail.fifoMu.Unlock()
+	if file != nil {
+		file.Close()
+		return
+	}
+	unblockFIFO(tail.filepath)
+}
+
+// waitFIFO interrupts runFIFO until it returns,
+// Stop may run before runFIFO reaches the blocking open.
+func (tail *Tail) waitFIFO() {
+	done := make(chan struct{})
+	go func() {
+		tail.wg.Wait()
+		close(done)
+	}()
+	for {
+		tail.interruptFIFO()
+		select {
+		case <-done:
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package tailer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LineCount selects how the lines of the files are counted for the ready event, see WithReadyEvent
+type LineCount int
+
+const (
+	NoLineCount        LineCount = iota // only the sizes are reported
+	EstimatedLineCount                  // estimated by EstimateLines
+	ExactLineCount                      // counted by CountLines, reading the whole files
+)
+
+// FileInfo describes a followed file when a stream starts
+type FileInfo struct {
+	File      string    `json:"file"`
+	Path      string    `json:"path"` // the absolute path of the file open, symlinks resolved
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mtime"`
+	Inode     uint64    `json:"inode,omitempty"` // on Unix
+	Lines     int64     `json:"lines,omitempty"`
+	Estimated bool      `json:"estimated,omitempty"` // Lines is approximate
+	Start     int64     `json:"start,omitempty"`     // offset the lines are sent from, see WithTailBytes
+	Coloring  string    `json:"coloring,omitempty"`  // the profile detected by WithAutoColoring
+}
+
+// lineSampleSize is the size of the block EstimateLines samples at the end of a file
+var lineSampleSize int64 = 64 * 1024
+
+// EstimateLines estimates the number of lines of a file from the average length
+// of the lines in a block at its end, without reading the whole file.
+// Files not larger than the block are counted exactly, estimated is false.
+func EstimateLines(path string) (lines int64, estimated bool, err error) {
+	file, err := openFileShared(path)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to stat file: %w", err)
+	}
+	size := stat.Size()
+	if size <= lineSampleSize {
+		lines, err = countLines(file)
+		return lines, false, err
+	}
+	buf := make([]byte, lineSampleSize)
+	if _, err := file.ReadAt(buf, size-lineSampleSize); err != nil && err != io.EOF {
+		return 0, false, fmt.Errorf("failed to read: %w", err)
+	}
+	newlines := int64(bytes.Count(buf, []byte{'\n'}))
+	if newlines == 0 {
+		// a single line longer than the block
+		return 1, true, nil
+	}
+	return size * newlines / lineSampleSize, true, nil
+}
+
+// CountLines counts the lines of a file, a last line without newline included.
+// It reads the whole file.
+func CountLines(path string) (int64, error) {
+	file, err := openFileShared(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	return countLines(file)
+}
+
+func countLines(r io.Reader) (int64, error) {
+	buf := make([]byte, 64*1024)
+	var lines int64
+	last := byte('\n')
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read: %w", err)
+		}
+	}
+	if last != '\n' {
+		lines++
+	}
+	return lines, nil
+}
+
+// followedTails returns the tails of the files followed by tail
+func followedTails(tail ITail) []*Tail {
+	var tails []*Tail
+	switch t := tail.(type) {
+	case *Tail:
+		tails = []*Tail{t}
+	case *MultiTail:
+		for _, sub := range t.tails {
+			if st, ok := sub.(*Tail); ok {
+				tails = append(tails, st)
+			}
+		}
+	}
+	return tails
+}
+
+// fileOpens returns the number of files opened by the tails of tail,
+// it changes when one of them is rotated or switched to another file
+func fileOpens(tail ITail) int64 {
+	var opens int64
+	for _, t := range followedTails(tail) {
+		opens += t.statOpens.Load()
+	}
+	return opens
+}
+
+// fileInfos describes the files followed by tail, files that can not be
+// read are left out. Only regular files have their lines counted.
+func fileInfos(tail ITail, count LineCount) []FileInfo {
+	infos := []FileInfo{}
+	for _, t := range followedTails(tail) {
+		// the path and the profile change on the run goroutine
+		open := t.statFile.Load()
+		if open == nil {
+			// not started
+			continue
+		}
+		path := *open
+		t.rulesMu.RLock()
+		profile := t.autoProfile
+		t.rulesMu.RUnlock()
+		stat, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		info := FileInfo{
+			File:     StripAnsiCodes(t.label),
+			Path:     absPath(path),
+			Size:     stat.Size(),
+			ModTime:  stat.ModTime(),
+			Inode:    inodeOf(stat),
+			Start:    t.statStart.Load(),
+			Coloring: profile,
+		}
+		if info.File == "" {
+			info.File = path
+		}
+		if stat.Mode().IsRegular() {
+			switch count {
+			case EstimatedLineCount:
+				info.Lines, info.Estimated, _ = EstimateLines(path)
+			case ExactLineCount:
+				info.Lines, _ = CountLines(path)
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// absPath returns the absolute path of the file, symlinks resolved,
+// or path if it can not be resolved
+func absPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
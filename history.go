@@ -0,0 +1,85 @@
+package tailer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+)
+
+// RotatedFiles returns the rotated siblings of filename in chronological order,
+// oldest first, following the logrotate naming scheme
+// (e.g. app.log.3.gz, app.log.2.gz, app.log.1, for app.log),
+// with dateext too (e.g. app.log-20261014.gz, app.log-20261015).
+// The live file itself is not included. See LogrotateScheme.
+func RotatedFiles(filename string) []string {
+	_, archives := LogrotateScheme().Files(filename)
+	return archives
+}
+
+// readHistory streams the archived files and then the live file
+// from its beginning, so that the following polls continue
+// exactly where the history ends.
+// It returns false if the tail was stopped.
+func (tail *Tail) readHistory() bool {
+	for _, name := range tail.history {
+		if !tail.readArchive(name) {
+			return false
+		}
+	}
+	if _, err := tail.file.Seek(0, io.SeekStart); err != nil {
+		return true
+	}
+	tail.lastPos = 0
+	tail.readLines()
+	tail.lastSize = tail.lastPos
+	return !tail.isStopped()
+}
+
+// readArchive sends all lines of a rotated file, decompressing it if needed.
+// Unreadable archives are skipped.
+func (tail *Tail) readArchive(name string) bool {
+	f, err := os.Open(name)
+	if err != nil {
+		return !tail.isStopped()
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return !tail.isStopped()
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return tail.readAllLines(r)
+}
+
+// readAllLines sends every line of r until EOF.
+// It returns false if the tail was stopped.
+func (tail *Tail) readAllLines(r io.Reader) bool {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && line == "" {
+			return !tail.isStopped()
+		}
+		line = strings.TrimSuffix(line, "\n")
+		line = strings.TrimSuffix(line, "\r")
+		if !tail.emit(line, -1) {
+			return false
+		}
+	}
+}
+
+func (tail *Tail) isStopped() bool {
+	select {
+	case <-tail.stopChan:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,57 @@
+package tailer
+
+import (
+	"path/filepath"
+	"regexp"
+)
+
+// kubePodsDir is where the kubelet writes the logs of the pods of the node
+var kubePodsDir = "/var/log/pods"
+
+// NewKubePodLog creates Tail instance that follows the log of a container of a pod
+// scheduled on this node, from the files the kubelet writes in
+// /var/log/pods/<namespace>_<pod>_<uid>/<container>/<restart>.log.
+// It runs in container mode (see WithContainerMode) for the rotations of the
+// kubelet, 0.log renamed to 0.log.<timestamp>, and follows the file of the
+// latest restart of the container. The CRI prefix of the lines, the time,
+// the stream and the tag, is removed like kubectl logs does, see CRIMessage.
+// The process needs read access to the directory, e.g. a DaemonSet mounting
+// it from the host; the logs of the pods on other nodes are not available.
+func NewKubePodLog(namespace, pod, container string, opts ...Option) ITail {
+	path, opt := kubePodLog(namespace, pod, container)
+	return New(path, append([]Option{WithLabel(namespace + "/" + pod + "/" + container), opt}, opts...)...)
+}
+
+// WithKubePodLog adds a tail of the log of a container of a pod to the terminal,
+// labeled "<namespace>/<pod>/<container>", see NewKubePodLog.
+func WithKubePodLog(namespace, pod, container string, opts ...Option) TerminalOption {
+	path, opt := kubePodLog(namespace, pod, container)
+	return WithTailLabel(namespace+"/"+pod+"/"+container, path, append([]Option{opt}, opts...)...)
+}
+
+// kubePodLog returns the pattern of the log files of the container
+// and the option following the latest one in container mode
+func kubePodLog(namespace, pod, container string) (string, Option) {
+	dir := kubePodsDir
+	// the uid of the pod is not known, the restarts are numbered
+	pattern := filepath.Join(namespace+"_"+pod+"_*", container, "*.log")
+	return filepath.Join(dir, pattern), func(t *Tail) {
+		t.container = true
+		t.resolve = latestResolver(dir, pattern)
+		t.transforms = append(t.transforms, CRIMessage)
+	}
+}
+
+// criLineRegexp matches the CRI log format: time, stream, tag (F for a full line,
+// P for a part of a long one) and the message
+var criLineRegexp = regexp.MustCompile(`^\S+ (?:stdout|stderr) [FP] `)
+
+// CRIMessage returns the message of a line of the CRI log format written by
+// the kubelet, e.g. "2026-10-14T12:00:00.000000001Z stdout F listening on :8080"
+// is "listening on :8080". Other lines are returned as they are.
+func CRIMessage(line string) string {
+	if loc := criLineRegexp.FindStringIndex(line); loc != nil {
+		return line[loc[1]:]
+	}
+	return line
+}
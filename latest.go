@@ -0,0 +1,75 @@
+package tailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// NewLatest creates Tail instance that follows the most recently modified file
+// in dir whose name matches the pattern (see filepath.Match),
+// e.g. NewLatest("/var/log/app", "app-*.log").
+// When a newer file appears, the remaining lines of the current file are read
+// before switching to the new one from its beginning. The files switched from
+// are never followed again, even when they are modified later.
+// It is useful for rotation schemes that create timestamped files instead of renaming.
+func NewLatest(dir string, pattern string, opts ...Option) ITail {
+	tail := New(filepath.Join(dir, pattern), append([]Option{WithLabel(pattern)}, opts...)...).(*Tail)
+	tail.resolve = latestResolver(dir, pattern)
+	return tail
+}
+
+// latestResolver returns the resolve function of a tail following the latest file
+// in dir matching the pattern. It remembers the files switched from and never
+// returns them again, so that touching an older file does not read it again.
+func latestResolver(dir string, pattern string) func() (string, error) {
+	var current string
+	var currentInfo os.FileInfo
+	finished := map[string]os.FileInfo{}
+	return func() (string, error) {
+		// a finished file removed since, its inode may be reused by a new file
+		for path, info := range finished {
+			if fi, err := os.Stat(path); err != nil || !os.SameFile(fi, info) {
+				delete(finished, path)
+			}
+		}
+		latest, info, err := latestFile(dir, pattern, finished)
+		if err != nil {
+			return "", err
+		}
+		if currentInfo != nil && !os.SameFile(currentInfo, info) {
+			finished[current] = currentInfo
+		}
+		current, currentInfo = latest, info
+		return latest, nil
+	}
+}
+
+// latestFile returns the most recently modified regular file in dir matching the pattern,
+// but the finished ones. If modification times are equal, the lexically greater name wins.
+func latestFile(dir string, pattern string, finished map[string]os.FileInfo) (string, os.FileInfo, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	var latest string
+	var latestInfo os.FileInfo
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if old, ok := finished[m]; ok && os.SameFile(old, info) {
+			continue
+		}
+		if latestInfo == nil ||
+			info.ModTime().After(latestInfo.ModTime()) ||
+			(info.ModTime().Equal(latestInfo.ModTime()) && m > latest) {
+			latest, latestInfo = m, info
+		}
+	}
+	if latest == "" {
+		return "", nil, fmt.Errorf("no file matches %q in %s", pattern, dir)
+	}
+	return latest, latestInfo, nil
+}
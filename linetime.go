@@ -0,0 +1,77 @@
+package tailer
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WithReplayDuration sends the lines of the last d on Start, judged by the
+// timestamps of the lines (see ParseLineTime), instead of the last lines of WithLast,
+// e.g. all the lines of the last 5 minutes for an incident. The lines without
+// a timestamp between them, like the continuations of a stack trace, are sent too.
+// At most 100000 lines are sent. If no line has a timestamp, the last lines
+// of WithLast are sent instead. It applies to regular files, and WithHistory,
+// WithTailBytes, WithResumeAfter and WithLiveOnly take precedence.
+func WithReplayDuration(d time.Duration) Option {
+	return func(t *Tail) {
+		t.replaySince = d
+	}
+}
+
+// maxReplayLines is the most lines sent by WithReplayDuration
+const maxReplayLines = 100000
+
+// isoTimeRegexp matches an ISO 8601 time like 2026-10-15T12:00:00.123Z,
+// 2026-10-15 12:00:00,123 or 2026/10/15 12:00:00 +0900
+var isoTimeRegexp = regexp.MustCompile(`(\d{4})[-/](\d{2})[-/](\d{2})[T ](\d{2}:\d{2}:\d{2})([.,]\d+)? ?(Z|[+-]\d{2}:?\d{2})?`)
+
+// syslogTimeRegexp matches the time of a syslog line like "Oct  5 12:00:00"
+var syslogTimeRegexp = regexp.MustCompile(`^[A-Z][a-z]{2} [ 0-9]\d \d{2}:\d{2}:\d{2}`)
+
+// lineTimePrefix is how far in the line ParseLineTime looks for an ISO 8601 time,
+// enough for a level, a label or a JSON key before it
+const lineTimePrefix = 64
+
+// ParseLineTime returns the time of a log line: an ISO 8601 time in the first
+// 64 bytes of the line, e.g. of RFC 3339, slog or JSON lines, or the time
+// starting a syslog line. Times without a zone are local, and a syslog time
+// is of the current year, or of the previous one if it would be in the future.
+// The color codes of the line are ignored.
+func ParseLineTime(line string) (time.Time, bool) {
+	line = StripAnsiCodes(line)
+	if m := syslogTimeRegexp.FindString(line); m != "" {
+		ts, err := time.ParseInLocation(time.Stamp, m, time.Local)
+		if err != nil {
+			return time.Time{}, false
+		}
+		now := time.Now()
+		ts = ts.AddDate(now.Year(), 0, 0)
+		if ts.After(now.Add(24 * time.Hour)) {
+			ts = ts.AddDate(-1, 0, 0)
+		}
+		return ts, true
+	}
+	if len(line) > lineTimePrefix {
+		line = line[:lineTimePrefix]
+	}
+	m := isoTimeRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return time.Time{}, false
+	}
+	value := m[1] + "-" + m[2] + "-" + m[3] + "T" + m[4] + strings.Replace(m[5], ",", ".", 1)
+	switch zone := m[6]; {
+	case zone == "":
+		ts, err := time.ParseInLocation("2006-01-02T15:04:05.999999999", value, time.Local)
+		return ts, err == nil
+	case zone == "Z":
+		value += "Z"
+	case len(zone) == 5:
+		// +0900
+		value += zone[:3] + ":" + zone[3:]
+	default:
+		value += zone
+	}
+	ts, err := time.Parse(time.RFC3339Nano, value)
+	return ts, err == nil
+}
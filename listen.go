@@ -0,0 +1,111 @@
+package tailer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// WithInterface listens on the address of the network interface with the name,
+// e.g. "eth0" or "tailscale0", instead of the host of the address.
+// Its first IPv4 address is used, or its first IPv6 address if it has none.
+func WithInterface(name string) ServerOption {
+	return func(c *serverConfig) {
+		c.iface = name
+	}
+}
+
+// ListenAndServe serves the terminal at the root path over HTTP.
+// Unlike http.ListenAndServe, the errors of binding the address tell
+// what to do about them, see Listen.
+// Like http.ListenAndServe it always returns a non-nil error.
+func ListenAndServe(addr string, t *Terminal, opts ...ServerOption) error {
+	ln, err := Listen(addr, opts...)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: t.Handler("/")}
+	return server.Serve(ln)
+}
+
+// Listen listens on the TCP address, "host:port" as for net.Listen, for a server
+// of its own, e.g. to shut it down gracefully. The address is validated first,
+// and an address in use, a port needing privileges or an address of another host
+// are reported with what to do about them, wrapping the error of the system.
+// The options apply as for ListenAndServe, see WithInterface.
+func Listen(addr string, opts ...ServerOption) (net.Listener, error) {
+	cfg := &serverConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q, expected host:port like 127.0.0.1:8080 or :8080: %w", addr, err)
+	}
+	if _, err := net.LookupPort("tcp", port); err != nil {
+		return nil, fmt.Errorf("invalid port %q of address %q, expected a number from 0 to 65535: %w", port, addr, err)
+	}
+	if cfg.iface != "" {
+		if host, err = interfaceAddr(cfg.iface); err != nil {
+			return nil, err
+		}
+		addr = net.JoinHostPort(host, port)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	switch {
+	case err == nil:
+		return ln, nil
+	case errors.Is(err, syscall.EADDRINUSE):
+		return nil, fmt.Errorf("address %s is already in use by another process, stop it or choose another port: %w", addr, err)
+	case errors.Is(err, os.ErrPermission):
+		return nil, fmt.Errorf("permission denied to listen on %s, ports below 1024 need root or the CAP_NET_BIND_SERVICE capability, or choose a port above 1023: %w", addr, err)
+	case errors.Is(err, syscall.EADDRNOTAVAIL):
+		return nil, fmt.Errorf("address %s is not an address of this host, use one of its interfaces or an empty host for all of them: %w", addr, err)
+	}
+	return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+}
+
+// interfaceAddr returns the address to listen on for the network interface with the name
+func interfaceAddr(name string) (string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		var names []string
+		if ifaces, err := net.Interfaces(); err == nil {
+			for _, i := range ifaces {
+				names = append(names, i.Name)
+			}
+		}
+		return "", fmt.Errorf("unknown network interface %q, the interfaces are %s: %w", name, strings.Join(names, ", "), err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the addresses of network interface %q: %w", name, err)
+	}
+	var ipv6 string
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip4 := ipnet.IP.To4(); ip4 != nil {
+			return ip4.String(), nil
+		}
+		if ipv6 == "" {
+			ipv6 = ipnet.IP.String()
+			if ipnet.IP.IsLinkLocalUnicast() {
+				// a link-local address is only meaningful with its zone
+				ipv6 += "%" + name
+			}
+		}
+	}
+	if ipv6 == "" {
+		return "", fmt.Errorf("network interface %q has no address, is it up?", name)
+	}
+	return ipv6, nil
+}
@@ -0,0 +1,217 @@
+package tailer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Multiplexer serves several terminals under one handler, each under the
+// subpath of its name, with an index page linking to them.
+//
+//	mux := tailer.NewMultiplexer()
+//	mux.Add("api", apiTerminal)     // served at /logs/api/
+//	mux.Add("worker", workerTerminal) // served at /logs/worker/
+//	http.Handle("/logs/", mux.Handler("/logs/"))
+type Multiplexer struct {
+	// BasePath is the public URL of the multiplexer when it is mounted under
+	// a prefix the requests do not carry, e.g. behind a proxy, see WithBasePath.
+	// The terminals get it followed by their names as their base paths.
+	BasePath string
+
+	entries []muxEntry
+}
+
+type muxEntry struct {
+	name     string
+	terminal Terminal
+}
+
+// MuxEntry describes a terminal of a Multiplexer on its index page
+// and in the terminals.json status it refreshes from.
+type MuxEntry struct {
+	Name   string   `json:"name"`
+	Files  []string `json:"files"`
+	Active bool     `json:"active"` // a file was written within its active window, see WithActiveWindow
+}
+
+// muxNameRegexp restricts the names to a single safe path segment
+var muxNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// NewMultiplexer returns an empty Multiplexer, see Add.
+func NewMultiplexer() *Multiplexer {
+	return &Multiplexer{}
+}
+
+// Add serves the terminal under the subpath name, made of letters,
+// digits, '-' and '_'. The terminals are listed in the order they were added.
+func (m *Multiplexer) Add(name string, t Terminal) error {
+	if !muxNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid terminal name %q", name)
+	}
+	for _, e := range m.entries {
+		if e.name == name {
+			return fmt.Errorf("duplicate terminal name %q", name)
+		}
+	}
+	m.entries = append(m.entries, muxEntry{name: name, terminal: t})
+	return nil
+}
+
+// Close closes all the terminals, see Terminal.Close.
+func (m *Multiplexer) Close() {
+	for _, e := range m.entries {
+		e.terminal.Close()
+	}
+}
+
+// Handler returns the handler serving the index page at cutPrefix
+// and each terminal at cutPrefix followed by its name.
+func (m *Multiplexer) Handler(cutPrefix string) http.Handler {
+	prefix := strings.TrimSuffix(cutPrefix, "/")
+	mh := muxHandler{prefix: prefix, mux: m, handlers: map[string]Handler{}}
+	for _, e := range m.entries {
+		t := e.terminal
+		if m.BasePath != "" {
+			base := m.BasePath
+			if !strings.HasSuffix(base, "/") {
+				base += "/"
+			}
+			t.BasePath = base + e.name + "/"
+		}
+		mh.handlers[e.name] = t.Handler(prefix + "/" + e.name)
+	}
+	return mh
+}
+
+type muxHandler struct {
+	prefix   string
+	mux      *Multiplexer
+	handlers map[string]Handler
+}
+
+func (mh muxHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rel := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, mh.prefix), "/")
+	switch rel {
+	case "":
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		}
+		mh.serveIndex(w)
+		return
+	case "terminals.json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		json.NewEncoder(w).Encode(mh.mux.entryInfos())
+		return
+	}
+	name, _, hasSlash := strings.Cut(rel, "/")
+	handler, ok := mh.handlers[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if !hasSlash {
+		// the page resolves its assets and stream relative to the directory
+		target := r.URL.Path + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+func (mh muxHandler) serveIndex(w http.ResponseWriter) {
+	base := mh.mux.BasePath
+	if base != "" && !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := tmplMux.Execute(w, map[string]any{
+		"BasePath": base,
+		"Entries":  mh.mux.entryInfos(),
+	})
+	if err != nil {
+		http.Error(w, "Failed to render index", http.StatusInternalServerError)
+	}
+}
+
+// entryInfos describes the terminals, active if any of their files
+// was modified within the active window of its tail
+func (m *Multiplexer) entryInfos() []MuxEntry {
+	now := time.Now()
+	infos := make([]MuxEntry, 0, len(m.entries))
+	for _, e := range m.entries {
+		info := MuxEntry{Name: e.name, Files: []string{}}
+		for _, to := range e.terminal.tails {
+			info.Files = append(info.Files, to.Alias)
+			probe := &Tail{activeWindow: DefaultActiveWindow}
+			for _, opt := range to.Options {
+				opt(probe)
+			}
+			if stat, err := os.Stat(to.Filename); err == nil && now.Sub(stat.ModTime()) < probe.activeWindow {
+				info.Active = true
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// muxStatusInterval is how often the index page refreshes the activity of the terminals
+const muxStatusInterval = 5 * time.Second
+
+var tmplMux = template.Must(template.New("mux").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    {{ if .BasePath }}<base href="{{ html .BasePath }}">{{ end }}
+    <title>Log Viewer</title>
+    <style>
+        body { background-color: #0d1117; color: #c9d1d9; font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; margin: 20px; }
+        ul { list-style: none; padding: 0; }
+        li { display: flex; align-items: center; gap: 10px; padding: 8px 0; }
+        a { color: #58a6ff; text-decoration: none; }
+        a:hover { text-decoration: underline; }
+        .files { color: #8b949e; font-size: 0.9em; }
+        .activity-dot { width: 8px; height: 8px; border-radius: 50%; background-color: #777; }
+        .activity-dot.active { background-color: #3fb950; }
+    </style>
+</head>
+<body>
+    <ul id="terminals">
+        {{ range .Entries }}
+        <li data-name="{{ html .Name }}">
+            <span class="activity-dot{{ if .Active }} active{{ end }}" role="status" title="{{ if .Active }}live{{ else }}idle{{ end }}"></span>
+            <a href="{{ html .Name }}/">{{ html .Name }}</a>
+            <span class="files">{{ range $i, $f := .Files }}{{ if $i }}, {{ end }}{{ html $f }}{{ end }}</span>
+        </li>
+        {{ end }}
+    </ul>
+    <script>
+        async function refresh() {
+            try {
+                const res = await fetch(new URL('terminals.json', document.baseURI));
+                for (const entry of await res.json()) {
+                    const dot = document.querySelector('li[data-name="' + entry.name + '"] .activity-dot');
+                    if (dot) {
+                        dot.classList.toggle('active', entry.active);
+                        dot.title = entry.active ? 'live' : 'idle';
+                    }
+                }
+            } catch (e) {}
+        }
+        setInterval(refresh, ` + fmt.Sprint(muxStatusInterval.Milliseconds()) + `);
+    </script>
+</body>
+</html>
+`))
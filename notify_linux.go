@@ -0,0 +1,58 @@
+//go:build linux
+
+package tailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// notifyEvents are the inotify events of the directory that wake the tail:
+// the writes to its files, and the renames, creations and removals of rotations
+const notifyEvents = syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB |
+	syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// notifier wakes the tail on the changes of the directory of the file,
+// see WithWatchMode
+type notifier struct {
+	file *os.File
+	c    chan struct{}
+}
+
+// newNotifier watches the directory of path with inotify
+func newNotifier(path string) (*notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init inotify: %w", err)
+	}
+	dir := filepath.Dir(path)
+	if _, err := syscall.InotifyAddWatch(fd, dir, notifyEvents); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	// the descriptor is non-blocking, reads wait in the runtime poller
+	// and return once it is closed
+	n := &notifier{file: os.NewFile(uintptr(fd), "inotify"), c: make(chan struct{}, 1)}
+	go n.run()
+	return n, nil
+}
+
+func (n *notifier) run() {
+	buf := make([]byte, 64*1024)
+	for {
+		if _, err := n.file.Read(buf); err != nil {
+			return
+		}
+		// the events read before the tail wakes are coalesced
+		select {
+		case n.c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (n *notifier) close() {
+	n.file.Close()
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package tailer
+
+import "errors"
+
+// notifier wakes the tail on the changes of the directory of the file,
+// the other systems than Linux poll only, see WithWatchMode
+type notifier struct {
+	c chan struct{}
+}
+
+func newNotifier(path string) (*notifier, error) {
+	return nil, errors.New("file notifications are not supported on this system")
+}
+
+func (n *notifier) close() {}
@@ -1,9 +1,14 @@
 package tailer
 
 import (
+	"cmp"
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // Remove any ANSI color codes from label, with regexp
@@ -13,10 +18,267 @@ func StripAnsiCodes(s string) string {
 	return stripAnsiCodesRegexp.ReplaceAllString(s, "")
 }
 
+// sgrRegexp matches an SGR sequence at the start of a string,
+// the color codes kept by SanitizeControl
+var sgrRegexp = regexp.MustCompile(`^\x1b\[[0-9;:]*m`)
+
+// SanitizeControl removes the control characters and escape sequences of s
+// that could move the cursor, clear the screen or retitle the terminal,
+// keeping the SGR color codes, tabs and newlines.
+// Vertical tabs and form feeds become spaces.
+func SanitizeControl(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\x1b':
+			if m := sgrRegexp.FindString(s[i:]); m != "" {
+				b.WriteString(m)
+				i += len(m)
+			} else {
+				i += controlSequenceLen(s[i:])
+			}
+			continue
+		case c == '\t' || c == '\n':
+			b.WriteByte(c)
+		case c == '\v' || c == '\f':
+			b.WriteByte(' ')
+		case c < 0x20 || c == 0x7f:
+			// dropped, e.g. carriage returns, backspaces and bells
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			// C1 controls, e.g. U+009B is a CSI of its own
+			if r < 0x80 || r > 0x9f {
+				b.WriteString(s[i : i+size])
+			}
+			i += size
+			continue
+		default:
+			b.WriteByte(c)
+		}
+		i++
+	}
+	return b.String()
+}
+
+// controlSequenceLen returns the length of the escape sequence at the start of s.
+// OSC, DCS, SOS, PM and APC strings end with BEL or ST (ESC \),
+// the other sequences like escapeLen.
+func controlSequenceLen(s string) int {
+	if len(s) < 2 || !strings.ContainsRune("]PX^_", rune(s[1])) {
+		return escapeLen(s)
+	}
+	for j := 2; j < len(s); j++ {
+		if s[j] == '\a' {
+			return j + 1
+		}
+		if s[j] == '\x1b' && j+1 < len(s) && s[j+1] == '\\' {
+			return j + 2
+		}
+	}
+	return len(s)
+}
+
+// TruncateAnsi shortens s to at most cols visible characters, ending with an ellipsis.
+// Escape sequences are kept whole and do not count, and a reset is appended
+// if the kept part contains any, so the colors do not leak past the line.
+func TruncateAnsi(s string, cols int) string {
+	if cols <= 0 || visibleWidth(s) <= cols {
+		return s
+	}
+	var b strings.Builder
+	visible, escaped := 0, false
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			b.WriteString(s[i : i+n])
+			escaped = true
+			i += n
+			continue
+		}
+		if visible == cols-1 {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(s[i : i+size])
+		visible++
+		i += size
+	}
+	b.WriteString("…")
+	if escaped {
+		b.WriteString(ColorReset)
+	}
+	return b.String()
+}
+
+// visibleWidth counts the characters of s that are not part of escape sequences.
+func visibleWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		width++
+		i += size
+	}
+	return width
+}
+
+// escapeLen returns the length of the escape sequence at the start of s, 0 if there is none.
+// CSI sequences (ESC [) end with a byte in 0x40-0x7E, other sequences after one byte.
+func escapeLen(s string) int {
+	if len(s) == 0 || s[0] != '\x1b' {
+		return 0
+	}
+	j := 1
+	if j < len(s) && s[j] == '[' {
+		j++
+		for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+			j++
+		}
+	}
+	if j < len(s) {
+		j++
+	}
+	return j
+}
+
 func Colorize(s string, color string) string {
 	return fmt.Sprintf("%s%s%s", color, s, ColorReset)
 }
 
+// Log levels reported by DetectLevel
+const (
+	LevelTrace = "TRACE"
+	LevelDebug = "DEBUG"
+	LevelInfo  = "INFO"
+	LevelWarn  = "WARN"
+	LevelError = "ERROR"
+)
+
+var levelRegexp = regexp.MustCompile(`\b(TRACE|DEBUG|INFO|WARN|WARNING|ERROR|FATAL|PANIC)\b`)
+
+// DetectLevel returns the log level of the line by the first
+// upper-case level keyword found, ignoring ANSI color codes.
+// WARNING is reported as WARN, FATAL and PANIC as ERROR.
+// The level or lvl value of a logfmt line, e.g. level=warn, takes precedence
+// and is normalized like DetectJSONLevel.
+// It returns an empty string if no level is found.
+func DetectLevel(line string) string {
+	line = StripAnsiCodes(line)
+	if level, ok := logfmtLevel(line); ok {
+		return level
+	}
+	m := levelRegexp.FindString(line)
+	switch m {
+	case "WARNING":
+		return LevelWarn
+	case "FATAL", "PANIC":
+		return LevelError
+	}
+	return m
+}
+
+// DetectJSONLevel returns the log level of a JSON line from the value of the key,
+// normalized like DetectLevel, e.g. "warning" is WARN and "fatal" ERROR;
+// numeric levels follow the bunyan and pino convention, 10 is TRACE up to 50 ERROR.
+// The JSON object may follow a prefix, e.g. the label of a MultiTail.
+// Lines that are not JSON objects with a known level fall back to DetectLevel.
+func DetectJSONLevel(line, key string) string {
+	if level, ok := jsonLevel(StripAnsiCodes(line), key); ok {
+		return level
+	}
+	return DetectLevel(line)
+}
+
+// jsonLevel returns the level of the key of the JSON object in the line
+func jsonLevel(line, key string) (string, bool) {
+	i := strings.IndexByte(line, '{')
+	if i < 0 {
+		return "", false
+	}
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(line[i:]), &obj); err != nil {
+		return "", false
+	}
+	switch v := obj[key].(type) {
+	case string:
+		return parseLevel(v)
+	case float64:
+		switch {
+		case v >= 50:
+			return LevelError, true
+		case v >= 40:
+			return LevelWarn, true
+		case v >= 30:
+			return LevelInfo, true
+		case v >= 20:
+			return LevelDebug, true
+		case v >= 10:
+			return LevelTrace, true
+		}
+	}
+	return "", false
+}
+
+// parseLevel returns the level of a level name in any case, e.g. "warning" is WARN
+func parseLevel(name string) (string, bool) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "TRACE":
+		return LevelTrace, true
+	case "DEBUG":
+		return LevelDebug, true
+	case "INFO", "INFORMATION", "NOTICE":
+		return LevelInfo, true
+	case "WARN", "WARNING":
+		return LevelWarn, true
+	case "ERROR", "ERR", "FATAL", "PANIC", "CRITICAL", "CRIT", "ALERT", "EMERG", "EMERGENCY":
+		return LevelError, true
+	}
+	return "", false
+}
+
+// levelColors are the colors of the levels, as of the "level" syntax
+var levelColors = map[string]string{
+	LevelTrace: ColorDarkGray,
+	LevelDebug: ColorLightGray,
+	LevelInfo:  ColorGreen,
+	LevelWarn:  ColorYellow,
+	LevelError: ColorRed,
+}
+
+// NewJSONLevelColoring returns a plugin coloring the value of the key
+// of JSON lines by its level, see DetectJSONLevel.
+// Other lines are left as they are.
+func NewJSONLevelColoring(key string) Plugin {
+	return jsonLevelColoring{
+		key:   key,
+		value: regexp.MustCompile(`"` + regexp.QuoteMeta(key) + `"\s*:\s*("(?:[^"\\]|\\.)*"|-?[0-9.]+)`),
+	}
+}
+
+type jsonLevelColoring struct {
+	key   string
+	value *regexp.Regexp // the key and its value, captured
+}
+
+func (c jsonLevelColoring) String() string {
+	return "json-level:" + c.key
+}
+
+func (c jsonLevelColoring) Apply(line string) (string, bool) {
+	level, ok := jsonLevel(StripAnsiCodes(line), c.key)
+	if !ok {
+		return line, true
+	}
+	m := c.value.FindStringSubmatchIndex(line)
+	if m == nil {
+		return line, true
+	}
+	return line[:m[2]] + Colorize(line[m[2]:m[3]], levelColors[level]) + line[m[3]:], true
+}
+
 type Plugin interface {
 	// Apply processes a line and returns the modified line
 	// and a boolean indicating processing ahead
@@ -59,64 +321,449 @@ const (
 	ColorWhite         = "\033[97m"       // White
 )
 
+// NewWithSyntaxHighlighting colors the lines with the Profiles of the syntax names in order,
+// unknown names are ignored
 func NewWithSyntaxHighlighting(syntax ...string) Plugin {
 	return syntaxColoring(syntax)
 }
 
+// Threshold colors the numbers from Min upward, up to the Min of the next threshold
+type Threshold struct {
+	Min   float64
+	Color string
+}
+
+// NewThresholdColoring returns a plugin coloring the number captured by the
+// first group of re (or the whole match) with the color of the highest
+// threshold whose Min the number reaches. Numbers below every threshold,
+// malformed numbers and lines without a match are left as they are.
+func NewThresholdColoring(re *regexp.Regexp, thresholds ...Threshold) Plugin {
+	sorted := slices.Clone(thresholds)
+	slices.SortStableFunc(sorted, func(a, b Threshold) int { return cmp.Compare(a.Min, b.Min) })
+	return thresholdColoring{re: re, thresholds: sorted}
+}
+
+type thresholdColoring struct {
+	re         *regexp.Regexp
+	thresholds []Threshold // sorted by Min
+}
+
+func (c thresholdColoring) String() string {
+	return "threshold:" + c.re.String()
+}
+
+func (c thresholdColoring) Apply(line string) (string, bool) {
+	matches := c.re.FindAllStringSubmatchIndex(line, -1)
+	if matches == nil {
+		return line, true
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		value, err := strconv.ParseFloat(line[start:end], 64)
+		if err != nil {
+			continue
+		}
+		color := ""
+		for _, t := range c.thresholds {
+			if value >= t.Min {
+				color = t.Color
+			}
+		}
+		if color == "" {
+			continue
+		}
+		b.WriteString(line[last:start])
+		b.WriteString(Colorize(line[start:end], color))
+		last = end
+	}
+	b.WriteString(line[last:])
+	return b.String(), true
+}
+
+// ColorByLevel is the color of WithColorRuleGroups coloring a group
+// by the level it names, e.g. "warn" yellow, as the "level" syntax does
+const ColorByLevel = "level"
+
+// NewColorRuleGroups returns a plugin coloring the named capture groups of re,
+// each with its color by name, in every match of the line. A group nested in
+// another one already colored, or not matched, is left as it is, so the
+// colors never overlap. Groups without a color, or colored ColorByLevel with
+// a text that is not a level, are left as they are.
+func NewColorRuleGroups(re *regexp.Regexp, colors map[string]string) Plugin {
+	c := colorRuleGroups{re: re}
+	for i, name := range re.SubexpNames() {
+		if color, ok := colors[name]; ok && name != "" && color != "" {
+			c.groups = append(c.groups, colorGroup{index: i, color: color})
+		}
+	}
+	return c
+}
+
+type colorRuleGroups struct {
+	re     *regexp.Regexp
+	groups []colorGroup // in the order of the groups in re
+}
+
+type colorGroup struct {
+	index int // of the group in the submatches
+	color string
+}
+
+func (c colorRuleGroups) String() string {
+	return "groups:" + c.re.String()
+}
+
+func (c colorRuleGroups) Apply(line string) (string, bool) {
+	if len(c.groups) == 0 {
+		return line, true
+	}
+	matches := c.re.FindAllStringSubmatchIndex(line, -1)
+	if matches == nil {
+		return line, true
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		// the groups start in their order in re, an outer group before the nested ones
+		for _, g := range c.groups {
+			start, end := m[2*g.index], m[2*g.index+1]
+			if start < last || start == end {
+				continue
+			}
+			color := g.color
+			if color == ColorByLevel {
+				color = levelColors[DetectLevel(strings.ToUpper(line[start:end]))]
+				if color == "" {
+					continue
+				}
+			}
+			b.WriteString(line[last:start])
+			b.WriteString(Colorize(line[start:end], color))
+			last = end
+		}
+	}
+	b.WriteString(line[last:])
+	return b.String(), true
+}
+
 type syntaxColoring []string
 
-var slogKeyValuePattern = regexp.MustCompile(`(\w+)=("(?:[^"\\]|\\.)*"|[^\s]+)`)
+func (c syntaxColoring) String() string {
+	return "syntax:" + strings.Join(c, ",")
+}
 
 func (c syntaxColoring) Apply(line string) (string, bool) {
-	// Default Keywords
 	for _, syntax := range c {
-		switch strings.ToLower(syntax) {
-		case "level", "levels":
-			line = strings.ReplaceAll(line, "TRACE", ColorDarkGray+"TRACE"+ColorReset)
-			line = strings.ReplaceAll(line, "DEBUG", ColorLightGray+"DEBUG"+ColorReset)
-			line = strings.ReplaceAll(line, "INFO", ColorGreen+"INFO"+ColorReset)
-			line = strings.ReplaceAll(line, "WARN", ColorYellow+"WARN"+ColorReset)
-			line = strings.ReplaceAll(line, "ERROR", ColorRed+"ERROR"+ColorReset)
-		case "slog-text":
-			// Color name=value patterns in slog format
-			line = slogKeyValuePattern.ReplaceAllStringFunc(line, func(match string) string {
-				parts := strings.SplitN(match, "=", 2)
-				if len(parts) == 2 {
-					key := parts[0]
-					value := parts[1]
-					return ColorCyan + key + ColorReset + "=" + ColorBlue + value + ColorReset
-				}
-				return match
-			})
-		case "slog-json":
-			// Color JSON key:value patterns in slog format
-			line = regexp.MustCompile(`"(\w+)":\s*("(?:[^"\\]|\\.)*"|[^\s,}]+)`).ReplaceAllStringFunc(line, func(match string) string {
-				parts := strings.SplitN(match, ":", 2)
-				if len(parts) == 2 {
-					key := strings.TrimSpace(parts[0])
-					value := strings.TrimSpace(parts[1])
-					return ColorCyan + key + ColorReset + ":" + ColorBlue + value + ColorReset
-				}
-				return match
-			})
-		case "syslog":
-			// /var/log/syslog specific coloring
-			// Pattern: timestamp hostname process[pid]: message
-			syslogPattern := regexp.MustCompile(`^(\S+)\s+(\S+)\s+([^\s:]+(?:\[\d+\])?):(.*)$`)
-			line = syslogPattern.ReplaceAllStringFunc(line, func(match string) string {
-				matches := syslogPattern.FindStringSubmatch(match)
-				if len(matches) == 5 {
-					timestamp := ColorBlue + matches[1] + ColorReset
-					hostname := ColorCyan + matches[2] + ColorReset
-					process := ColorYellow + matches[3] + ColorReset
-					message := matches[4]
-					return timestamp + " " + hostname + " " + process + ":" + message
-				}
-				return match
-			})
-			// syslog file encodes ESC as #033[
-			line = strings.ReplaceAll(line, "#033[", "\033[")
+		if profile, ok := Profiles[strings.ToLower(syntax)]; ok {
+			line = profile(line)
 		}
 	}
 	return line, true
 }
+
+// Profile colors a line with ANSI codes. It is a Plugin, so a custom profile
+// can be added to a tail by WithPlugins, and tested by ColorizeLine and Spans.
+type Profile func(line string) string
+
+// Apply colors the line, it never drops it
+func (p Profile) Apply(line string) (string, bool) {
+	return p(line), true
+}
+
+// Profiles are the coloring profiles by the syntax names of WithSyntaxHighlighting
+var Profiles = map[string]Profile{
+	"level":     ColorLevels,
+	"levels":    ColorLevels,
+	"slog-text": ColorSlogText,
+	"slog-json": ColorSlogJSON,
+	"json":      ColorSlogJSON,
+	"syslog":    ColorSyslog,
+	"access":    ColorAccessLog,
+	"logfmt":    ColorLogfmt,
+}
+
+// NoColoring is the name of WithColoringProfile removing the coloring
+const NoColoring = "none"
+
+// isColoring reports whether the plugin only colors the lines, see WithColoringProfile
+func isColoring(p Plugin) bool {
+	switch p.(type) {
+	case syntaxColoring, jsonLevelColoring, thresholdColoring, colorRuleGroups, ColorPipeline, Profile:
+		return true
+	}
+	return false
+}
+
+// recolor returns the plugins with their coloring replaced as set by WithColoringProfile,
+// the profile takes the place of the first coloring plugin, or comes last.
+func (tail *Tail) recolor(plugins []Plugin) []Plugin {
+	profile, ok := Profiles[tail.coloring]
+	if !ok && tail.coloring != NoColoring {
+		return plugins
+	}
+	ret := make([]Plugin, 0, len(plugins)+1)
+	replaced := false
+	for _, p := range plugins {
+		if !isColoring(p) {
+			ret = append(ret, p)
+			continue
+		}
+		if !replaced && profile != nil {
+			ret = append(ret, profile)
+		}
+		replaced = true
+	}
+	if !replaced && profile != nil {
+		ret = append(ret, profile)
+	}
+	return ret
+}
+
+// ProfileNames returns the names of Profiles, sorted
+func ProfileNames() []string {
+	names := make([]string, 0, len(Profiles))
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ColorizeLine applies the profiles to the line in order
+func ColorizeLine(line string, profiles ...Profile) string {
+	for _, profile := range profiles {
+		line = profile(line)
+	}
+	return line
+}
+
+// ColorLevels colors the log level keywords
+func ColorLevels(line string) string {
+	line = strings.ReplaceAll(line, "TRACE", ColorDarkGray+"TRACE"+ColorReset)
+	line = strings.ReplaceAll(line, "DEBUG", ColorLightGray+"DEBUG"+ColorReset)
+	line = strings.ReplaceAll(line, "INFO", ColorGreen+"INFO"+ColorReset)
+	line = strings.ReplaceAll(line, "WARN", ColorYellow+"WARN"+ColorReset)
+	line = strings.ReplaceAll(line, "ERROR", ColorRed+"ERROR"+ColorReset)
+	return line
+}
+
+var slogKeyValuePattern = regexp.MustCompile(`(\w+)=("(?:[^"\\]|\\.)*"|[^\s]+)`)
+
+// ColorSlogText colors the name=value pairs of slog's text format
+func ColorSlogText(line string) string {
+	return slogKeyValuePattern.ReplaceAllStringFunc(line, func(match string) string {
+		parts := strings.SplitN(match, "=", 2)
+		if len(parts) == 2 {
+			key := parts[0]
+			value := parts[1]
+			return ColorCyan + key + ColorReset + "=" + ColorBlue + value + ColorReset
+		}
+		return match
+	})
+}
+
+var slogJSONPattern = regexp.MustCompile(`"(\w+)":\s*("(?:[^"\\]|\\.)*"|[^\s,}]+)`)
+
+// ColorSlogJSON colors the "key":value pairs of slog's JSON format
+func ColorSlogJSON(line string) string {
+	return slogJSONPattern.ReplaceAllStringFunc(line, func(match string) string {
+		parts := strings.SplitN(match, ":", 2)
+		if len(parts) == 2 {
+			key := strings.TrimSpace(parts[0])
+			value := strings.TrimSpace(parts[1])
+			return ColorCyan + key + ColorReset + ":" + ColorBlue + value + ColorReset
+		}
+		return match
+	})
+}
+
+// logfmtPairPattern matches a key=value pair of logfmt, the value bare or
+// double-quoted with backslash escapes, e.g. msg="said \"hi\"" or dur=12ms
+var logfmtPairPattern = regexp.MustCompile(`(?:^|\s)([\w.\-/@]+)=("(?:[^"\\]|\\.)*"|[^\s"]*)`)
+
+// logfmtLevelPattern matches the level of a logfmt line
+var logfmtLevelPattern = regexp.MustCompile(`(?:^|\s)(?:level|lvl)="?(\w+)`)
+
+// logfmtLevel returns the level of the level or lvl key of a logfmt line
+func logfmtLevel(line string) (string, bool) {
+	m := logfmtLevelPattern.FindStringSubmatch(line)
+	if m == nil {
+		return "", false
+	}
+	return parseLevel(m[1])
+}
+
+// ColorLogfmt colors the key=value pairs of logfmt lines, as written by
+// logrus, zap or go-kit: the keys, the quoted values, and the value of
+// the level or lvl key by its level. Lines with fewer than two pairs are
+// not logfmt and are returned as is.
+func ColorLogfmt(line string) string {
+	pairs := logfmtPairPattern.FindAllStringSubmatchIndex(line, -1)
+	if len(pairs) < 2 {
+		return line
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range pairs {
+		key, value := line[m[2]:m[3]], line[m[4]:m[5]]
+		b.WriteString(line[last:m[2]])
+		b.WriteString(ColorCyan + key + ColorReset + "=")
+		switch level, ok := parseLevel(strings.Trim(value, `"`)); {
+		case (key == "level" || key == "lvl") && ok:
+			b.WriteString(levelColors[level] + value + ColorReset)
+		case strings.HasPrefix(value, `"`):
+			b.WriteString(ColorOrange + value + ColorReset)
+		default:
+			b.WriteString(value)
+		}
+		last = m[5]
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// Pattern: timestamp hostname process[pid]: message
+var syslogPattern = regexp.MustCompile(`^(\S+)\s+(\S+)\s+([^\s:]+(?:\[\d+\])?):(.*)$`)
+
+// ColorSyslog colors the timestamp, hostname and process of /var/log/syslog lines
+func ColorSyslog(line string) string {
+	line = syslogPattern.ReplaceAllStringFunc(line, func(match string) string {
+		matches := syslogPattern.FindStringSubmatch(match)
+		if len(matches) == 5 {
+			timestamp := ColorBlue + matches[1] + ColorReset
+			hostname := ColorCyan + matches[2] + ColorReset
+			process := ColorYellow + matches[3] + ColorReset
+			message := matches[4]
+			return timestamp + " " + hostname + " " + process + ":" + message
+		}
+		return match
+	})
+	// syslog file encodes ESC as #033[
+	return strings.ReplaceAll(line, "#033[", "\033[")
+}
+
+// ColorAccessLog colors the client, time, method and status of the lines of
+// the common and combined log formats of web servers, the status by its class
+func ColorAccessLog(line string) string {
+	m := accessLogPattern.FindStringSubmatch(line)
+	if m == nil {
+		return line
+	}
+	status := ColorGreen
+	switch m[7][0] {
+	case '3':
+		status = ColorCyan
+	case '4':
+		status = ColorYellow
+	case '5':
+		status = ColorRed
+	}
+	return ColorCyan + m[1] + ColorReset + m[2] + ColorBlue + m[3] + ColorReset + m[4] +
+		ColorYellow + m[5] + ColorReset + m[6] + status + m[7] + ColorReset + line[len(m[0]):]
+}
+
+// Span is a part of a colored line, its text and the ANSI color code
+// it is shown with, empty for the default color
+type Span struct {
+	Text  string
+	Color string
+}
+
+// Spans splits a line colored with ANSI codes into its spans,
+// adjacent spans of the same color are merged.
+//
+//	Spans(ColorizeLine("INFO ok", ColorLevels)) // [{INFO \033[32m} { ok }]
+func Spans(line string) []Span {
+	var spans []Span
+	color := ""
+	add := func(text string) {
+		if text == "" {
+			return
+		}
+		if n := len(spans); n > 0 && spans[n-1].Color == color {
+			spans[n-1].Text += text
+			return
+		}
+		spans = append(spans, Span{Text: text, Color: color})
+	}
+	for {
+		loc := stripAnsiCodesRegexp.FindStringIndex(line)
+		if loc == nil {
+			add(line)
+			return spans
+		}
+		add(line[:loc[0]])
+		if code := line[loc[0]:loc[1]]; code == ColorReset || code == "\x1b[m" {
+			color = ""
+		} else {
+			color = code
+		}
+		line = line[loc[1]:]
+	}
+}
+
+// ColorPipeline is a Plugin coloring a line with its passes in order, each pass
+// a coloring plugin such as a Profile or NewThresholdColoring. The earlier passes
+// take precedence: a pass is applied only to the parts of the line still in the
+// default color, each part separately, and the colored parts, the colors of the
+// line itself included, are kept as they are. So the colors never nest or overlap,
+// and a pass matching the whole line, like ColorSyslog, goes first.
+// The passes can not drop the line.
+//
+//	tailer.WithPlugins(tailer.ColorPipeline{
+//		tailer.Profile(tailer.ColorLevels),
+//		tailer.NewThresholdColoring(latency, tailer.Threshold{Min: 1000, Color: tailer.ColorRed}),
+//		tailer.Profile(tailer.ColorSlogText),
+//	})
+type ColorPipeline []Plugin
+
+func (p ColorPipeline) String() string {
+	names := make([]string, len(p))
+	for i, pass := range p {
+		names[i] = pluginName(i, pass)
+	}
+	return "colors:" + strings.Join(names, ",")
+}
+
+func (p ColorPipeline) Apply(line string) (string, bool) {
+	spans := Spans(line)
+	for _, pass := range p {
+		next := make([]Span, 0, len(spans))
+		for _, span := range spans {
+			if span.Color != "" {
+				next = append(next, span)
+				continue
+			}
+			colored, _ := pass.Apply(span.Text)
+			next = append(next, Spans(colored)...)
+		}
+		spans = next
+	}
+	return joinSpans(spans), true
+}
+
+// joinSpans renders the spans with a color code before and a reset after
+// each run of colored text
+func joinSpans(spans []Span) string {
+	var b strings.Builder
+	color := ""
+	for _, span := range spans {
+		if span.Color != color {
+			if color != "" {
+				b.WriteString(ColorReset)
+			}
+			b.WriteString(span.Color)
+			color = span.Color
+		}
+		b.WriteString(span.Text)
+	}
+	if color != "" {
+		b.WriteString(ColorReset)
+	}
+	return b.String()
+}
@@ -0,0 +1,195 @@
+package tailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// MaxRangeBytes limits the size of a range requested from the web handler
+const MaxRangeBytes = 4 * 1024 * 1024
+
+// Range is a bounded part of a file snapped to line boundaries
+type Range struct {
+	Start int64    `json:"start"` // offset of the first byte of the first line
+	End   int64    `json:"end"`   // offset just after the last line
+	Size  int64    `json:"size"`  // size of the file at the time of reading
+	Lines []string `json:"lines"` // lines after applying the patterns and plugins
+}
+
+// ReadRange reads the complete lines overlapping the byte range [start, end)
+// of the file. A line cut by start is skipped, a line cut by end is read
+// to its end, so the returned Range.Start and Range.End may differ from the
+// requested offsets. The lines pass through the patterns and plugins of the tail.
+// It does not require the tail to be started.
+func (tail *Tail) ReadRange(ctx context.Context, start, end int64) (Range, error) {
+	ret := Range{}
+	if start < 0 || end < start {
+		return ret, fmt.Errorf("invalid range %d-%d", start, end)
+	}
+
+	file, err := openFileShared(tail.filepath)
+	if err != nil {
+		return ret, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return ret, fmt.Errorf("failed to stat file: %w", err)
+	}
+	ret.Size = stat.Size()
+	if end > ret.Size {
+		end = ret.Size
+	}
+	if start > end {
+		start = end
+	}
+
+	// Step back one byte to find out if start is at a line boundary
+	pos := start
+	if start > 0 {
+		pos = start - 1
+	}
+	if _, err := file.Seek(pos, io.SeekStart); err != nil {
+		return ret, fmt.Errorf("failed to seek: %w", err)
+	}
+	br := bufio.NewReader(file)
+	if start > 0 {
+		skipped, err := br.ReadString('\n')
+		pos += int64(len(skipped))
+		if err != nil {
+			// no line starts within the range
+			ret.Start, ret.End = ret.Size, ret.Size
+			return ret, nil
+		}
+	}
+	ret.Start = pos
+
+	for pos < end {
+		if err := ctx.Err(); err != nil {
+			return ret, err
+		}
+		line, err := br.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return ret, fmt.Errorf("failed to read: %w", err)
+		}
+		if line == "" {
+			break
+		}
+		pos += int64(len(line))
+		line = strings.TrimSuffix(line, "\n")
+		line = strings.TrimSuffix(line, "\r")
+		if ln, ok := tail.process(line); ok {
+			ret.Lines = append(ret.Lines, ln)
+		}
+		if err != nil {
+			break
+		}
+	}
+	ret.End = pos
+	return ret, nil
+}
+
+// historyBlockSize is the size of the blocks History reads backward from the offset
+var historyBlockSize = 64 * 1024
+
+// Line is a line of the file with the byte offset of its first byte
+type Line struct {
+	Offset int64  `json:"offset"`
+	Text   string `json:"text"`           // the line after applying the patterns and plugins
+	File   string `json:"file,omitempty"` // the alias of the tail, set by Subscribe
+}
+
+// History returns up to maxLines complete lines ending just before beforeOffset,
+// oldest first, for paging backward through a large file. A line cut by
+// beforeOffset is skipped; a last line without newline at the end of the file is
+// included. The file is read backward in blocks, so the cost depends on
+// the lines read, not on the size of the file. The lines pass through the patterns
+// and plugins of the tail, only the returned lines count toward maxLines.
+// A line longer than maxLastLineSize starts at this length from its end,
+// and the lines before it are not read.
+// The returned offset is the beforeOffset of the previous page, 0 at the beginning of the file.
+// It does not require the tail to be started.
+func (tail *Tail) History(ctx context.Context, beforeOffset int64, maxLines int) ([]Line, int64, error) {
+	if beforeOffset < 0 || maxLines <= 0 {
+		return nil, 0, fmt.Errorf("invalid history request %d, %d lines", beforeOffset, maxLines)
+	}
+
+	file, err := openFileShared(tail.filepath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+	end := min(beforeOffset, stat.Size())
+
+	// a line cut by the offset is skipped, unless it is the end of the file
+	skip := false
+	if end > 0 && end < stat.Size() {
+		b := make([]byte, 1)
+		if _, err := file.ReadAt(b, end-1); err != nil {
+			return nil, 0, fmt.Errorf("failed to read: %w", err)
+		}
+		skip = b[0] != '\n'
+	}
+
+	var lines []Line // newest first
+	var data []byte  // bytes from cur to the end of the region not split into lines yet
+	cur := end
+	addLine := func(offset int64, b []byte) {
+		if skip {
+			skip = false
+			return
+		}
+		line := strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r")
+		if ln, ok := tail.process(line); ok {
+			lines = append(lines, Line{Offset: offset, Text: ln})
+		}
+	}
+	for len(lines) < maxLines && (cur > 0 || len(data) > 0) {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		if cur > 0 {
+			n := min(int64(historyBlockSize), cur)
+			cur -= n
+			block := make([]byte, n, n+int64(len(data)))
+			if _, err := file.ReadAt(block, cur); err != nil && !errors.Is(err, io.EOF) {
+				return nil, 0, fmt.Errorf("failed to read: %w", err)
+			}
+			data = append(block, data...)
+		}
+		// split the lines from the end, the first one may continue in the previous block
+		for len(lines) < maxLines && len(data) > 0 {
+			i := bytes.LastIndexByte(data[:len(data)-1], '\n')
+			if i < 0 && cur > 0 && len(data) <= maxLastLineSize {
+				break
+			}
+			seg, offset := data[i+1:], cur+int64(i)+1
+			data = data[:i+1]
+			if len(seg) > maxLastLineSize {
+				// no beginning within the cap, e.g. a file without newlines
+				seg, offset = seg[len(seg)-maxLastLineSize:], offset+int64(len(seg)-maxLastLineSize)
+				data, cur = nil, 0
+			}
+			addLine(offset, seg)
+		}
+	}
+
+	slices.Reverse(lines)
+	var next int64
+	if len(lines) == maxLines {
+		next = lines[0].Offset
+	}
+	return lines, next, nil
+}
@@ -0,0 +1,77 @@
+package tailer
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// NewReader starts the tail and returns its lines as a continuous stream,
+// each followed by a newline, to pipe into the io ecosystem: io.Copy,
+// a bufio.Scanner, a gzip.Writer... The lines are those of Lines, after the
+// patterns, transforms and plugins of the tail.
+// The reader follows the file as the tail does, across rotations and
+// truncations, so Read waits for the next line instead of returning io.EOF
+// at the end of the file. It returns io.EOF once the reader is closed,
+// and the error of ctx once ctx is done. Close stops the tail.
+func NewReader(ctx context.Context, tail ITail) (io.ReadCloser, error) {
+	if err := tail.Start(); err != nil {
+		return nil, err
+	}
+	return &tailReader{ctx: ctx, tail: tail}, nil
+}
+
+type tailReader struct {
+	ctx       context.Context
+	tail      ITail
+	buf       []byte // the rest of the lines received, not read yet
+	closed    atomic.Bool
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (r *tailReader) Read(p []byte) (int, error) {
+	if r.closed.Load() {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(r.buf) == 0 {
+		select {
+		case line, ok := <-r.tail.Lines():
+			if !ok {
+				return 0, io.EOF
+			}
+			r.buf = append(append(r.buf[:0], line...), '\n')
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		}
+	}
+	// fill p with the lines already received, without waiting for more
+fill:
+	for len(r.buf) < len(p) {
+		select {
+		case line, ok := <-r.tail.Lines():
+			if !ok {
+				break fill
+			}
+			r.buf = append(append(r.buf, line...), '\n')
+		default:
+			break fill
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close stops the tail, the following reads return io.EOF
+func (r *tailReader) Close() error {
+	r.closeOnce.Do(func() {
+		r.closed.Store(true)
+		r.closeErr = r.tail.Stop()
+	})
+	return r.closeErr
+}
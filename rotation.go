@@ -0,0 +1,184 @@
+package tailer
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RotationScheme describes how the files of a rotated log are named, so that
+// the tail knows which file is written and the order of the archives,
+// see WithRotationPattern.
+type RotationScheme interface {
+	// Files returns the file being written of the log at path, and its archives
+	// in chronological order, oldest first. The live file is path itself
+	// when no file of the scheme is found.
+	Files(path string) (live string, archives []string)
+}
+
+// RotationOrder tells which of the numbered files of a log is the live one
+type RotationOrder int
+
+const (
+	// LowestIsLive is the order of the schemes renaming the files on rotation,
+	// e.g. app.0.log, app.1.log, app.2.log of java.util.logging: the lowest
+	// number is written and the higher the number the older the file.
+	LowestIsLive RotationOrder = iota
+	// HighestIsLive is the order of the schemes starting a file with the next
+	// number on rotation, e.g. app.1.log, app.2.log, app.3.log: the highest
+	// number is written and the lower the number the older the file.
+	HighestIsLive
+)
+
+// WithRotationPattern follows the live file of the log among its rotated files
+// named by the scheme, e.g. the highest numbered of app.1.log, app.2.log, ...
+// with NumberedScheme(HighestIsLive) for the path app.log. The scheme is checked
+// on every poll: when another file becomes the live one, the remaining lines of
+// the current file are read before switching to it from its beginning.
+// The archives of the scheme, see RotationScheme.Files, can be streamed first
+// with WithHistory. WithFollowSymlink takes precedence.
+func WithRotationPattern(scheme RotationScheme) Option {
+	return func(t *Tail) {
+		t.rotation = scheme
+	}
+}
+
+// LogrotateScheme returns the scheme of logrotate: the path is written, and it
+// is renamed to app.log.1, app.log.2.gz, ... (the higher the older), or with
+// dateext to app.log-20261015, app.log-20261016.gz, ... (the later the newer).
+func LogrotateScheme() RotationScheme {
+	return logrotateScheme{}
+}
+
+type logrotateScheme struct{}
+
+func (logrotateScheme) Files(path string) (string, []string) {
+	type numbered struct {
+		path string
+		num  int
+	}
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return path, nil
+	}
+	// dateext
+	dates, _ := filepath.Glob(path + "-*")
+	matches = append(matches, dates...)
+	var dated []string
+	var list []numbered
+	for _, m := range matches {
+		suffix := strings.TrimSuffix(m[len(path):], ".gz")
+		if date, ok := strings.CutPrefix(suffix, "-"); ok {
+			if date != "" && strings.Trim(date, "0123456789-") == "" {
+				dated = append(dated, m)
+			}
+			continue
+		}
+		num, err := strconv.Atoi(suffix[1:])
+		if err != nil {
+			continue
+		}
+		list = append(list, numbered{path: m, num: num})
+	}
+	// the dates sort as their names, the higher number is older
+	sort.Strings(dated)
+	sort.Slice(list, func(i, j int) bool { return list[i].num > list[j].num })
+	archives := dated
+	for _, n := range list {
+		archives = append(archives, n.path)
+	}
+	return path, archives
+}
+
+// NumberedScheme returns the scheme numbering the files of the path between
+// its name and its extension, e.g. app.1.log, app.2.log.gz for app.log,
+// the live one by the order. The path itself, unnumbered, is the live file
+// if it exists, like app.log before app.1.log of log4j. Archives ending
+// with ".gz" are included.
+func NumberedScheme(order RotationOrder) RotationScheme {
+	return patternScheme{order: order}
+}
+
+// RegexpScheme returns the scheme of the files in the directory of the path
+// whose names match the pattern, ordered by its first group: as numbers if
+// they are, else as text, like the dates of app-2026-10-15.log. A file
+// matching with an empty group is the live one, like app.log of
+// `^app(?:\.(\d+))?\.log$`. Files ending with ".gz" are archives only.
+func RegexpScheme(pattern *regexp.Regexp, order RotationOrder) RotationScheme {
+	return patternScheme{pattern: pattern, order: order}
+}
+
+// patternScheme is the scheme of NumberedScheme and RegexpScheme
+type patternScheme struct {
+	pattern *regexp.Regexp // nil for the numbers of NumberedScheme
+	order   RotationOrder
+}
+
+func (s patternScheme) Files(path string) (string, []string) {
+	pattern := s.pattern
+	if pattern == nil {
+		ext := filepath.Ext(path)
+		stem := strings.TrimSuffix(filepath.Base(path), ext)
+		pattern = regexp.MustCompile(`^` + regexp.QuoteMeta(stem) + `(?:\.(\d+))?` + regexp.QuoteMeta(ext) + `(?:\.gz)?$`)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return path, nil
+	}
+	type rotated struct {
+		path string
+		key  string
+	}
+	var list []rotated
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		m := pattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		var key string
+		if len(m) > 1 {
+			key = m[1]
+		}
+		list = append(list, rotated{path: filepath.Join(filepath.Dir(path), entry.Name()), key: key})
+	}
+	// oldest first, the unnumbered file is the newest
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].key, list[j].key
+		if a == "" || b == "" {
+			return b == "" && a != ""
+		}
+		older := compareKeys(a, b) < 0
+		if s.order == LowestIsLive {
+			older = compareKeys(a, b) > 0
+		}
+		return older
+	})
+	for i := len(list) - 1; i >= 0; i-- {
+		if !strings.HasSuffix(list[i].path, ".gz") {
+			archives := make([]string, i)
+			for j, r := range list[:i] {
+				archives[j] = r.path
+			}
+			return list[i].path, archives
+		}
+	}
+	return path, nil
+}
+
+// compareKeys compares the keys of two rotated files,
+// as numbers if both are, else as text
+func compareKeys(a, b string) int {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return cmp.Compare(x, y)
+}
@@ -0,0 +1,135 @@
+package tailer
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// UpdateOptions replaces the rules of the tail, its patterns and plugins,
+// with the ones configured by opts; other options are ignored.
+// It is safe to call while the tail is running,
+// the new rules apply to the lines emitted afterwards.
+func (tail *Tail) UpdateOptions(opts ...Option) {
+	updated := &Tail{}
+	for _, opt := range opts {
+		opt(updated)
+	}
+	tail.rulesMu.Lock()
+	tail.patterns = updated.patterns
+	tail.plugins = tail.recolor(updated.plugins)
+	tail.rulesMu.Unlock()
+}
+
+// rules returns the current patterns and plugins of the tail
+func (tail *Tail) rules() ([]Pattern, []Plugin) {
+	tail.rulesMu.RLock()
+	defer tail.rulesMu.RUnlock()
+	return tail.patterns, tail.plugins
+}
+
+// ruleRegistry keeps the rules updated by Terminal.UpdateTail
+// and the tails running for the handlers, to update them in place.
+type ruleRegistry struct {
+	mu      sync.Mutex
+	rules   map[string][]Option // alias -> rule options
+	paused  map[string]bool     // aliases paused by PauseTail
+	running map[*Tail]runningTail
+}
+
+type runningTail struct {
+	alias   string
+	filters []Option // filters of the request, kept across updates
+}
+
+func newRuleRegistry() *ruleRegistry {
+	return &ruleRegistry{
+		rules:   map[string][]Option{},
+		paused:  map[string]bool{},
+		running: map[*Tail]runningTail{},
+	}
+}
+
+// register applies the updated rules of the alias, if any, to the new tail
+// and tracks it until the returned function is called.
+func (rr *ruleRegistry) register(tail *Tail, alias string, filters []Option) func() {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	if opts, ok := rr.rules[alias]; ok {
+		tail.UpdateOptions(slices.Concat(opts, filters)...)
+	}
+	if rr.paused[alias] {
+		tail.Pause()
+	}
+	rr.running[tail] = runningTail{alias: alias, filters: filters}
+	return func() {
+		rr.mu.Lock()
+		delete(rr.running, tail)
+		rr.mu.Unlock()
+	}
+}
+
+// UpdateTail replaces the rules, patterns and plugins, of the tail with the alias
+// by the ones configured by opts, without a restart. The streams already open
+// apply them to the lines emitted afterwards, keeping their request filters,
+// and the new streams start with them.
+func (t Terminal) UpdateTail(alias string, opts ...Option) error {
+	if !slices.ContainsFunc(t.tails, func(to TailOption) bool { return to.Alias == alias }) {
+		return fmt.Errorf("unknown tail %q", alias)
+	}
+	rr := t.rules
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.rules[alias] = opts
+	for tail, rt := range rr.running {
+		if rt.alias == alias {
+			tail.UpdateOptions(slices.Concat(opts, rt.filters)...)
+		}
+	}
+	return nil
+}
+
+// PauseTail stops reading the file of the tail with the alias in all the streams
+// and subscriptions, open or new, until ResumeTail, see Tail.Pause.
+// The streams stay open and continue where they left off.
+func (t Terminal) PauseTail(alias string) error {
+	return t.setPaused(alias, true)
+}
+
+// ResumeTail continues reading the file of the tail with the alias paused by PauseTail
+func (t Terminal) ResumeTail(alias string) error {
+	return t.setPaused(alias, false)
+}
+
+func (t Terminal) setPaused(alias string, paused bool) error {
+	if !slices.ContainsFunc(t.tails, func(to TailOption) bool { return to.Alias == alias }) {
+		return fmt.Errorf("unknown tail %q", alias)
+	}
+	rr := t.rules
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	if paused {
+		rr.paused[alias] = true
+	} else {
+		delete(rr.paused, alias)
+	}
+	for tail, rt := range rr.running {
+		if rt.alias != alias {
+			continue
+		}
+		if paused {
+			tail.Pause()
+		} else {
+			tail.Resume()
+		}
+	}
+	return nil
+}
+
+// TailPaused reports whether the tail with the alias is paused by PauseTail
+func (t Terminal) TailPaused(alias string) bool {
+	rr := t.rules
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.paused[alias]
+}
@@ -0,0 +1,130 @@
+package tailer
+
+import (
+	"io"
+	"log"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+// DefaultSinkBuffer is the number of lines a sink of AddSink buffers
+const DefaultSinkBuffer = 1000
+
+// SinkOption configures a sink of AddSink
+type SinkOption func(*sink)
+
+// SinkBuffer sets the number of lines buffered for the sink, DefaultSinkBuffer by default
+func SinkBuffer(size int) SinkOption {
+	return func(s *sink) {
+		if size > 0 {
+			s.buffer = size
+		}
+	}
+}
+
+// SinkStripColors writes the lines to the sink without their color codes
+func SinkStripColors() SinkOption {
+	return func(s *sink) {
+		s.stripColors = true
+	}
+}
+
+// SinkPattern adds a pattern group the lines written to the sink must match,
+// like WithPattern: the patterns of a group must all match, and the groups
+// of several SinkPattern are OR'ed. Invalid patterns are ignored.
+func SinkPattern(patterns ...string) SinkOption {
+	return func(s *sink) {
+		var group Pattern
+		for _, pattern := range patterns {
+			if re, err := regexp.Compile(pattern); err == nil {
+				group = append(group, re)
+			}
+		}
+		s.patterns = append(s.patterns, group)
+	}
+}
+
+// sink writes the lines of a tail to a writer from its own goroutine
+type sink struct {
+	w           io.Writer
+	buffer      int
+	stripColors bool
+	patterns    []Pattern
+	c           chan string
+	closeOnce   sync.Once
+}
+
+// AddSink writes every line the tail emits, after its patterns, transforms
+// and plugins, to w followed by a newline, e.g. a file, a socket or a logging
+// backend, in addition to Lines. Each sink is written from its own goroutine
+// through its own buffer: the lines that find the buffer full are dropped and
+// counted as SinkDrops by Stats, so a slow sink never stalls the tail or the
+// other sinks. A sink whose write fails is removed and the error is logged.
+// The lines buffered when remove is called, or when the tail stops, are still written.
+func (tail *Tail) AddSink(w io.Writer, opts ...SinkOption) (remove func()) {
+	s := &sink{w: w, buffer: DefaultSinkBuffer}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.c = make(chan string, s.buffer)
+
+	tail.sinksMu.Lock()
+	tail.sinks = append(tail.sinks, s)
+	tail.sinksMu.Unlock()
+	go s.run(tail)
+
+	return func() {
+		tail.removeSink(s)
+	}
+}
+
+func (tail *Tail) removeSink(s *sink) {
+	tail.sinksMu.Lock()
+	tail.sinks = slices.DeleteFunc(tail.sinks, func(other *sink) bool { return other == s })
+	tail.sinksMu.Unlock()
+	s.closeOnce.Do(func() { close(s.c) })
+}
+
+// closeSinks removes all the sinks when the tail stops
+func (tail *Tail) closeSinks() {
+	tail.sinksMu.Lock()
+	sinks := tail.sinks
+	tail.sinks = nil
+	tail.sinksMu.Unlock()
+	for _, s := range sinks {
+		s.closeOnce.Do(func() { close(s.c) })
+	}
+}
+
+// fanOut offers the line to the sinks without waiting for them
+func (tail *Tail) fanOut(line string) {
+	tail.sinksMu.RLock()
+	defer tail.sinksMu.RUnlock()
+	for _, s := range tail.sinks {
+		if len(s.patterns) > 0 && !slices.ContainsFunc(s.patterns, func(p Pattern) bool { return p.Match(line) }) {
+			continue
+		}
+		select {
+		case s.c <- line:
+		default:
+			tail.statSinkDrops.Add(1)
+		}
+	}
+}
+
+func (s *sink) run(tail *Tail) {
+	for line := range s.c {
+		if s.stripColors {
+			line = StripAnsiCodes(line)
+		}
+		if _, err := io.WriteString(s.w, line+"\n"); err != nil {
+			log.Printf("tailer: removed a sink of %s after a failed write: %v", StripAnsiCodes(tail.label), err)
+			tail.removeSink(s)
+			// the lines still buffered are discarded
+			for range s.c {
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// TailerStream wraps EventSource for the tailer streams. On a connection error it
+// reconnects with exponential backoff instead of the native retry, resuming after
+// the last event id received (the byte offset of the line with permalinks) or,
+// without ids, in live mode, so that a reconnection does not replay the lines shown.
+// A stream rejected at capacity (503) is retried no sooner than its Retry-After,
+// and after a shutdown event of the server it reconnects every maxDelay.
+// A stream of follow=false ends with a complete event and is not reconnected.
+// The connection state is reported to onstate: connecting, live, stalled, error,
+// shutdown, complete or closed.
+//
+//   const stream = new TailerStream('watch.stream?file=app.log', {
+//       onstate: (state) => console.log(state),
+//   });
+//   stream.onmessage = (event) => console.log(event.data);
+//   stream.addEventListener('summary', (event) => console.log(event.data));
+class TailerStream {
+    constructor(url, options = {}) {
+        this.url = url;
+        this.minDelay = options.minDelay || 1000;
+        this.maxDelay = options.maxDelay || 30000;
+        this.onstate = options.onstate || null;
+        this.onopen = null;    // called with true on a reconnection
+        this.onmessage = null;
+        this.onshutdown = null; // called with the message of the server and the delay of the next attempt in ms
+        this.oncomplete = null; // called with the number of lines of a stream of follow=false
+        this.onerror = null;   // called with the delay of the next attempt in ms, 0 if none, the HTTP status of a rejection and the message of an error event
+        this.state = '';
+        this.lastEventId = '';
+        this.listeners = [];
+        this.attempts = 0;
+        this.connected = false;
+        this.closed = false;
+        this.source = null;
+        this.timer = null;
+        this.connect();
+    }
+
+    // addEventListener listens to the named events of the stream,
+    // on the current and the following connections
+    addEventListener(type, listener) {
+        const tracked = this.track(listener);
+        this.listeners.push([type, tracked]);
+        if (this.source) {
+            this.source.addEventListener(type, tracked);
+        }
+    }
+
+    close() {
+        this.closed = true;
+        clearTimeout(this.timer);
+        if (this.source) {
+            this.source.close();
+            this.source = null;
+        }
+        this.setState('closed');
+    }
+
+    setState(state) {
+        if (state !== this.state) {
+            this.state = state;
+            if (this.onstate) {
+                this.onstate(state);
+            }
+        }
+    }
+
+    // track remembers the id of the events passed to listener
+    track(listener) {
+        return (event) => {
+            if (event.lastEventId) {
+                this.lastEventId = event.lastEventId;
+            }
+            listener(event);
+        };
+    }
+
+    // resumeURL is the URL of a reconnection
+    resumeURL() {
+        const url = new URL(this.url, document.baseURI);
+        if (this.lastEventId) {
+            url.searchParams.set('lastEventId', this.lastEventId);
+        } else if (!url.searchParams.has('mode')) {
+            url.searchParams.set('mode', 'live');
+        }
+        return url.href;
+    }
+
+    connect() {
+        const reconnecting = this.connected;
+        this.setState('connecting');
+        const source = new EventSource(reconnecting ? this.resumeURL() : this.url);
+        this.source = source;
+        source.onopen = () => {
+            this.attempts = 0;
+            this.connected = true;
+            this.setState('live');
+            if (this.onopen) {
+                this.onopen(reconnecting);
+            }
+        };
+        source.onmessage = this.track((event) => {
+            if (this.onmessage) {
+                this.onmessage(event);
+            }
+        });
+        this.listeners.forEach(([type, listener]) => source.addEventListener(type, listener));
+        source.addEventListener('error', (event) => {
+            // an error event of the server, not of the connection, refuses the stream
+            // for good, e.g. a file that is not a text file
+            if (event.data === undefined || this.source !== source) {
+                return;
+            }
+            source.close();
+            this.source = null;
+            this.setState('error');
+            if (this.onerror) {
+                this.onerror(0, 0, JSON.parse(event.data).message);
+            }
+        });
+        source.addEventListener('stall', () => this.setState('stalled'));
+        source.addEventListener('resume', () => this.setState('live'));
+        source.addEventListener('shutdown', (event) => {
+            source.close();
+            if (this.closed || this.source !== source) {
+                return;
+            }
+            this.source = null;
+            this.setState('shutdown');
+            const info = JSON.parse(event.data);
+            // a restart takes a while, retry at the longest backoff until connected again
+            this.attempts = Math.ceil(Math.log2(this.maxDelay / this.minDelay));
+            if (this.onshutdown) {
+                this.onshutdown(info.message, this.maxDelay);
+            }
+            this.timer = setTimeout(() => this.connect(), this.maxDelay);
+        });
+        source.addEventListener('complete', (event) => {
+            source.close();
+            if (this.closed || this.source !== source) {
+                return;
+            }
+            this.closed = true;
+            this.source = null;
+            this.setState('complete');
+            if (this.oncomplete) {
+                this.oncomplete(JSON.parse(event.data).lines);
+            }
+        });
+        source.onerror = () => {
+            // a response that is not a stream, e.g. 503 of WithMaxClients, closes the source
+            const rejected = source.readyState === EventSource.CLOSED;
+            // the native retry would start over, reconnect with resume instead
+            source.close();
+            if (this.closed || this.source !== source) {
+                return;
+            }
+            this.source = null;
+            this.setState('error');
+            if (rejected) {
+                this.probe(source.url);
+            } else {
+                this.retry(0);
+            }
+        };
+    }
+
+    // probe requests url again to learn why the stream was rejected,
+    // EventSource does not tell the status
+    probe(url) {
+        const controller = new AbortController();
+        fetch(url, { signal: controller.signal, headers: { 'Accept': 'text/event-stream' } }).then((res) => {
+            controller.abort();
+            if (this.closed) {
+                return;
+            }
+            if (res.status === 503) {
+                // at capacity, no sooner than the server asks
+                const after = parseInt(res.headers.get('Retry-After'), 10) || 0;
+                this.retry(res.status, after * 1000);
+            } else if (res.ok || res.status >= 500) {
+                this.retry(res.status);
+            } else if (this.onerror) {
+                // a request the server will not accept again, e.g. an invalid filter
+                this.onerror(0, res.status);
+            }
+        }).catch(() => {
+            if (!this.closed) {
+                this.retry(0);
+            }
+        });
+    }
+
+    // retry reconnects after the backoff, at least after minDelay ms if given
+    retry(status, minDelay = 0) {
+        const backoff = Math.min(this.maxDelay, this.minDelay * 2 ** this.attempts++);
+        // jitter spreads the reconnections of many clients after a restart
+        const delay = Math.round(Math.max(backoff * (0.5 + Math.random() / 2), minDelay * (1 + Math.random() / 2)));
+        if (this.onerror) {
+            this.onerror(delay, status);
+        }
+        this.timer = setTimeout(() => this.connect(), delay);
+    }
+}
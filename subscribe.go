@@ -0,0 +1,152 @@
+package tailer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// SubscribeOption configures a subscription of Terminal.Subscribe
+type SubscribeOption func(*subscription)
+
+type subscription struct {
+	aliases  []string
+	buffer   int
+	dropping bool
+}
+
+// SubscribeTo subscribes to the tails with the aliases only, instead of all tails of the terminal
+func SubscribeTo(aliases ...string) SubscribeOption {
+	return func(s *subscription) {
+		s.aliases = append(s.aliases, aliases...)
+	}
+}
+
+// SubscribeBuffer sets the capacity of the channel of the subscription, 1000 by default
+func SubscribeBuffer(size int) SubscribeOption {
+	return func(s *subscription) {
+		s.buffer = size
+	}
+}
+
+// SubscribeDropWhenFull drops the lines that find the channel of the subscription full,
+// instead of waiting for the subscriber. A slow subscriber misses lines
+// but the tails keep up with the files.
+func SubscribeDropWhenFull() SubscribeOption {
+	return func(s *subscription) {
+		s.dropping = true
+	}
+}
+
+// Subscribe follows the tails of the terminal for Go code using the lines
+// without HTTP, e.g. an alerting layer. The lines go through the options
+// of the tails and the rules set by UpdateTail like those of the streams,
+// each subscription runs its own tails.
+//
+// By default a slow subscriber blocks: once the channel and the buffers of
+// the tails are full, reading stops until the subscriber catches up, and no
+// line is lost. With SubscribeDropWhenFull the lines are dropped instead.
+//
+// The channel is closed after the returned function is called, ctx is done
+// or the terminal is closed, in that case after the lines already read.
+// The function can be called more than once.
+func (t Terminal) Subscribe(ctx context.Context, opts ...SubscribeOption) (<-chan Line, func(), error) {
+	sub := subscription{buffer: 1000}
+	for _, opt := range opts {
+		opt(&sub)
+	}
+
+	selected := t.tails
+	if len(sub.aliases) > 0 {
+		selected = nil
+		for _, alias := range sub.aliases {
+			idx := slices.IndexFunc(t.tails, func(to TailOption) bool { return to.Alias == alias })
+			if idx < 0 {
+				return nil, nil, fmt.Errorf("unknown tail %q", alias)
+			}
+			selected = append(selected, t.tails[idx])
+		}
+	}
+	if len(selected) == 0 {
+		return nil, nil, fmt.Errorf("no tails to subscribe")
+	}
+
+	var tails []*Tail
+	var releases []func()
+	stopAll := func() {
+		for _, fn := range releases {
+			fn()
+		}
+		for _, tail := range tails {
+			tail.Stop()
+		}
+	}
+	for _, to := range selected {
+		tail := New(to.Filename, slices.Concat(defaultTailOptions, to.Options, []Option{WithByteOffset()})...).(*Tail)
+		if err := tail.Start(); err != nil {
+			stopAll()
+			return nil, nil, fmt.Errorf("failed to start tail %q: %w", to.Alias, err)
+		}
+		tails = append(tails, tail)
+		if t.rules != nil {
+			// rules updated by UpdateTail replace the configured ones
+			releases = append(releases, t.rules.register(tail, to.Alias, nil))
+		}
+	}
+
+	ch := make(chan Line, sub.buffer)
+	done := make(chan struct{})
+	var cancelOnce sync.Once
+	cancel := func() {
+		cancelOnce.Do(func() { close(done) })
+	}
+
+	send := func(alias string, text string) {
+		line := Line{Offset: -1, Text: text, File: alias}
+		if offset, rest, ok := cutOffset(text); ok {
+			line.Offset, line.Text = offset, rest
+		}
+		if sub.dropping {
+			select {
+			case ch <- line:
+			default:
+			}
+			return
+		}
+		select {
+		case ch <- line:
+		case <-done:
+		case <-ctx.Done():
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, tail := range tails {
+		alias := selected[i].Alias
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case line := <-tail.Lines():
+					send(alias, line)
+				case <-t.closeCh:
+					drain(tail, func(line string) { send(alias, line) })
+					return
+				case <-ctx.Done():
+					return
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		stopAll()
+		close(ch)
+	}()
+	return ch, cancel, nil
+}
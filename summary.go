@@ -0,0 +1,64 @@
+package tailer
+
+import "time"
+
+// levelSummary counts the detected log levels over a sliding window
+// in per-second buckets
+type levelSummary struct {
+	window  time.Duration
+	level   func(line string) string // detects the level of a line
+	buckets []levelBucket
+}
+
+type levelBucket struct {
+	sec    int64
+	counts map[string]int
+}
+
+func newLevelSummary(window time.Duration, level func(line string) string) *levelSummary {
+	n := int(window / time.Second)
+	if n < 1 {
+		n = 1
+	}
+	return &levelSummary{
+		window:  window,
+		level:   level,
+		buckets: make([]levelBucket, n),
+	}
+}
+
+// add counts the level of the line, lines without a level are ignored
+func (ls *levelSummary) add(now time.Time, line string) {
+	level := ls.level(line)
+	if level == "" {
+		return
+	}
+	sec := now.Unix()
+	b := &ls.buckets[sec%int64(len(ls.buckets))]
+	if b.sec != sec {
+		b.sec = sec
+		b.counts = map[string]int{}
+	}
+	b.counts[level]++
+}
+
+// counts returns the per-level counts within the window
+func (ls *levelSummary) counts(now time.Time) map[string]int {
+	ret := map[string]int{
+		LevelTrace: 0,
+		LevelDebug: 0,
+		LevelInfo:  0,
+		LevelWarn:  0,
+		LevelError: 0,
+	}
+	oldest := now.Unix() - int64(len(ls.buckets))
+	for _, b := range ls.buckets {
+		if b.sec <= oldest {
+			continue
+		}
+		for level, n := range b.counts {
+			ret[level] += n
+		}
+	}
+	return ret
+}
@@ -1,13 +1,21 @@
 package tailer
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,14 +25,81 @@ type ITail interface {
 	Lines() <-chan string
 }
 
+// StatsProvider is implemented by the tails reporting their runtime
+// statistics, like Tail and MultiTail. The web terminal uses them
+// for the activity, stall and adaptive flush of a stream.
+type StatsProvider interface {
+	Stats() Stats
+	IsActive() bool
+}
+
+// DefaultActiveWindow is the window within which a tail that read a line is active
+const DefaultActiveWindow = 10 * time.Second
+
+// Stats holds the runtime statistics of a tail
+type Stats struct {
+	Lines       int64     `json:"lines"`                 // number of lines sent to the channel
+	LastLine    time.Time `json:"lastLine,omitempty"`    // time of the last line sent, zero if none
+	LinesPerSec float64   `json:"linesPerSec"`           // throughput over the last few seconds
+	Error       string    `json:"error,omitempty"`       // why the file can not be read while it is retried, e.g. permission denied
+	StartOffset int64     `json:"startOffset,omitempty"` // byte offset the file was read from with WithTailBytes
+	Paused      bool      `json:"paused,omitempty"`      // the file is not read, see Pause
+	SinkDrops   int64     `json:"sinkDrops,omitempty"`   // lines dropped by the full buffers of the sinks, see AddSink
+}
+
+// rateWindow is the number of seconds the throughput is measured over
+const rateWindow = 5
+
+// rateMeter counts events in per-second buckets over a sliding window
+type rateMeter struct {
+	mu      sync.Mutex
+	buckets [rateWindow]int64
+	last    int64 // unix second of the latest bucket
+}
+
+func (m *rateMeter) add(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sec := max(now.Unix(), m.last)
+	m.advance(sec)
+	m.buckets[sec%rateWindow]++
+}
+
+// rate returns the average events per second over the window
+func (m *rateMeter) rate(now time.Time) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advance(now.Unix())
+	var sum int64
+	for _, n := range m.buckets {
+		sum += n
+	}
+	return float64(sum) / rateWindow
+}
+
+// advance clears the buckets that are older than the window
+func (m *rateMeter) advance(sec int64) {
+	if sec <= m.last {
+		return
+	}
+	gap := min(sec-m.last, rateWindow)
+	for i := int64(1); i <= gap; i++ {
+		m.buckets[(m.last+i)%rateWindow] = 0
+	}
+	m.last = sec
+}
+
 var _ ITail = (*Tail)(nil)
 var _ ITail = (*MultiTail)(nil)
+var _ StatsProvider = (*Tail)(nil)
+var _ StatsProvider = (*MultiTail)(nil)
 
 // MultiTail allows tailing multiple files and merging their output
 type MultiTail struct {
 	tails []ITail
 	wg    sync.WaitGroup
 	c     chan string
+	done  chan struct{} // closed by Stop, the lines no longer read are dropped
 }
 
 func NewMultiTail(tails ...ITail) ITail {
@@ -37,6 +112,7 @@ func NewMultiTail(tails ...ITail) ITail {
 	mt := &MultiTail{
 		tails: tails,
 		c:     make(chan string, buff),
+		done:  make(chan struct{}),
 	}
 	return mt
 }
@@ -68,7 +144,12 @@ func (mt *MultiTail) Start() error {
 				label = label + strings.Repeat(" ", aliasWidth-labelLen)
 			}
 			for line := range t.Lines() {
-				mt.c <- label + " " + line
+				select {
+				case mt.c <- label + " " + line:
+				case <-mt.done:
+					// the reader is gone, Stop does not wait for it
+					return
+				}
 			}
 		}(tail)
 	}
@@ -77,6 +158,7 @@ func (mt *MultiTail) Start() error {
 }
 
 func (mt *MultiTail) Stop() error {
+	close(mt.done)
 	var firstErr error
 	for _, tail := range mt.tails {
 		if err := tail.Stop(); err != nil && firstErr == nil {
@@ -93,29 +175,143 @@ func (mt *MultiTail) Lines() <-chan string {
 	return mt.c
 }
 
+// Stats returns the sum of the line counts and the latest line time
+// of all tails providing them
+func (mt *MultiTail) Stats() Stats {
+	var ret Stats
+	for _, tail := range mt.tails {
+		sp, ok := tail.(StatsProvider)
+		if !ok {
+			continue
+		}
+		st := sp.Stats()
+		ret.Lines += st.Lines
+		ret.LinesPerSec += st.LinesPerSec
+		if st.LastLine.After(ret.LastLine) {
+			ret.LastLine = st.LastLine
+		}
+		if ret.Error == "" {
+			ret.Error = st.Error
+		}
+	}
+	return ret
+}
+
+// IsActive reports whether any of the tails is active
+func (mt *MultiTail) IsActive() bool {
+	for _, tail := range mt.tails {
+		if sp, ok := tail.(StatsProvider); ok && sp.IsActive() {
+			return true
+		}
+	}
+	return false
+}
+
 // Tail provides functionality to tail a file
 // it works similar to 'tail -F' command in unix,
 // which follows the file even if it is rotated
 type Tail struct {
-	filepath     string
-	label        string // terminal display label for the file, it can contain ANSI color codes
-	c            chan string
-	stopChan     chan struct{}
-	pollInterval time.Duration
-	bufferSize   int
-	patterns     []Pattern
-	showLastN    int
-	plugins      []Plugin
-	file         *os.File
-	lastSize     int64
-	lastInode    uint64
-	lastPos      int64
-	wg           sync.WaitGroup
+	filepath        string
+	label           string // terminal display label for the file, it can contain ANSI color codes
+	c               chan string
+	stopChan        chan struct{}
+	pollInterval    time.Duration
+	watchMode       WatchMode // see WithWatchMode
+	notify          *notifier // nil unless WatchNotify watches the directory
+	bufferSize      int
+	readBufSize     int
+	rulesMu         sync.RWMutex // guards patterns and plugins, see UpdateOptions
+	patterns        []Pattern
+	showLastN       int
+	replaySince     time.Duration // see WithReplayDuration
+	lastBytes       int64         // see WithTailBytes
+	liveOnly        bool
+	resume          bool // resumeAfter is set, see WithResumeAfter
+	resumeAfter     int64
+	liveFrom        int64 // offset of the first byte written after Start in live only mode
+	plugins         []Plugin
+	history         []string
+	resolve         func() (string, error) // resolves the path to follow on every poll, if set
+	rotation        RotationScheme         // see WithRotationPattern
+	followLink      bool
+	multiline       *regexp.Regexp
+	pending         []string // lines of the multiline record being framed
+	pendingOffset   int64    // byte offset of the first line of pending
+	pendingPolls    int      // polls since the last line was added to pending
+	partial         []byte   // bytes after the last newline, already counted in lastPos
+	byteOffset      bool
+	ruleDebug       bool
+	timestampFormat string
+	levelField      string // JSON key of the level, see WithJSONLevelField
+	sanitize        bool
+	normalize       bool                       // see WithNormalizeNewlines
+	collapseBlank   bool                       // see WithCollapseBlankLines
+	lastBlank       bool                       // the last line was blank, see WithCollapseBlankLines
+	transforms      []func(line string) string // see WithTransform
+	coloring        string                     // replaces the coloring plugins, see WithColoringProfile
+	autoColoring    bool                       // see WithAutoColoring
+	autoProfile     string                     // the profile detected by WithAutoColoring
+	activeWindow    time.Duration
+	clock           clock            // the time source of the read loop, see withClock
+	statPath        statFunc         // stats the followed path on every poll, see withStatPath
+	compressed      *compressedState // set by WithCompressedPoll
+	fifo            bool             // the file is a named pipe, see runFIFO
+	command         *commandState    // set by WithCommand
+	restart         RestartPolicy    // see WithRestart
+	restartDelay    time.Duration    // delay before a restart
+	followFD        bool             // the open file is followed instead of the path, see WithFollowRotation
+	container       bool             // see WithContainerMode
+	containerPolls  int              // polls since the path was reopened in container mode
+	rotateDebounce  time.Duration    // see WithRotationDebounce
+	rotateInode     uint64           // inode of the path while a rotation is debounced
+	rotateSince     time.Time        // first poll the path showed rotateInode, zero if none
+	rotationMarker  bool             // see WithRotationMarker
+	reopenMin       time.Duration    // see WithMinReopenInterval
+	lastReopen      time.Time        // when the path was last reopened on a rotation
+	binaryMode      BinaryMode       // see WithBinaryMode
+	hexdump         bool             // the file looked binary, it is read as a hexdump
+	fifoMu          sync.Mutex       // guards file while following a named pipe
+	file            *os.File
+	lastSize        int64
+	lastInode       uint64
+	lastPos         int64
+	reopening       bool   // the file was closed by an error and is being reopened
+	reopenInode     uint64 // inode and position of the file closed by the error
+	reopenPos       int64
+	wg              sync.WaitGroup
+	statLines       atomic.Int64
+	statLastLine    atomic.Int64 // unix nano
+	statRate        rateMeter
+	statErr         atomic.Pointer[string] // the error retried by run, nil once the file is read again
+	statStart       atomic.Int64           // offset the file was read from, see WithTailBytes
+	statFile        atomic.Pointer[string] // the path of the file open, or to open, set by Start, see FileInfo
+	statOpens       atomic.Int64           // the files opened, another one on rotation
+	paused          atomic.Bool            // see Pause
+	sinksMu         sync.RWMutex           // guards sinks
+	sinks           []*sink                // see AddSink
+	statSinkDrops   atomic.Int64
 }
 
 type Pattern []*regexp.Regexp
 
+// String returns the expressions of the group joined by "&&",
+// the same form as the filter parameter of the web terminal.
+func (p Pattern) String() string {
+	exprs := make([]string, len(p))
+	for i, re := range p {
+		exprs[i] = re.String()
+	}
+	return strings.Join(exprs, "&&")
+}
+
+// Match reports whether all the expressions of the group match s.
+// They match the text of the line without its color codes, so that a filter
+// finds the keywords colored by the application that wrote the log,
+// e.g. "level=ERROR" matches "level=\x1b[31mERROR\x1b[0m".
 func (p Pattern) Match(s string) bool {
+	if strings.IndexByte(s, '\x1b') >= 0 {
+		s = StripAnsiCodes(s)
+	}
 	matched := true
 	for _, re := range p {
 		if !re.MatchString(s) {
@@ -136,12 +332,79 @@ func WithPollInterval(d time.Duration) Option {
 	}
 }
 
+// WatchMode selects how the tail notices the changes of the file, see WithWatchMode
+type WatchMode int
+
+const (
+	// WatchPoll checks the file every poll interval, see WithPollInterval
+	WatchPoll WatchMode = iota
+	// WatchNotify checks the file as soon as the system notifies a change
+	// in its directory, and still every poll interval
+	WatchNotify
+)
+
+// WithWatchMode sets how the tail notices the changes of the file, WatchPoll
+// by default. With WatchNotify the lines are read as soon as they are written,
+// woken by inotify on Linux, and the polls continue every WithPollInterval as
+// the fallback of the filesystems that do not notify the writes, like NFS or
+// the lower layers of overlayfs, so a longer interval saves the polls of
+// quiet logs. inotify is the only notification supported: on macOS, Windows
+// and the other systems, or when inotify can not watch the directory,
+// e.g. out of watches, the tail falls back to polling only.
+func WithWatchMode(mode WatchMode) Option {
+	return func(t *Tail) {
+		t.watchMode = mode
+	}
+}
+
+// WithBufferSize sets the capacity of the channel of Lines. Once it is full,
+// e.g. before a consumer reads it, the tail waits for the consumer: no line
+// is dropped and the file is read on where it was left. See Lines.
 func WithBufferSize(size int) Option {
 	return func(t *Tail) {
 		t.bufferSize = size
 	}
 }
 
+// WithReadBufferSize sets the size of the buffer used for each read from the file.
+// It is independent of WithBufferSize, which sizes the lines channel.
+// Larger buffers mean fewer read calls on busy logs, see BenchmarkTail.
+func WithReadBufferSize(size int) Option {
+	return func(t *Tail) {
+		if size > 0 {
+			t.readBufSize = size
+		}
+	}
+}
+
+// WithActiveWindow sets the window within which the tail is active
+// after reading a line, see IsActive. The default is DefaultActiveWindow.
+func WithActiveWindow(d time.Duration) Option {
+	return func(t *Tail) {
+		if d > 0 {
+			t.activeWindow = d
+		}
+	}
+}
+
+// WithServerTimestamp prefixes every record with the time it was read,
+// formatted with the time layout, before the coloring plugins run.
+// A multiline record is stamped once.
+func WithServerTimestamp(layout string) Option {
+	return func(t *Tail) {
+		t.timestampFormat = layout
+	}
+}
+
+// WithRuleDebug sends every line as a JSON RuleFrame naming the rules that
+// matched it, the pattern groups of WithPattern and the transforms and plugins that changed it.
+// It is meant for building and tuning filter and coloring configurations.
+func WithRuleDebug() Option {
+	return func(t *Tail) {
+		t.ruleDebug = true
+	}
+}
+
 func WithPattern(patterns ...string) Option {
 	return func(t *Tail) {
 		var group Pattern
@@ -155,12 +418,49 @@ func WithPattern(patterns ...string) Option {
 	}
 }
 
+// WithLast sends the last n lines of the file on Start, 0 for none.
+// The tail continues at the end of the file as Start read it, not at the end
+// when it returns, so no line appended by an active writer during Start is lost,
+// and a line still being written is sent once its newline arrives.
 func WithLast(n int) Option {
 	return func(t *Tail) {
 		t.showLastN = n
 	}
 }
 
+// WithTailBytes sends the lines of the last n bytes of the file on Start,
+// like 'tail -c', instead of the last lines of WithLast. The start snaps forward
+// to the next line so that the first line is not partial, unless no line ends
+// in the last n bytes. The offset it starts at is reported by Stats.
+// It applies to regular files, not to compressed files or named pipes.
+func WithTailBytes(n int64) Option {
+	return func(t *Tail) {
+		t.lastBytes = n
+	}
+}
+
+// WithResumeAfter continues a stream after the line starting at the byte offset,
+// the last one a client received (see WithByteOffset), instead of the last lines
+// or the history. If the file is now shorter than the offset, it was truncated
+// or rotated and the tail starts as configured.
+func WithResumeAfter(offset int64) Option {
+	return func(t *Tail) {
+		if offset >= 0 {
+			t.resumeAfter = offset
+			t.resume = true
+		}
+	}
+}
+
+// WithLiveOnly sends only the lines written after Start, for privacy:
+// no last lines, no history, and a line already in progress at Start is dropped.
+// It takes precedence over WithLast and WithHistory.
+func WithLiveOnly() Option {
+	return func(t *Tail) {
+		t.liveOnly = true
+	}
+}
+
 func WithLabel(label string) Option {
 	return func(t *Tail) {
 		t.label = label
@@ -173,53 +473,422 @@ func WithSyntaxHighlighting(syntax ...string) Option {
 	}
 }
 
+// WithSyntaxColoring is WithSyntaxHighlighting, colors the lines with the Profiles
+// of the syntax names in order, e.g. WithSyntaxColoring("logfmt")
+func WithSyntaxColoring(syntax ...string) Option {
+	return WithSyntaxHighlighting(syntax...)
+}
+
+// WithSanitizeControl removes the control characters and escape sequences of the lines
+// that would move the cursor or clear the web terminal, keeping the colors, see SanitizeControl.
+// It applies before the patterns and plugins, so their own color codes are kept.
+func WithSanitizeControl() Option {
+	return func(t *Tail) {
+		t.sanitize = true
+	}
+}
+
+// WithNormalizeNewlines emits clean lines for display and archival (WithTee):
+// the UTF-8 byte order marks are removed, a leading one of the file included,
+// and a carriage return is a line ending, whether followed by a newline
+// (CRLF) or alone (classic Mac OS), so mixed line endings become separate lines.
+// It applies before the multiline framing and the coloring.
+func WithNormalizeNewlines() Option {
+	return func(t *Tail) {
+		t.normalize = true
+	}
+}
+
+// WithCollapseBlankLines collapses the runs of empty or whitespace-only lines
+// to a single empty line, e.g. the bursts of blank lines some logs emit.
+// It applies after WithNormalizeNewlines and before the multiline framing.
+func WithCollapseBlankLines() Option {
+	return func(t *Tail) {
+		t.collapseBlank = true
+	}
+}
+
+// WithTransform rewrites each record before it is filtered and colored,
+// e.g. a JSON log reformatted to "level | time | msg". Returning an empty
+// string drops the record. The stages of a record run in this order:
+// multiline framing, WithSanitizeControl, the transforms in the order given,
+// the patterns of WithPattern and the request filter, WithServerTimestamp,
+// then the plugins, coloring included.
+func WithTransform(fn func(line string) string) Option {
+	return func(t *Tail) {
+		t.transforms = append(t.transforms, fn)
+	}
+}
+
+// WithJSONLevelField reads the level of JSON lines from the value of the key,
+// e.g. {"level":"error"}, and colors the value by it, see NewJSONLevelColoring.
+// The level summary and the NDJSON stream of the web terminal detect the levels
+// of the tail the same way, for the event types see JSONLevelEventType.
+// Lines that are not JSON fall back to the keyword detection of DetectLevel.
+func WithJSONLevelField(key string) Option {
+	return func(t *Tail) {
+		t.levelField = key
+		t.plugins = append(t.plugins, NewJSONLevelColoring(key))
+	}
+}
+
+// WithThresholdColor colors the number captured by re according to the thresholds,
+// e.g. a latency green below 100, yellow up to 500 and red above, see NewThresholdColoring.
+func WithThresholdColor(re *regexp.Regexp, thresholds ...Threshold) Option {
+	return func(t *Tail) {
+		t.plugins = append(t.plugins, NewThresholdColoring(re, thresholds...))
+	}
+}
+
+// WithColorRuleGroups colors the named capture groups of re in one pass, each
+// with its color by name, e.g. the time dim, the level by its severity with
+// ColorByLevel and the request id cyan, see NewColorRuleGroups.
+func WithColorRuleGroups(re *regexp.Regexp, colors map[string]string) Option {
+	return func(t *Tail) {
+		t.plugins = append(t.plugins, NewColorRuleGroups(re, colors))
+	}
+}
+
+// WithColoringProfile replaces the coloring of the tail, the plugins of
+// WithSyntaxHighlighting, WithJSONLevelField, WithThresholdColor,
+// WithColorRuleGroups, Profiles and ColorPipelines, by the profile of Profiles with the name, or removes it
+// for "none". The other plugins are kept. Unknown names keep the coloring.
+// It also applies to the plugins set by UpdateOptions.
+func WithColoringProfile(name string) Option {
+	return func(t *Tail) {
+		t.coloring = strings.ToLower(name)
+	}
+}
+
 func WithPlugins(p ...Plugin) Option {
 	return func(t *Tail) {
 		t.plugins = append(t.plugins, p...)
 	}
 }
 
+// WithHistory streams the given rotated files in order (oldest first)
+// before following the live file. Files ending with ".gz" are decompressed.
+// When history is set, the live file is read from its beginning
+// instead of showing the last N lines.
+// Use RotatedFiles to derive the list from the live filename.
+func WithHistory(files ...string) Option {
+	return func(t *Tail) {
+		t.history = append(t.history, files...)
+	}
+}
+
+// WithFollowSymlink resolves the tailed path as a symlink on every poll
+// and follows its target. When the symlink is repointed
+// (e.g. current -> app-2024-06-01.log), the remaining lines of the old target
+// are read before switching to the new target from its beginning.
+func WithFollowSymlink() Option {
+	return func(t *Tail) {
+		t.followLink = true
+	}
+}
+
+// WithContainerMode follows a log bind-mounted into a container and rotated
+// by the host, such as the kubelet rotating 0.log to 0.log.<timestamp>, where
+// the inodes seen through the mount are not reliable. Besides an inode change,
+// a rotation is suspected when the open file and the path disagree on size or
+// modification time, and the path is reopened every few polls; the first bytes
+// of both files tell a new file from the same one, so a reused or changing
+// inode neither stops the tail nor sends the file again.
+func WithContainerMode() Option {
+	return func(t *Tail) {
+		t.container = true
+	}
+}
+
+// WithFollowRotation sets whether the tail follows the path across rotations,
+// as it does by default: when the file is renamed or removed and a new one
+// is created at the path, like logrotate does, the rest of the old file is
+// read and the new file is followed from its beginning, and a truncated file
+// is read again from its beginning, without the stream being interrupted.
+// With false the open file is followed wherever it is renamed to, like
+// tail --follow=descriptor, and the new file at the path is ignored;
+// its truncation is still followed.
+func WithFollowRotation(follow bool) Option {
+	return func(t *Tail) {
+		t.followFD = !follow
+	}
+}
+
+// WithRotationDebounce acts on a rotation only once the path has shown
+// the same new file for d, instead of on the first poll that sees it.
+// Meanwhile the lines appended to the open file are read as usual.
+// Filesystems presenting inconsistent stat results during a rotation
+// then reopen the file once, not on every flap, and send no line twice.
+func WithRotationDebounce(d time.Duration) Option {
+	return func(t *Tail) {
+		t.rotateDebounce = d
+	}
+}
+
+// WithMinReopenInterval reopens the path on a rotation at most once per d,
+// protecting the host from the churn of the file descriptors when the logs
+// rotate every few seconds. Until then the lines appended to the open file,
+// the rotated one, are still read, and the new file is read from its beginning
+// once reopened. A file rotated away and back within d, never opened, is skipped.
+func WithMinReopenInterval(d time.Duration) Option {
+	return func(t *Tail) {
+		t.reopenMin = d
+	}
+}
+
+// WithRotationMarker sends a marker line when the tail continues with a new file,
+// e.g. "──── rotated: app.log → app.log.1 at 2026-10-15T12:00:00Z ────",
+// naming the file the old one was renamed to if it is found next to the path.
+// The tails following the latest file, such as NewLatest, mark their switches too.
+// The marker is dimmed unless the coloring is NoColoring, and the patterns
+// do not apply to it, so the context break is visible in filtered streams.
+func WithRotationMarker() Option {
+	return func(t *Tail) {
+		t.rotationMarker = true
+	}
+}
+
+// WithByteOffset prefixes each line with its byte offset in the file
+// followed by a colon, like 'grep -b'. Every line is prefixed: the lines
+// without a known offset, like those of history archives, compressed files
+// and rotation markers, are prefixed with -1.
+func WithByteOffset() Option {
+	return func(t *Tail) {
+		t.byteOffset = true
+	}
+}
+
+// WithCompressedPoll follows a gzip compressed file that is rewritten in place
+// by re-reading it from the start on every poll and sending only the lines
+// beyond those already sent. If the file is replaced by one with fewer lines,
+// all of its lines are sent. An incomplete last line or a truncated gzip stream
+// waits for the next poll.
+// It decompresses the whole file on every change, so it is meant for small logs.
+func WithCompressedPoll() Option {
+	return func(t *Tail) {
+		t.compressed = &compressedState{}
+	}
+}
+
+// WithCommand follows the combined stdout and stderr of a command run by Start
+// instead of a file, the path of the tail is only its name. Stop kills the command
+// and the processes it started. When it exits it is run again as WithRestart tells,
+// its exit error is reported by Stats meanwhile. Like a named pipe, there is
+// no history or rotation, and WithLast does not apply.
+func WithCommand(name string, args ...string) Option {
+	return WithCommandContext(context.Background(), name, args...)
+}
+
+// WithCommandContext is WithCommand with a context, when it is done the command
+// is killed and not run again, the tail receives no more lines until Stop
+func WithCommandContext(ctx context.Context, name string, args ...string) Option {
+	return func(t *Tail) {
+		t.command = &commandState{ctx: ctx, name: name, args: args}
+	}
+}
+
+// WithRestart sets when the command of WithCommand is run again after it exits,
+// and the delay before, DefaultRestartDelay if not positive.
+func WithRestart(policy RestartPolicy, delay time.Duration) Option {
+	return func(t *Tail) {
+		if delay <= 0 {
+			delay = DefaultRestartDelay
+		}
+		t.restart, t.restartDelay = policy, delay
+	}
+}
+
+// WithMultilinePattern frames multiline log entries such as stack traces.
+// A line matching startRe begins a new record, subsequent non-matching lines
+// are appended to it, and the whole record is sent as one item
+// with its lines joined by "\n". Patterns and plugins apply to the whole record.
+// A record is complete when the next one begins, when no more lines arrive
+// within a poll interval, or when the tail stops.
+func WithMultilinePattern(startRe *regexp.Regexp) Option {
+	return func(t *Tail) {
+		t.multiline = startRe
+	}
+}
+
 // New creates Tail instance
 func New(filename string, opts ...Option) ITail {
 	t := &Tail{
 		filepath:     filename,
 		label:        filepath.Base(filename),
 		bufferSize:   100,
+		readBufSize:  4096,
 		stopChan:     make(chan struct{}),
 		pollInterval: 1 * time.Second,
 		showLastN:    10,
+		activeWindow: DefaultActiveWindow,
+		clock:        realClock{},
+		statPath:     os.Stat,
 	}
 
 	for _, opt := range opts {
 		opt(t)
 	}
 
+	t.plugins = t.recolor(t.plugins)
+
+	if t.followLink {
+		t.resolve = func() (string, error) {
+			return filepath.EvalSymlinks(filename)
+		}
+	} else if t.rotation != nil {
+		t.resolve = func() (string, error) {
+			live, _ := t.rotation.Files(filename)
+			return live, nil
+		}
+	}
+
 	t.c = make(chan string, t.bufferSize)
 	return t
 }
 
 // Lines returns output channel
-// caller can read lines from this channel
+// caller can read lines from this channel.
+// The tail starts reading on Start, whether or not the channel is read yet:
+// the lines fill its buffer of WithBufferSize, then the tail waits for the
+// reader, so the lines of WithHistory, WithLast and the live ones written
+// meanwhile are all delivered in order once it reads. Only the sinks of
+// AddSink and the subscriptions of SubscribeDropWhenFull drop lines.
 func (tail *Tail) Lines() <-chan string {
 	return tail.c
 }
 
+// Stats returns the runtime statistics of the tail
+func (tail *Tail) Stats() Stats {
+	ret := Stats{
+		Lines:       tail.statLines.Load(),
+		LinesPerSec: tail.statRate.rate(tail.clock.Now()),
+		StartOffset: tail.statStart.Load(),
+	}
+	if ts := tail.statLastLine.Load(); ts != 0 {
+		ret.LastLine = time.Unix(0, ts)
+	}
+	if err := tail.statErr.Load(); err != nil {
+		ret.Error = *err
+	}
+	ret.Paused = tail.paused.Load()
+	ret.SinkDrops = tail.statSinkDrops.Load()
+	return ret
+}
+
+// Pause stops reading the file, the lines appended meanwhile wait in the file
+// instead of being read and buffered, e.g. to relieve a host from a runaway log.
+// Resume continues where the tail left off. Named pipes and commands are not paused.
+func (tail *Tail) Pause() {
+	tail.paused.Store(true)
+}
+
+// Resume continues reading the file paused by Pause from where it left off.
+// The next poll reconciles the offset with the file as usual: the rest of a file
+// rotated meanwhile is read before the new one, a truncated file from its start.
+func (tail *Tail) Resume() {
+	tail.paused.Store(false)
+}
+
+// IsActive reports whether a line was read within the active window,
+// see WithActiveWindow.
+func (tail *Tail) IsActive() bool {
+	ts := tail.statLastLine.Load()
+	return ts != 0 && tail.clock.Now().Sub(time.Unix(0, ts)) < tail.activeWindow
+}
+
 // Start begins tailing the file
 func (tail *Tail) Start() error {
+	if tail.resolve != nil {
+		target, err := tail.resolve()
+		if err != nil {
+			return err
+		}
+		tail.filepath = target
+	}
+	// the path described by fileInfos until a file is open,
+	// tail.filepath changes on the run goroutine
+	path := tail.filepath
+	tail.statFile.Store(&path)
+
+	if tail.liveOnly {
+		tail.showLastN = 0
+		tail.replaySince = 0
+		tail.lastBytes = 0
+		tail.history = nil
+	}
+
+	if tail.compressed != nil {
+		if err := tail.readCompressed(tail.showLastN); err != nil {
+			return err
+		}
+		tail.wg.Add(1)
+		go tail.run()
+		return nil
+	}
+
+	if tail.command != nil {
+		return tail.startCommand()
+	}
+
+	if isFIFO(tail.filepath) {
+		tail.fifo = true
+		tail.wg.Add(1)
+		go tail.runFIFO()
+		return nil
+	}
+
 	// Open the file initially
 	if err := tail.openFile(); err != nil {
 		return err
 	}
+	if binary, err := tail.sniffBinary(); err != nil || binary && tail.binaryMode != BinaryHexdump {
+		tail.file.Close()
+		if err == nil {
+			err = fmt.Errorf("%w: %s", ErrBinaryFile, tail.filepath)
+		}
+		return err
+	} else if binary {
+		tail.hexdump = true
+	} else if tail.autoColoring {
+		tail.detectColoring()
+	}
 
-	// Read last 10 lines before starting to tail
-	if err := tail.readLastLines(tail.showLastN); err != nil {
-		// If we can't read last lines, just seek to end
-		pos, seekErr := tail.file.Seek(0, io.SeekEnd)
-		if seekErr != nil {
-			tail.file.Close()
-			return fmt.Errorf("failed to seek to end: %w", seekErr)
+	// Watch before the initial read, so that the lines written
+	// while it reads wake the first check of run()
+	if tail.watchMode == WatchNotify {
+		if n, err := newNotifier(tail.filepath); err == nil {
+			tail.notify = n
 		}
-		tail.lastPos = pos
+	}
+
+	// Read last 10 lines before starting to tail,
+	// history and the whole live file are streamed by run() instead
+	if tail.hexdump {
+		if err := tail.seekHexdump(tail.showLastN); err != nil {
+			return tail.failStart(err)
+		}
+		tail.readLines()
+	} else if tail.resume && tail.resumeAfter < tail.lastSize {
+		if err := tail.skipLine(tail.resumeAfter); err != nil {
+			return tail.failStart(err)
+		}
+		tail.history = nil
+	} else if len(tail.history) > 0 {
+		tail.lastPos = 0
+	} else if tail.lastBytes > 0 {
+		if err := tail.seekLastBytes(tail.lastBytes); err != nil {
+			return tail.failStart(err)
+		}
+	} else if err := tail.readLastLines(tail.showLastN); err != nil {
+		// If we can't read last lines, continue at the size seen on open
+		// instead of the end, the lines appended since are read by the first poll
+		if _, seekErr := tail.file.Seek(tail.lastSize, io.SeekStart); seekErr != nil {
+			return tail.failStart(fmt.Errorf("failed to seek: %w", seekErr))
+		}
+		tail.lastPos = tail.lastSize
+	}
+	if tail.liveOnly {
+		tail.liveFrom = tail.lastPos
 	}
 
 	tail.wg.Add(1)
@@ -228,7 +897,82 @@ func (tail *Tail) Start() error {
 	return nil
 }
 
-// readLastLines reads the last n lines from the file and sends them to the channel
+// failStart closes the file and the notifier opened by Start, and returns err
+func (tail *Tail) failStart(err error) error {
+	tail.file.Close()
+	if tail.notify != nil {
+		tail.notify.close()
+	}
+	return err
+}
+
+// skipLine positions the tail after the end of the line at offset,
+// the line itself and its newline are not sent again
+func (tail *Tail) skipLine(offset int64) error {
+	if _, err := tail.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek: %w", err)
+	}
+	br := bufio.NewReaderSize(tail.file, tail.readBufSize)
+	pos := offset
+	for {
+		chunk, err := br.ReadSlice('\n')
+		pos += int64(len(chunk))
+		if err == nil {
+			break
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			// not a line sent before, the file was rewritten, send it
+			pos = offset
+			break
+		}
+		return fmt.Errorf("failed to read: %w", err)
+	}
+	if _, err := tail.file.Seek(pos, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek: %w", err)
+	}
+	// the lines written since are read by the next poll
+	tail.lastPos = pos
+	tail.lastSize = pos
+	return nil
+}
+
+// seekLastBytes positions the tail at the first line starting in the last n bytes
+func (tail *Tail) seekLastBytes(n int64) error {
+	start := max(tail.lastSize-n, 0)
+	if start > 0 {
+		// a line starts at start if the byte before it is a newline
+		if err := tail.skipLine(start - 1); err != nil {
+			return err
+		}
+	}
+	if tail.lastPos < start || start == 0 {
+		// the start of the file, or the middle of a line if none ends in the last n bytes
+		if _, err := tail.file.Seek(start, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek: %w", err)
+		}
+		tail.lastPos = start
+	}
+	// the lines from there are read by the first poll
+	tail.lastSize = tail.lastPos
+	tail.statStart.Store(tail.lastPos)
+	return nil
+}
+
+// lastLinesBlockSize is the size of the blocks readLastLines reads backward from the end
+var lastLinesBlockSize = 64 * 1024
+
+// maxLastLineSize is the length readLastLines and History scan back for the beginning of a line,
+// a longer line starts at this length from its end and the lines before are not read
+const maxLastLineSize = 1024 * 1024
+
+// readLastLines reads the last n lines from the file and sends them to the channel.
+// The file is read backward in blocks from the end until n lines are found,
+// so the cost depends on the lines read, not on the size of the file.
+// With WithReplayDuration, the lines are read back to the first one older
+// than the duration, and the last n lines are kept if none has a timestamp.
 func (tail *Tail) readLastLines(n int) error {
 	stat, err := tail.file.Stat()
 	if err != nil {
@@ -241,110 +985,90 @@ func (tail *Tail) readLastLines(n int) error {
 		return nil
 	}
 
-	// Buffer to read file in chunks from the end
-	const chunkSize = 4096
-	var allData []byte
-	bytesToRead := fileSize
-
-	// Limit how much we read (don't read more than necessary)
-	maxRead := int64(chunkSize * 4) // Read up to 16KB max
-	if bytesToRead > maxRead {
-		bytesToRead = maxRead
+	var lines []string  // newest first
+	var offsets []int64 // offsets of the lines
+	var partial []byte
+	var data []byte // bytes from cur to the end of the region not split into lines yet
+	cur := fileSize
+	atEnd := true // the next line split is the last one of the file
+	limit := n
+	var since time.Time // the lines before are not sent, see WithReplayDuration
+	stamped := false    // a line had a timestamp
+	if tail.replaySince > 0 {
+		limit = maxReplayLines
+		since = tail.clock.Now().Add(-tail.replaySince)
 	}
-
-	// Seek to position
-	offset := fileSize - bytesToRead
-	if _, err := tail.file.Seek(offset, io.SeekStart); err != nil {
-		return fmt.Errorf("failed to seek: %w", err)
-	}
-
-	// Read the data
-	buf := make([]byte, bytesToRead)
-	readBytes, err := io.ReadFull(tail.file, buf)
-	if err != nil && err != io.ErrUnexpectedEOF {
-		return fmt.Errorf("failed to read: %w", err)
-	}
-	allData = buf[:readBytes]
-
-	// Split into lines
-	var lines []string
-	var lineStart int
-
-	for i := 0; i < len(allData); i++ {
-		if allData[i] == '\n' {
-			line := string(allData[lineStart:i])
-			// Trim \r if present
-			if len(line) > 0 && line[len(line)-1] == '\r' {
-				line = line[:len(line)-1]
+	older := false // a line older than since was reached
+	for (len(lines) < limit && !older || atEnd) && (cur > 0 || len(data) > 0) {
+		if cur > 0 {
+			size := min(int64(lastLinesBlockSize), cur)
+			cur -= size
+			block := make([]byte, size, size+int64(len(data)))
+			if _, err := tail.file.ReadAt(block, cur); err != nil && !errors.Is(err, io.EOF) {
+				return fmt.Errorf("failed to read: %w", err)
+			}
+			data = append(block, data...)
+		}
+		// split the lines from the end, the first one may continue in the previous block
+		for (len(lines) < limit && !older || atEnd) && len(data) > 0 {
+			i := bytes.LastIndexByte(data[:len(data)-1], '\n')
+			if i < 0 && cur > 0 && len(data) <= maxLastLineSize {
+				break
+			}
+			seg, offset := data[i+1:], cur+int64(i)+1
+			data = data[:i+1]
+			if len(seg) > maxLastLineSize {
+				// no beginning within the cap, e.g. a file without newlines
+				seg, offset = seg[len(seg)-maxLastLineSize:], offset+int64(len(seg)-maxLastLineSize)
+				data, cur = nil, 0
+			}
+			if atEnd {
+				atEnd = false
+				// The last line without a newline is kept until the newline arrives
+				if seg[len(seg)-1] != '\n' {
+					partial = slices.Clone(seg)
+					continue
+				}
+			}
+			if len(lines) >= limit {
+				break
+			}
+			line := trimCR(strings.TrimSuffix(string(seg), "\n"))
+			if !since.IsZero() {
+				if ts, ok := ParseLineTime(line); ok {
+					stamped = true
+					if ts.Before(since) {
+						older = true
+						break
+					}
+				}
 			}
 			if len(line) > 0 { // Skip empty lines
 				lines = append(lines, line)
+				offsets = append(offsets, offset)
 			}
-			lineStart = i + 1
 		}
 	}
 
-	// Handle last line if file doesn't end with newline
-	if lineStart < len(allData) {
-		line := string(allData[lineStart:])
-		if len(line) > 0 && line[len(line)-1] == '\r' {
-			line = line[:len(line)-1]
-		}
-		if len(line) > 0 {
-			lines = append(lines, line)
-		}
-	}
-
-	// Keep only last n lines
-	startIdx := 0
-	if len(lines) > n {
-		startIdx = len(lines) - n
+	if !since.IsZero() && !stamped && len(lines) > n {
+		// no timestamps, the last lines instead
+		lines, offsets = lines[:n], offsets[:n]
 	}
-	lines = lines[startIdx:]
 
 	// Send lines to channel (in correct order)
-	for _, line := range lines {
-		matched := false
-		if len(tail.patterns) > 0 {
-			for _, p := range tail.patterns {
-				if p.Match(line) {
-					matched = true
-					break
-				}
-			}
-		} else {
-			matched = true
-		}
-
-		if !matched {
-			continue
-		}
-		for _, plugin := range tail.plugins {
-			if ln, ok := plugin.Apply(line); ok {
-				line = ln
-			} else {
-				// Plugin indicated to drop the line
-				matched = false
-				break
-			}
-		}
-
-		if !matched {
-			continue
-		}
-		select {
-		case tail.c <- line:
-		case <-tail.stopChan:
+	for i := len(lines) - 1; i >= 0; i-- {
+		if !tail.emit(lines[i], offsets[i]) {
 			return nil
 		}
 	}
 
-	// Seek to end and update position
-	pos, err := tail.file.Seek(0, io.SeekEnd)
-	if err != nil {
-		return fmt.Errorf("failed to seek to end: %w", err)
+	// Continue at the end of the file as read, the partial line included,
+	// so that the lines appended while Start runs are read by the first poll
+	if _, err := tail.file.Seek(fileSize, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek: %w", err)
 	}
-	tail.lastPos = pos
+	tail.lastPos = fileSize
+	tail.partial = partial
 
 	return nil
 }
@@ -353,10 +1077,17 @@ func (tail *Tail) readLastLines(n int) error {
 func (tail *Tail) Stop() error {
 	close(tail.stopChan)
 
+	if tail.fifo {
+		tail.waitFIFO()
+	}
+	if tail.command != nil {
+		tail.interruptCommand()
+	}
 	// Wait for goroutine to finish before closing the channel
 	tail.wg.Wait()
 
 	close(tail.c)
+	tail.closeSinks()
 
 	if tail.file != nil {
 		return tail.file.Close()
@@ -379,9 +1110,15 @@ func (tail *Tail) openFile() error {
 	}
 
 	tail.file = file
+	path := tail.filepath
+	tail.statFile.Store(&path)
+	tail.statOpens.Add(1)
 	tail.lastSize = stat.Size()
 	tail.lastInode = getInode(stat)
 	tail.lastPos = 0
+	tail.partial = nil
+	// all of another file is written after Start
+	tail.liveFrom = 0
 
 	return nil
 }
@@ -389,26 +1126,82 @@ func (tail *Tail) openFile() error {
 // run is the main loop that tails the file
 func (tail *Tail) run() {
 	defer tail.wg.Done()
-	ticker := time.NewTicker(tail.pollInterval)
+	if len(tail.history) > 0 {
+		if !tail.readHistory() {
+			return
+		}
+	}
+	ticker := tail.clock.NewTicker(tail.pollInterval)
 	defer ticker.Stop()
+	// the notifications of WithWatchMode wake the tail between the polls,
+	// without them it only polls
+	var wake <-chan struct{}
+	if tail.notify != nil {
+		defer tail.notify.close()
+		wake = tail.notify.c
+	}
 
 	for {
+		// polled is false when a notification woke the tail
+		polled := false
 		select {
 		case <-tail.stopChan:
+			tail.sendTrailing()
 			return
-		case <-ticker.C:
-			if err := tail.checkAndRead(); err != nil {
-				// If there's an error, try to reopen the file (might be rotated)
-				if tail.file != nil {
-					tail.file.Close()
-				}
-
-				// Wait a bit and try to open again
-				time.Sleep(tail.pollInterval)
-				if err := tail.reopenIfNeeded(); err != nil {
-					// Still can't open, continue waiting
-					continue
-				}
+		case <-ticker.C():
+			polled = true
+		case <-wake:
+		}
+		if tail.paused.Load() {
+			continue
+		}
+		var err error
+		if tail.compressed != nil {
+			// errors are retried on the next poll
+			tail.readCompressed(-1)
+		} else {
+			err = tail.checkAndRead()
+		}
+		if len(tail.pending) > 0 && polled {
+			// complete the record if no line was added during this poll
+			if tail.pendingPolls > 0 {
+				tail.flushPending()
+			} else {
+				tail.pendingPolls++
+			}
+		}
+		if err != nil {
+			// If there's an error, try to reopen the file (might be rotated)
+			if tail.file != nil && !tail.reopening {
+				// the file is closed, its partial last line is complete
+				tail.flushPartial()
+				tail.file.Close()
+				tail.reopening = true
+				tail.reopenInode, tail.reopenPos = tail.lastInode, tail.lastPos
+			}
+			msg := err.Error()
+			tail.statErr.Store(&msg)
+
+			// Wait a bit, or for the notification of a new file, and try to open again
+			select {
+			case <-tail.clock.After(tail.pollInterval):
+			case <-wake:
+			case <-tail.stopChan:
+				tail.sendTrailing()
+				return
+			}
+			if err := tail.reopenIfNeeded(); err != nil {
+				// Still can't open, e.g. the permissions are being fixed,
+				// continue waiting
+				msg := err.Error()
+				tail.statErr.Store(&msg)
+				continue
+			}
+			tail.statErr.Store(nil)
+			if wake != nil {
+				// the notification of the lines already written was consumed,
+				// they are read now instead of on the next poll
+				tail.checkAndRead()
 			}
 		}
 	}
@@ -416,8 +1209,22 @@ func (tail *Tail) run() {
 
 // checkAndRead checks for file changes and reads new lines
 func (tail *Tail) checkAndRead() error {
+	// Check if the path to follow has changed
+	if tail.resolve != nil {
+		if target, err := tail.resolve(); err == nil && target != tail.filepath {
+			return tail.switchTo(target)
+		}
+	}
+
 	// Check if file still exists and hasn't been rotated
-	stat, err := os.Stat(tail.filepath)
+	var stat os.FileInfo
+	var err error
+	if tail.followFD {
+		// the open file is followed wherever it is, it is never rotated
+		stat, err = tail.file.Stat()
+	} else {
+		stat, err = tail.statPath(tail.filepath)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to stat file: %w", err)
 	}
@@ -426,20 +1233,40 @@ func (tail *Tail) checkAndRead() error {
 	currentSize := stat.Size()
 
 	// Check if file was rotated (inode changed)
-	if currentInode != tail.lastInode {
+	rotated := currentInode != tail.lastInode
+	if tail.container && !tail.followFD {
+		rotated = tail.containerRotated(stat, rotated)
+	}
+	if rotated && (!tail.rotationSettled(currentInode) || !tail.reopenAllowed()) {
+		// the path may flap back, or the reopen is rate limited,
+		// read what the open file has meanwhile
+		if _, err := tail.file.Seek(tail.lastPos, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek: %w", err)
+		}
+		tail.readLines()
+		return nil
+	}
+	tail.rotateSince = time.Time{}
+	if rotated {
 		// File was rotated - read remaining content from old file then switch to new file
 		if _, err := tail.file.Seek(tail.lastPos, io.SeekStart); err == nil {
 			tail.readLines()
 		}
+		tail.flushPartial()
 
 		// Reopen the new file
 		if tail.file != nil {
 			tail.file.Close()
 		}
 
+		oldInode := tail.lastInode
 		if err := tail.openFile(); err != nil {
 			return err
 		}
+		tail.lastReopen = tail.clock.Now()
+		if !tail.markRotation(oldInode) {
+			return nil
+		}
 
 		// Start from beginning of new file
 		tail.lastPos = 0
@@ -454,9 +1281,12 @@ func (tail *Tail) checkAndRead() error {
 	// Check if file was truncated
 	// This happens if: size decreased, OR our position is beyond current size
 	if currentSize < tail.lastSize || tail.lastPos > currentSize {
-		// File was truncated, seek to beginning
+		// File was truncated, the partial line was the end of the old content
+		tail.flushPartial()
+		// seek to beginning
 		tail.lastPos = 0
 		tail.lastSize = 0
+		tail.liveFrom = 0
 		if _, err := tail.file.Seek(0, io.SeekStart); err != nil {
 			return fmt.Errorf("failed to seek to start: %w", err)
 		}
@@ -483,10 +1313,134 @@ func (tail *Tail) checkAndRead() error {
 	return nil
 }
 
+// statFunc returns the info of the file at a path, like os.Stat
+type statFunc func(path string) (os.FileInfo, error)
+
+// withStatPath replaces the stat of the followed path on every poll, for the tests
+func withStatPath(stat statFunc) Option {
+	return func(t *Tail) {
+		t.statPath = stat
+	}
+}
+
+// rotationSettled reports whether the path has shown the file with the inode
+// for the debounce of WithRotationDebounce, true without a debounce.
+func (tail *Tail) rotationSettled(inode uint64) bool {
+	if tail.rotateDebounce <= 0 {
+		return true
+	}
+	now := tail.clock.Now()
+	if tail.rotateSince.IsZero() || inode != tail.rotateInode {
+		tail.rotateInode, tail.rotateSince = inode, now
+	}
+	return now.Sub(tail.rotateSince) >= tail.rotateDebounce
+}
+
+// reopenAllowed reports whether the interval of WithMinReopenInterval
+// has passed since the last reopen, true without an interval.
+func (tail *Tail) reopenAllowed() bool {
+	return tail.reopenMin <= 0 || tail.clock.Now().Sub(tail.lastReopen) >= tail.reopenMin
+}
+
+// markRotation sends the marker of WithRotationMarker for the rotation
+// of the file with the inode. It returns false if the tail was stopped while sending.
+func (tail *Tail) markRotation(oldInode uint64) bool {
+	if !tail.rotationMarker {
+		return true
+	}
+	name := filepath.Base(tail.filepath)
+	if rotated := rotatedName(tail.filepath, oldInode); rotated != "" {
+		name += " → " + rotated
+	}
+	return tail.sendMarker("rotated: " + name)
+}
+
+// rotatedName returns the name of the file with the inode next to path
+// whose name starts with the name of path, e.g. app.log.1, "" if none
+func rotatedName(path string, inode uint64) string {
+	if inode == 0 {
+		return ""
+	}
+	base := filepath.Base(path)
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.Name() == base || !strings.HasPrefix(entry.Name(), base) {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && getInode(info) == inode {
+			return entry.Name()
+		}
+	}
+	return ""
+}
+
+// sendMarker sends the marker of WithRotationMarker with the time, after the
+// record being framed, without the patterns and plugins.
+// It returns false if the tail was stopped while sending.
+func (tail *Tail) sendMarker(text string) bool {
+	if !tail.rotationMarker {
+		return true
+	}
+	if !tail.flushPending() {
+		return false
+	}
+	line := fmt.Sprintf("──── %s at %s ────", text, tail.clock.Now().UTC().Format(time.RFC3339))
+	if tail.coloring != NoColoring {
+		line = "\x1b[90m" + line + "\x1b[0m"
+	}
+	if tail.ruleDebug {
+		b, _ := json.Marshal(RuleFrame{Line: line, Rules: []string{"rotation-marker"}})
+		line = string(b)
+	}
+	if tail.byteOffset {
+		line = "-1:" + line
+	}
+	tail.fanOut(line)
+	select {
+	case tail.c <- line:
+		return true
+	case <-tail.stopChan:
+		return false
+	}
+}
+
+// switchTo reads the remaining content of the current file
+// then continues with the target file from its beginning
+func (tail *Tail) switchTo(target string) error {
+	if tail.file != nil {
+		if _, err := tail.file.Seek(tail.lastPos, io.SeekStart); err == nil {
+			tail.readLines()
+		}
+		tail.flushPartial()
+		tail.file.Close()
+	}
+
+	from := tail.filepath
+	tail.filepath = target
+	if err := tail.openFile(); err != nil {
+		return err
+	}
+	if !tail.sendMarker(fmt.Sprintf("switched: %s → %s", filepath.Base(from), filepath.Base(target))) {
+		return nil
+	}
+	tail.readLines()
+	return nil
+}
+
 // readLines reads new lines from the file
 func (tail *Tail) readLines() {
-	buf := make([]byte, 4096)
-	var lineBuf []byte
+	if tail.hexdump {
+		tail.readHexdump()
+		return
+	}
+	buf := make([]byte, tail.readBufSize)
+	// a line without its newline yet is kept across polls,
+	// it is emitted once the newline arrives
+	lineBuf := tail.partial
+	defer func() { tail.partial = lineBuf }()
 
 	for {
 		n, err := tail.file.Read(buf)
@@ -504,7 +1458,8 @@ func (tail *Tail) readLines() {
 				}
 
 				if nlIdx >= 0 {
-					// Found a complete line
+					// Found a complete line, the partial data in lineBuf has been counted in lastPos
+					lineOffset := tail.lastPos - int64(len(lineBuf))
 					lineBuf = append(lineBuf, data[:nlIdx]...)
 
 					// Convert to string and trim \r if present
@@ -513,49 +1468,13 @@ func (tail *Tail) readLines() {
 						line = line[:len(line)-1]
 					}
 
-					matched := false
-					if len(tail.patterns) > 0 {
-						for _, p := range tail.patterns {
-							if p.Match(line) {
-								matched = true
-								break
-							}
-						}
-					} else {
-						matched = true
-					}
-
-					if !matched {
-						// Move to next data
-						data = data[nlIdx+1:]
-						lineBuf = lineBuf[:0]
-						tail.lastPos += int64(nlIdx + 1)
-						continue
-					}
-
-					// Apply plugins
-					for _, plugin := range tail.plugins {
-						if ln, ok := plugin.Apply(line); ok {
-							line = ln
-						} else {
-							// Plugin indicated to drop the line
-							matched = false
-							break
-						}
-					}
-
-					if matched {
-						// Send the line
-						select {
-						case tail.c <- line:
-						case <-tail.stopChan:
-							return
-						}
+					if !tail.emit(line, lineOffset) {
+						return
 					}
 
 					// Move to next data
 					data = data[nlIdx+1:]
-					lineBuf = lineBuf[:0]
+					lineBuf = nil
 					tail.lastPos += int64(nlIdx + 1)
 				} else {
 					// No newline found, save to buffer
@@ -581,8 +1500,275 @@ func (tail *Tail) readLines() {
 	}
 }
 
-// reopenIfNeeded tries to reopen the file if it was rotated
+// flushPartial emits the partial last line as a complete line,
+// used when no more bytes can be appended to it (rotation, truncation, close).
+// It returns false if the tail was stopped while sending.
+func (tail *Tail) flushPartial() bool {
+	if len(tail.partial) == 0 {
+		return true
+	}
+	line, offset := trimCR(string(tail.partial)), tail.lastPos-int64(len(tail.partial))
+	tail.partial = nil
+	return tail.emit(line, offset)
+}
+
+// sendTrailing delivers the record being framed and the partial last line
+// when the tail stops, if the reader is still there.
+func (tail *Tail) sendTrailing() {
+	type record struct {
+		line   string
+		offset int64
+	}
+	var records []record
+	var last *record
+	if len(tail.partial) > 0 {
+		last = &record{trimCR(string(tail.partial)), tail.lastPos - int64(len(tail.partial))}
+		tail.partial = nil
+		if tail.multiline != nil && len(tail.pending) > 0 && !tail.multiline.MatchString(last.line) {
+			tail.pending = append(tail.pending, last.line)
+			last = nil
+		}
+	}
+	if len(tail.pending) > 0 {
+		records = append(records, record{strings.Join(tail.pending, "\n"), tail.pendingOffset})
+		tail.pending = tail.pending[:0]
+	}
+	if last != nil {
+		records = append(records, *last)
+	}
+	for _, r := range records {
+		if line, ok := tail.format(r.line, r.offset); ok {
+			tail.fanOut(line)
+			select {
+			case tail.c <- line:
+			default:
+				return
+			}
+		}
+	}
+}
+
+func trimCR(line string) string {
+	return strings.TrimSuffix(line, "\r")
+}
+
+// process applies the patterns and plugins to the line.
+// It returns false if the line should be dropped.
+func (tail *Tail) process(line string) (string, bool) {
+	line, _, ok := tail.processRules(line, "", false)
+	return line, ok
+}
+
+// processRules is process that prepends stamp to the line once the patterns matched,
+// before the plugins, and also reports, if debug is true, the rules that matched
+// the line: every matching pattern group and every plugin that changed the line.
+func (tail *Tail) processRules(line string, stamp string, debug bool) (string, []string, bool) {
+	var rules []string
+	if tail.sanitize {
+		line = SanitizeControl(line)
+	}
+	for i, transform := range tail.transforms {
+		ln := transform(line)
+		if ln == "" {
+			return line, nil, false
+		}
+		if debug && ln != line {
+			rules = append(rules, fmt.Sprintf("transform[%d]", i))
+		}
+		line = ln
+	}
+	patterns, plugins := tail.rules()
+	if len(patterns) > 0 {
+		matched := false
+		for _, p := range patterns {
+			if p.Match(line) {
+				matched = true
+				if !debug {
+					break
+				}
+				rules = append(rules, "pattern:"+p.String())
+			}
+		}
+		if !matched {
+			return line, nil, false
+		}
+	}
+	line = stamp + line
+
+	for i, plugin := range plugins {
+		if ln, ok := plugin.Apply(line); ok {
+			if debug && ln != line {
+				rules = append(rules, pluginName(i, plugin))
+			}
+			line = ln
+		} else {
+			// Plugin indicated to drop the line
+			return line, nil, false
+		}
+	}
+	return line, rules, true
+}
+
+// pluginName names the plugin for WithRuleDebug,
+// plugins implementing fmt.Stringer name themselves.
+func pluginName(idx int, plugin Plugin) string {
+	if s, ok := plugin.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("plugin[%d]", idx)
+}
+
+// RuleFrame is the JSON frame sent for each line with WithRuleDebug.
+type RuleFrame struct {
+	Line  string   `json:"line"`
+	Rules []string `json:"rules"`
+}
+
+// emit frames the line into a record and sends it to the channel.
+// Without a multiline pattern every line is a record.
+// offset is the byte offset of the line in the file, -1 if unknown.
+// It returns false if the tail was stopped while sending.
+func (tail *Tail) emit(line string, offset int64) bool {
+	if !tail.normalize {
+		return tail.frameLine(line, offset)
+	}
+	parts, offsets := splitNewlines(line, offset)
+	for i, part := range parts {
+		if !tail.frameLine(part, offsets[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitNewlines removes the byte order marks of the line and splits it at the
+// carriage returns left in it, the line endings of classic Mac OS files,
+// see WithNormalizeNewlines. The offsets are those of the parts in the file.
+func splitNewlines(line string, offset int64) ([]string, []int64) {
+	parts := strings.Split(line, "\r")
+	if len(parts) > 1 && parts[len(parts)-1] == "" {
+		// a trailing carriage return ends the line
+		parts = parts[:len(parts)-1]
+	}
+	offsets := make([]int64, len(parts))
+	pos := offset
+	for i, part := range parts {
+		offsets[i] = pos
+		if offset >= 0 {
+			pos += int64(len(part)) + 1
+		}
+		parts[i] = strings.ReplaceAll(part, "\uFEFF", "")
+	}
+	return parts, offsets
+}
+
+// frameLine adds the line to the multiline record, see emit
+func (tail *Tail) frameLine(line string, offset int64) bool {
+	if tail.collapseBlank {
+		blank := strings.TrimSpace(line) == ""
+		if blank && tail.lastBlank {
+			return true
+		}
+		tail.lastBlank = blank
+		if blank {
+			line = ""
+		}
+	}
+	if tail.multiline == nil {
+		return tail.send(line, offset)
+	}
+	if len(tail.pending) > 0 && !tail.multiline.MatchString(line) {
+		// continuation of the current record
+		tail.pending = append(tail.pending, line)
+		tail.pendingPolls = 0
+		return true
+	}
+	if !tail.flushPending() {
+		return false
+	}
+	tail.pending = append(tail.pending, line)
+	tail.pendingOffset = offset
+	tail.pendingPolls = 0
+	return true
+}
+
+// flushPending sends the buffered multiline record, if any.
+// It returns false if the tail was stopped while sending.
+func (tail *Tail) flushPending() bool {
+	if len(tail.pending) == 0 {
+		return true
+	}
+	record := strings.Join(tail.pending, "\n")
+	tail.pending = tail.pending[:0]
+	return tail.send(record, tail.pendingOffset)
+}
+
+// format processes the record, stamped with the receive time,
+// and prefixes it with the offset if enabled.
+// It returns false if the record should be dropped.
+func (tail *Tail) format(line string, offset int64) (string, bool) {
+	if tail.liveFrom > 0 && offset < tail.liveFrom {
+		// written before the live only tail started, -1 is no offset
+		return "", false
+	}
+	var stamp string
+	if tail.timestampFormat != "" {
+		stamp = tail.clock.Now().Format(tail.timestampFormat) + " "
+	}
+	line, rules, ok := tail.processRules(line, stamp, tail.ruleDebug)
+	if ok && tail.ruleDebug {
+		if rules == nil {
+			rules = []string{}
+		}
+		b, _ := json.Marshal(RuleFrame{Line: line, Rules: rules})
+		line = string(b)
+	}
+	if ok && tail.byteOffset {
+		line = strconv.FormatInt(max(offset, -1), 10) + ":" + line
+	}
+	return line, ok
+}
+
+// send processes the record and sends it to the channel.
+// It returns false if the tail was stopped while sending.
+func (tail *Tail) send(line string, offset int64) bool {
+	line, ok := tail.format(line, offset)
+	if !ok {
+		return true
+	}
+
+	tail.fanOut(line)
+	// the time of the line is stored first, so that its reader sees the tail active
+	now := tail.clock.Now()
+	tail.statLastLine.Store(now.UnixNano())
+	select {
+	case tail.c <- line:
+		tail.statLines.Add(1)
+		tail.statRate.add(now)
+		return true
+	case <-tail.stopChan:
+		return false
+	}
+}
+
+// reopenIfNeeded tries to reopen the file if it was rotated.
+// The same file continues where it was left, a new one from its beginning.
 func (tail *Tail) reopenIfNeeded() error {
+	liveFrom := tail.liveFrom
 	// Try to open the file
-	return tail.openFile()
+	if err := tail.openFile(); err != nil {
+		return err
+	}
+	if tail.reopening {
+		tail.reopening = false
+		if tail.lastInode == tail.reopenInode && tail.reopenPos <= tail.lastSize {
+			tail.lastPos = tail.reopenPos
+			tail.liveFrom = liveFrom
+		} else if tail.lastInode != tail.reopenInode && !tail.markRotation(tail.reopenInode) {
+			return nil
+		}
+		// the content from lastPos is read by the next poll
+		tail.lastSize = tail.lastPos
+	}
+	return nil
 }
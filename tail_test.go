@@ -1,9 +1,23 @@
 package tailer
 
 import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -77,6 +91,32 @@ func TestTail(t *testing.T) {
 	}
 }
 
+// TestTailStats tests the statistics count the lines sent with the time of the last one
+func TestTailStats(t *testing.T) {
+	testFile := createTestFile(t, "stats.log", "line 1\nline 2\n")
+
+	tail := New(testFile, WithPollInterval(50*time.Millisecond)).(*Tail)
+	if st := tail.Stats(); st.Lines != 0 || !st.LastLine.IsZero() {
+		t.Errorf("Expected no lines before start, got %+v", st)
+	}
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	appendToFile(t, testFile, "line 3\nline 4\n")
+	for i := 0; i < 4; i++ {
+		select {
+		case <-tail.Lines():
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for lines")
+		}
+	}
+	if st := tail.Stats(); st.Lines != 4 || st.LastLine.IsZero() {
+		t.Errorf("Expected stats of 4 lines with last line time, got %+v", st)
+	}
+}
+
 func TestTailRotation(t *testing.T) {
 	// Create a temporary file
 	tmpDir := t.TempDir()
@@ -189,6 +229,519 @@ func TestTailRotation(t *testing.T) {
 	}
 }
 
+// TestTailWatchNotify tests the notifications wake the tail long before the next poll,
+// for the writes and the rotations
+func TestTailWatchNotify(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("File notifications are only supported on Linux")
+	}
+	testFile := createTestFile(t, "notify.log", "line 1\n")
+
+	tail := New(testFile, WithPollInterval(time.Hour), WithWatchMode(WatchNotify), WithLast(1))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case line := <-tail.Lines():
+			if line != want {
+				t.Errorf("Expected '%s', got '%s'", want, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for '%s'", want)
+		}
+	}
+	expect("line 1")
+	appendToFile(t, testFile, "line 2\n")
+	expect("line 2")
+
+	if err := os.Rename(testFile, testFile+".1"); err != nil {
+		t.Fatalf("Failed to rotate file: %v", err)
+	}
+	if err := os.WriteFile(testFile, []byte("line 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to create new file: %v", err)
+	}
+	expect("line 3")
+
+	// the file is missing for a while, its creation is notified too
+	if err := os.Rename(testFile, testFile+".2"); err != nil {
+		t.Fatalf("Failed to rotate file: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := os.WriteFile(testFile, []byte("line 4\n"), 0644); err != nil {
+		t.Fatalf("Failed to create new file: %v", err)
+	}
+	expect("line 4")
+}
+
+// TestTailFollowRotation tests the path is followed across a rotation by default,
+// and the open file with WithFollowRotation(false)
+func TestTailFollowRotation(t *testing.T) {
+	for _, follow := range []bool{true, false} {
+		tmpDir := t.TempDir()
+		testFile := filepath.Join(tmpDir, "test.log")
+		if err := os.WriteFile(testFile, []byte("line 1\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		tail := New(testFile, WithPollInterval(50*time.Millisecond), WithFollowRotation(follow))
+		if err := tail.Start(); err != nil {
+			t.Fatalf("Failed to start tail: %v", err)
+		}
+
+		if err := os.Rename(testFile, testFile+".1"); err != nil {
+			t.Fatalf("Failed to rotate file: %v", err)
+		}
+		if err := os.WriteFile(testFile, []byte("new file\n"), 0644); err != nil {
+			t.Fatalf("Failed to create new file: %v", err)
+		}
+		time.Sleep(200 * time.Millisecond)
+		appendToFile(t, testFile+".1", "old file\n")
+
+		expected := []string{"line 1", "new file"}
+		if !follow {
+			expected = []string{"line 1", "old file"}
+		}
+		timeout := time.After(2 * time.Second)
+		for _, want := range expected {
+			select {
+			case line := <-tail.Lines():
+				if line != want {
+					t.Errorf("follow %v: expected '%s', got '%s'", follow, want, line)
+				}
+			case <-timeout:
+				t.Fatalf("follow %v: timeout waiting for '%s'", follow, want)
+			}
+		}
+		select {
+		case line := <-tail.Lines():
+			t.Errorf("follow %v: unexpected line '%s'", follow, line)
+		case <-time.After(200 * time.Millisecond):
+		}
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// TestTailRotationDebounce tests a rotation whose stat results flap
+// between the old and the new file before settling on the new one
+func TestTailRotationDebounce(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+	rotatedFile := testFile + ".1"
+	if err := os.WriteFile(testFile, []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// the path shows the old and the new file in turn while flapping is set
+	var flapping atomic.Bool
+	var polls atomic.Int64
+	stat := func(path string) (os.FileInfo, error) {
+		if flapping.Load() && polls.Add(1)%2 == 0 {
+			return os.Stat(rotatedFile)
+		}
+		return os.Stat(path)
+	}
+
+	tail := New(testFile, WithPollInterval(20*time.Millisecond), WithRotationDebounce(150*time.Millisecond), withStatPath(stat))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	flapping.Store(true)
+	if err := os.Rename(testFile, rotatedFile); err != nil {
+		t.Fatalf("Failed to rotate file: %v", err)
+	}
+	appendToFile(t, rotatedFile, "line 2\n")
+	if err := os.WriteFile(testFile, []byte("line 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to create new file: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	flapping.Store(false)
+	time.Sleep(300 * time.Millisecond)
+	appendToFile(t, testFile, "line 4\n")
+
+	var lines []string
+	timeout := time.After(2 * time.Second)
+	for len(lines) < 4 {
+		select {
+		case line := <-tail.Lines():
+			lines = append(lines, line)
+		case <-timeout:
+			t.Fatalf("Timeout waiting for lines, got %q", lines)
+		}
+	}
+	select {
+	case line := <-tail.Lines():
+		lines = append(lines, line)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if expected := []string{"line 1", "line 2", "line 3", "line 4"}; !slices.Equal(lines, expected) {
+		t.Errorf("Expected %q, got %q", expected, lines)
+	}
+}
+
+// TestTailPause tests a paused tail reads nothing, then resumes
+// after a rotation where it left off
+func TestTailPause(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "app.log")
+	if err := os.WriteFile(testFile, []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile, WithPollInterval(50*time.Millisecond)).(*Tail)
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+	if line := <-tail.Lines(); line != "line 1" {
+		t.Fatalf("Expected the last line, got %q", line)
+	}
+
+	tail.Pause()
+	if !tail.Stats().Paused {
+		t.Error("Expected the stats to report the pause")
+	}
+	appendToFile(t, testFile, "line 2\n")
+	if err := os.Rename(testFile, testFile+".1"); err != nil {
+		t.Fatalf("Failed to rotate file: %v", err)
+	}
+	if err := os.WriteFile(testFile, []byte("line 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to create new file: %v", err)
+	}
+	select {
+	case line := <-tail.Lines():
+		t.Fatalf("Expected no line while paused, got %q", line)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	tail.Resume()
+	for _, expected := range []string{"line 2", "line 3"} {
+		select {
+		case line := <-tail.Lines():
+			if line != expected {
+				t.Errorf("Expected %q, got %q", expected, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for %q", expected)
+		}
+	}
+}
+
+// sinkWriter is a sink of TestTailAddSink, blocked until release is closed if set
+type sinkWriter struct {
+	mu      sync.Mutex
+	lines   []string
+	release chan struct{}
+	err     error
+}
+
+func (sw *sinkWriter) Write(p []byte) (int, error) {
+	if sw.release != nil {
+		<-sw.release
+	}
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.err != nil {
+		return 0, sw.err
+	}
+	sw.lines = append(sw.lines, string(p))
+	return len(p), nil
+}
+
+func (sw *sinkWriter) written() []string {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return slices.Clone(sw.lines)
+}
+
+// TestTailAddSink tests the lines are written to the sinks,
+// and a blocked or failing sink does not stall the others
+func TestTailAddSink(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(testFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile, WithPollInterval(50*time.Millisecond), WithSyntaxHighlighting("level")).(*Tail)
+	all, errs, blocked := &sinkWriter{}, &sinkWriter{}, &sinkWriter{release: make(chan struct{})}
+	tail.AddSink(all)
+	tail.AddSink(errs, SinkPattern("ERROR"), SinkStripColors())
+	tail.AddSink(blocked, SinkBuffer(1))
+	failing := &sinkWriter{err: errors.New("connection reset")}
+	tail.AddSink(failing)
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+
+	appendToFile(t, testFile, "INFO one\nERROR two\nINFO three\nINFO four\n")
+	for i := 0; i < 4; i++ {
+		select {
+		case <-tail.Lines():
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for the lines")
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for (len(all.written()) < 4 || len(errs.written()) < 1) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := all.written(); len(got) != 4 || got[1] != ColorRed+"ERROR"+ColorReset+" two\n" {
+		t.Errorf("Expected the colored lines in the sink, got %q", got)
+	}
+	if got := errs.written(); !slices.Equal(got, []string{"ERROR two\n"}) {
+		t.Errorf("Expected the uncolored ERROR line only, got %q", got)
+	}
+	if drops := tail.Stats().SinkDrops; drops == 0 {
+		t.Error("Expected the blocked sink to drop lines")
+	}
+	// the failing sink is removed after its write error
+	sinks := func() int {
+		tail.sinksMu.RLock()
+		defer tail.sinksMu.RUnlock()
+		return len(tail.sinks)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for sinks() != 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if sinks := sinks(); sinks != 3 {
+		t.Errorf("Expected the failing sink to be removed, got %d sinks", sinks)
+	}
+
+	close(blocked.release)
+	tail.Stop()
+	deadline = time.Now().Add(2 * time.Second)
+	for len(blocked.written()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := blocked.written(); len(got) == 0 || len(got) >= 4 {
+		t.Errorf("Expected the blocked sink to write part of the lines, got %q", got)
+	}
+}
+
+// TestTailRotationMarker tests the marker sent between the files of a rotation
+func TestTailRotationMarker(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "app.log")
+
+	for _, tt := range []struct {
+		opts   []Option
+		prefix string
+	}{
+		{nil, "\x1b[90m──── rotated: app.log → app.log.1 at "},
+		{[]Option{WithColoringProfile(NoColoring), WithPattern("^line")}, "──── rotated: app.log → app.log.1 at "},
+	} {
+		os.Remove(testFile + ".1")
+		if err := os.WriteFile(testFile, []byte("line 1\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		tail := New(testFile, append([]Option{WithPollInterval(50 * time.Millisecond), WithRotationMarker()}, tt.opts...)...)
+		if err := tail.Start(); err != nil {
+			t.Fatalf("Failed to start tail: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if err := os.Rename(testFile, testFile+".1"); err != nil {
+			t.Fatalf("Failed to rotate file: %v", err)
+		}
+		if err := os.WriteFile(testFile, []byte("line 2\n"), 0644); err != nil {
+			t.Fatalf("Failed to create new file: %v", err)
+		}
+
+		var lines []string
+		timeout := time.After(2 * time.Second)
+		for len(lines) < 3 {
+			select {
+			case line := <-tail.Lines():
+				lines = append(lines, line)
+			case <-timeout:
+				t.Fatalf("Timeout waiting for lines, got %q", lines)
+			}
+		}
+		tail.Stop()
+
+		if lines[0] != "line 1" || lines[2] != "line 2" {
+			t.Errorf("Expected the lines around the marker, got %q", lines)
+		}
+		if !strings.HasPrefix(lines[1], tt.prefix) || !strings.Contains(lines[1], " ────") {
+			t.Errorf("Expected the marker %q..., got %q", tt.prefix, lines[1])
+		}
+	}
+}
+
+// TestTailMinReopenInterval tests the rapid rotations reopen the path once per interval without losing lines
+func TestTailMinReopenInterval(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "app.log")
+	if err := os.WriteFile(testFile, []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	const interval = 400 * time.Millisecond
+	tail := New(testFile, WithPollInterval(20*time.Millisecond), WithMinReopenInterval(interval))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	next := func(expected string) time.Time {
+		t.Helper()
+		select {
+		case line := <-tail.Lines():
+			if line != expected {
+				t.Errorf("Expected %q, got %q", expected, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for %q", expected)
+		}
+		return time.Now()
+	}
+	rotate := func(n int, content string) {
+		t.Helper()
+		if err := os.Rename(testFile, fmt.Sprintf("%s.%d", testFile, n)); err != nil {
+			t.Fatalf("Failed to rotate file: %v", err)
+		}
+		if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create new file: %v", err)
+		}
+	}
+
+	next("line 1")
+	rotate(1, "line 2\n")
+	reopened := next("line 2")
+
+	// the rotated file is still read until the interval passes
+	appendToFile(t, testFile, "line 3\n")
+	rotate(2, "line 4\n")
+	appendToFile(t, testFile+".2", "line 3b\n")
+	next("line 3")
+	next("line 3b")
+	if elapsed := next("line 4").Sub(reopened); elapsed < interval-50*time.Millisecond {
+		t.Errorf("Expected the path to be reopened after %v, got %v", interval, elapsed)
+	}
+}
+
+// TestTailContainerMode tests the rotation of the kubelet and of docker:
+// 0.log is renamed, the runtime writes its last lines to the renamed file,
+// then reopens 0.log as a new file
+func TestTailContainerMode(t *testing.T) {
+	cri := func(msg string) string {
+		return "2026-10-14T12:00:00.000000001Z stdout F " + msg + "\n"
+	}
+	for _, rotatedName := range []string{"0.log.1", "0.log.20261014-120000"} {
+		t.Run(rotatedName, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			logFile := filepath.Join(tmpDir, "0.log")
+			if err := os.WriteFile(logFile, []byte(cri("before 1")), 0644); err != nil {
+				t.Fatalf("Failed to create log file: %v", err)
+			}
+
+			tail := New(logFile, WithPollInterval(50*time.Millisecond), WithContainerMode())
+			if err := tail.Start(); err != nil {
+				t.Fatalf("Failed to start tail: %v", err)
+			}
+			defer tail.Stop()
+
+			appendTo := func(path string, content string) {
+				f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+				if err != nil {
+					t.Fatalf("Failed to open %s: %v", path, err)
+				}
+				f.WriteString(content)
+				f.Close()
+			}
+			appendTo(logFile, cri("before 2"))
+
+			// a hard link and an atomic replace make the rename and the reopen
+			// a single step for the tail
+			rotatedFile := filepath.Join(tmpDir, rotatedName)
+			if err := os.Link(logFile, rotatedFile); err != nil {
+				t.Fatalf("Failed to rotate log file: %v", err)
+			}
+			appendTo(rotatedFile, cri("late"))
+			if err := os.WriteFile(logFile+".tmp", []byte(cri("new 1")), 0644); err != nil {
+				t.Fatalf("Failed to create new log file: %v", err)
+			}
+			if err := os.Rename(logFile+".tmp", logFile); err != nil {
+				t.Fatalf("Failed to replace log file: %v", err)
+			}
+
+			for _, expected := range []string{"before 1", "before 2", "late", "new 1"} {
+				select {
+				case line := <-tail.Lines():
+					if line != strings.TrimSuffix(cri(expected), "\n") {
+						t.Errorf("Expected %q, got %q", expected, line)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatalf("Timeout waiting for %q", expected)
+				}
+			}
+		})
+	}
+
+	// the inodes seen through a mount can change for the same file,
+	// or stay the same for a new one
+	t.Run("unreliable inodes", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		logFile := filepath.Join(tmpDir, "0.log")
+		if err := os.WriteFile(logFile, []byte(cri("old 1")), 0644); err != nil {
+			t.Fatalf("Failed to create log file: %v", err)
+		}
+		tail := New(logFile, WithContainerMode()).(*Tail)
+		if err := tail.openFile(); err != nil {
+			t.Fatalf("Failed to open log file: %v", err)
+		}
+		defer func() { tail.file.Close() }()
+
+		stat, err := os.Stat(logFile)
+		if err != nil {
+			t.Fatalf("Failed to stat log file: %v", err)
+		}
+		opened := tail.file
+		if tail.containerRotated(stat, true) {
+			t.Error("Expected the same file after an inode change")
+		}
+		if tail.file == opened {
+			t.Error("Expected the same file to continue on a new descriptor")
+		}
+
+		if err := os.Rename(logFile, logFile+".1"); err != nil {
+			t.Fatalf("Failed to rotate log file: %v", err)
+		}
+		if err := os.WriteFile(logFile, []byte(cri("new 1")+cri("new 2")), 0644); err != nil {
+			t.Fatalf("Failed to create new log file: %v", err)
+		}
+		// a stale stat of the path finds nothing, the new file is found by the periodic reopen
+		stale, err := tail.file.Stat()
+		if err != nil {
+			t.Fatalf("Failed to stat open file: %v", err)
+		}
+		for i := 1; i < containerReopenPolls; i++ {
+			if tail.containerRotated(stale, false) {
+				t.Fatalf("Expected no rotation before the periodic reopen, poll %d", i)
+			}
+		}
+		if !tail.containerRotated(stale, false) {
+			t.Error("Expected a rotation found by the periodic reopen")
+		}
+
+		// the size of the path differs from the open file
+		stat, err = os.Stat(logFile)
+		if err != nil {
+			t.Fatalf("Failed to stat log file: %v", err)
+		}
+		if !tail.containerRotated(stat, false) {
+			t.Error("Expected a rotation with the same inode")
+		}
+	})
+}
+
 func TestTailTruncation(t *testing.T) {
 	// Create a temporary file
 	tmpDir := t.TempDir()
@@ -358,6 +911,92 @@ func TestTailGrepPattern(t *testing.T) {
 	}
 }
 
+func TestPatternMatchColored(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		line     string
+		expected bool
+	}{
+		{[]string{"ERROR"}, "\x1b[31mERROR\x1b[0m disk full", true},
+		{[]string{"level=ERROR"}, "level=\x1b[1;31mERROR\x1b[0m msg=failed", true},
+		{[]string{"^ERROR disk", "full$"}, "\x1b[31mERROR\x1b[0m disk full", true},
+		{[]string{"level=ERROR"}, "level=\x1b[33mWARN\x1b[0m msg=slow", false},
+		{[]string{"level=ERROR"}, "level=ERROR msg=failed", true},
+	}
+	for _, tt := range tests {
+		tail := New("", WithPattern(tt.patterns...)).(*Tail)
+		if matched := tail.patterns[0].Match(tt.line); matched != tt.expected {
+			t.Errorf("%q matching %q: expected %v, got %v", tt.patterns, tt.line, tt.expected, matched)
+		}
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		sample   string
+		expected bool
+	}{
+		{"", false},
+		{"INFO started\nWARN slow\r\n", false},
+		{"\x1b[31mERROR\x1b[0m failed\tretry\n", false},
+		{"héllo wörld ✓\n", false},
+		{"text\x00more text\n", true},
+		{"\x7fELF\x02\x01\x01\x03\x04\x05", true},
+		{"\xff\xfe\xfd\xfc\xfb plain", true},
+		{"mostly text with one \x07 bell\n", false},
+		{"cut at the end \xe2\x9c", false},
+	}
+	for _, tt := range tests {
+		if binary := IsBinary([]byte(tt.sample)); binary != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.sample, tt.expected, binary)
+		}
+	}
+}
+
+func TestTailBinaryMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "app.bin")
+	data := []byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x03\x00>\x00")
+	if err := os.WriteFile(testFile, data, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile)
+	if err := tail.Start(); !errors.Is(err, ErrBinaryFile) {
+		tail.Stop()
+		t.Fatalf("Expected ErrBinaryFile, got %v", err)
+	}
+
+	tail = New(testFile, WithBinaryMode(BinaryHexdump), WithPollInterval(50*time.Millisecond))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	f, err := os.OpenFile(testFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	f.Write([]byte("Hi\n"))
+	f.Close()
+
+	expected := []string{
+		"00000000  7f 45 4c 46 02 01 01 00  00 00 00 00 00 00 00 00  |.ELF............|",
+		"00000010  03 00 3e 00                                       |..>.|",
+		"00000014  48 69 0a                                          |Hi.|",
+	}
+	for _, want := range expected {
+		select {
+		case line := <-tail.Lines():
+			if line != want {
+				t.Errorf("Expected %q, got %q", want, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for %q", want)
+		}
+	}
+}
+
 func TestTailWithColoringPlugin(t *testing.T) {
 	// Create a temporary file
 	tmpDir := t.TempDir()
@@ -559,3 +1198,2504 @@ func TestMultiTail(t *testing.T) {
 		t.Error("Did not find 'test2.log file2 line3'")
 	}
 }
+
+// chanTail is an ITail of another package, without the statistics of StatsProvider
+type chanTail struct {
+	c chan string
+}
+
+func (ct chanTail) Start() error         { return nil }
+func (ct chanTail) Stop() error          { close(ct.c); return nil }
+func (ct chanTail) Lines() <-chan string { return ct.c }
+
+// TestMultiTailStatsProvider tests the statistics of a MultiTail
+// merging a tail without them
+func TestMultiTailStatsProvider(t *testing.T) {
+	testFile := createTestFile(t, "test.log", "")
+	tail := New(testFile, WithPollInterval(50*time.Millisecond))
+	other := chanTail{c: make(chan string, 1)}
+
+	multiTail := NewMultiTail(tail, other)
+	if err := multiTail.Start(); err != nil {
+		t.Fatalf("Failed to start MultiTail: %v", err)
+	}
+	defer multiTail.Stop()
+
+	other.c <- "other line"
+	appendToFile(t, testFile, "line 1\n")
+	for range 2 {
+		select {
+		case <-multiTail.Lines():
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for the lines")
+		}
+	}
+	stats, ok := multiTail.(StatsProvider)
+	if !ok {
+		t.Fatal("Expected MultiTail to provide its statistics")
+	}
+	if n := stats.Stats().Lines; n != 1 {
+		t.Errorf("Expected the line of the file tail only, got %d", n)
+	}
+	if !stats.IsActive() {
+		t.Error("Expected active after a line")
+	}
+}
+
+func TestTailHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	// Oldest archive is compressed
+	gzFile, err := os.Create(testFile + ".2.gz")
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	gz := gzip.NewWriter(gzFile)
+	fmt.Fprintln(gz, "line 1")
+	fmt.Fprintln(gz, "line 2")
+	gz.Close()
+	gzFile.Close()
+
+	if err := os.WriteFile(testFile+".1", []byte("line 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to create rotated file: %v", err)
+	}
+	if err := os.WriteFile(testFile, []byte("line 4\nline 5\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	history := RotatedFiles(testFile)
+	if len(history) != 2 || history[0] != testFile+".2.gz" || history[1] != testFile+".1" {
+		t.Fatalf("Unexpected rotated files: %v", history)
+	}
+
+	tail := New(testFile, WithPollInterval(100*time.Millisecond), WithHistory(history...))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer func() {
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	f, err := os.OpenFile(testFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	fmt.Fprintln(f, "line 6")
+	f.Close()
+
+	timeout := time.After(2 * time.Second)
+	lines := []string{}
+	for i := 0; i < 6; i++ {
+		select {
+		case line := <-tail.Lines():
+			lines = append(lines, line)
+		case <-timeout:
+			t.Fatalf("Timeout waiting for lines, got %d lines: %v", len(lines), lines)
+		}
+	}
+
+	for i, line := range lines {
+		expected := fmt.Sprintf("line %d", i+1)
+		if line != expected {
+			t.Errorf("Expected '%s', got '%s'", expected, line)
+		}
+	}
+
+	select {
+	case line := <-tail.Lines():
+		t.Errorf("Unexpected extra line '%s'", line)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+// TestNewReader tests the reader streams the lines across a rotation until it is closed
+func TestNewReader(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+	if err := os.WriteFile(testFile, []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, err := NewReader(ctx, New(testFile, WithPollInterval(20*time.Millisecond)))
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer r.Close()
+
+	appendToFile(t, testFile, "line 2\n")
+	time.Sleep(60 * time.Millisecond)
+	if err := os.Rename(testFile, testFile+".1"); err != nil {
+		t.Fatalf("Failed to rotate file: %v", err)
+	}
+	if err := os.WriteFile(testFile, []byte("line 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to create new file: %v", err)
+	}
+
+	scanner := bufio.NewScanner(r)
+	for i := 1; i <= 3; i++ {
+		if !scanner.Scan() {
+			t.Fatalf("Failed to scan line %d: %v", i, scanner.Err())
+		}
+		if expected := fmt.Sprintf("line %d", i); scanner.Text() != expected {
+			t.Errorf("Expected %q, got %q", expected, scanner.Text())
+		}
+	}
+
+	cancel()
+	if _, err := r.Read(make([]byte, 16)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the error of the context, got %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Failed to close reader: %v", err)
+	}
+	if _, err := r.Read(make([]byte, 16)); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF once closed, got %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Expected a second close to succeed, got %v", err)
+	}
+}
+
+// TestTailNoReader tests the lines written before the channel is read are all delivered
+func TestTailNoReader(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+	if err := os.WriteFile(testFile+".1", []byte("line 1\nline 2\n"), 0644); err != nil {
+		t.Fatalf("Failed to create rotated file: %v", err)
+	}
+	if err := os.WriteFile(testFile, []byte("line 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want int // the first line delivered
+	}{
+		{"history", []Option{WithHistory(testFile + ".1")}, 1},
+		{"last", nil, 3},
+	}
+	for _, tt := range tests {
+		tail := New(testFile, append([]Option{WithPollInterval(20 * time.Millisecond), WithBufferSize(2)}, tt.opts...)...)
+		if err := tail.Start(); err != nil {
+			t.Fatalf("%s: failed to start tail: %v", tt.name, err)
+		}
+		for i := 4; i <= 10; i++ {
+			appendToFile(t, testFile, fmt.Sprintf("line %d\n", i))
+			time.Sleep(30 * time.Millisecond)
+		}
+		// the tail waits for the reader once the buffer is full
+		if n := tail.(*Tail).Stats().Lines; n != 2 {
+			t.Errorf("%s: expected 2 lines in the buffer, got %d", tt.name, n)
+		}
+
+		for i := tt.want; i <= 10; i++ {
+			expected := fmt.Sprintf("line %d", i)
+			select {
+			case line := <-tail.Lines():
+				if line != expected {
+					t.Errorf("%s: expected %q, got %q", tt.name, expected, line)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("%s: timeout waiting for %q", tt.name, expected)
+			}
+		}
+		tail.Stop()
+		// the next case starts from the same files
+		if err := os.WriteFile(testFile, []byte("line 3\n"), 0644); err != nil {
+			t.Fatalf("Failed to reset test file: %v", err)
+		}
+	}
+}
+
+func TestTailReadRange(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	// each line is 7 bytes long
+	if err := os.WriteFile(testFile, []byte("line 1\nline 2\nline 3\nline 4\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile).(*Tail)
+
+	tests := []struct {
+		start, end  int64
+		expectStart int64
+		expectEnd   int64
+		expectLines []string
+	}{
+		{0, 7, 0, 7, []string{"line 1"}},
+		{7, 14, 7, 14, []string{"line 2"}},
+		{3, 10, 7, 14, []string{"line 2"}},
+		{8, 22, 14, 28, []string{"line 3", "line 4"}},
+		{0, 100, 0, 28, []string{"line 1", "line 2", "line 3", "line 4"}},
+		{25, 28, 28, 28, nil},
+	}
+	for _, tt := range tests {
+		rng, err := tail.ReadRange(context.Background(), tt.start, tt.end)
+		if err != nil {
+			t.Fatalf("ReadRange(%d, %d) failed: %v", tt.start, tt.end, err)
+		}
+		if rng.Start != tt.expectStart || rng.End != tt.expectEnd || rng.Size != 28 {
+			t.Errorf("ReadRange(%d, %d): unexpected offsets %+v", tt.start, tt.end, rng)
+		}
+		if fmt.Sprint(rng.Lines) != fmt.Sprint(tt.expectLines) {
+			t.Errorf("ReadRange(%d, %d): expected lines %v, got %v", tt.start, tt.end, tt.expectLines, rng.Lines)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tail.ReadRange(ctx, 0, 28); err == nil {
+		t.Error("Expected error on canceled context")
+	}
+}
+
+// createSparseLog creates a log of size bytes ending with the tail content,
+// the head is a hole that takes no disk space.
+func createSparseLog(tb testing.TB, size int64, tail string) string {
+	tb.Helper()
+	testFile := filepath.Join(tb.TempDir(), "large.log")
+	f, err := os.Create(testFile)
+	if err != nil {
+		tb.Fatalf("Failed to create test file: %v", err)
+	}
+	defer f.Close()
+	if err := f.Truncate(size - int64(len(tail))); err != nil {
+		tb.Fatalf("Failed to size test file: %v", err)
+	}
+	if _, err := f.WriteAt([]byte(tail), size-int64(len(tail))); err != nil {
+		tb.Fatalf("Failed to write test file: %v", err)
+	}
+	return testFile
+}
+
+func TestTailLastLinesLargeFile(t *testing.T) {
+	const size = 512 << 20
+	testFile := createSparseLog(t, size, "\nline a\nline b\r\n\nline c\nline d\npart")
+
+	check := func() {
+		t.Helper()
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+
+		tail := New(testFile, WithLast(3), WithPollInterval(50*time.Millisecond))
+		if err := tail.Start(); err != nil {
+			t.Fatalf("Failed to start tail: %v", err)
+		}
+		defer tail.Stop()
+
+		var got []string
+		for len(got) < 3 {
+			select {
+			case line := <-tail.Lines():
+				got = append(got, line)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("Timeout waiting for the last lines, got %v", got)
+			}
+		}
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+
+		if expected := []string{"line b", "line c", "line d"}; !slices.Equal(got, expected) {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+		if elapsed > time.Second {
+			t.Errorf("Reading the last lines of a %d MB file took %v", size>>20, elapsed)
+		}
+		if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 8<<20 {
+			t.Errorf("Reading the last lines allocated %d bytes", alloc)
+		}
+
+		// the partial last line is completed by the live follow
+		f, err := os.OpenFile(testFile, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatalf("Failed to open test file: %v", err)
+		}
+		fmt.Fprintln(f, "ial")
+		f.Close()
+		select {
+		case line := <-tail.Lines():
+			if line != "partial" {
+				t.Errorf("Expected 'partial', got '%s'", line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for the completed line")
+		}
+		// restore the file for the next check
+		if err := os.Truncate(testFile, size); err != nil {
+			t.Fatalf("Failed to truncate test file: %v", err)
+		}
+	}
+	// within one block
+	check()
+
+	// spanning many blocks, smaller than a line
+	defer func(size int) { lastLinesBlockSize = size }(lastLinesBlockSize)
+	lastLinesBlockSize = 3
+	check()
+}
+
+// TestTailLastLinesWithoutNewline tests the backward scan of the last lines
+// stops at maxLastLineSize in a file without newlines
+func TestTailLastLinesWithoutNewline(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.log")
+	size := 3*maxLastLineSize + 10
+	if err := os.WriteFile(testFile, []byte(strings.Repeat("x", size)), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile, WithPollInterval(50*time.Millisecond), WithByteOffset())
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	// the line is held back until its newline, then sent from the cap
+	appendToFile(t, testFile, "\nline 2\n")
+	expected := []string{
+		fmt.Sprintf("%d:%s", size-maxLastLineSize, strings.Repeat("x", maxLastLineSize)),
+		fmt.Sprintf("%d:line 2", size+1),
+	}
+	for _, exp := range expected {
+		select {
+		case line := <-tail.Lines():
+			if line != exp {
+				t.Errorf("Expected %.20q (%d bytes), got %.20q (%d bytes)", exp, len(exp), line, len(line))
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for %.20q", exp)
+		}
+	}
+}
+
+func TestTailHistoryPage(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	// each line is 7 bytes long, the last one has no newline
+	if err := os.WriteFile(testFile, []byte("line 1\nline 2\nline 3\nline 4\nline 5"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	tail := New(testFile).(*Tail)
+
+	tests := []struct {
+		before      int64
+		maxLines    int
+		expectLines []string
+		expectNext  int64
+	}{
+		{100, 2, []string{"4:line 4", "5:line 5"}, 21},
+		{21, 2, []string{"2:line 2", "3:line 3"}, 7},
+		{7, 2, []string{"1:line 1"}, 0},
+		// a line cut by the offset is skipped
+		{24, 5, []string{"1:line 1", "2:line 2", "3:line 3"}, 0},
+		{3, 5, nil, 0},
+		{0, 5, nil, 0},
+	}
+	check := func(tail *Tail) {
+		t.Helper()
+		for _, tt := range tests {
+			lines, next, err := tail.History(context.Background(), tt.before, tt.maxLines)
+			if err != nil {
+				t.Fatalf("History(%d, %d) failed: %v", tt.before, tt.maxLines, err)
+			}
+			var got []string
+			for _, l := range lines {
+				got = append(got, fmt.Sprintf("%d:%s", l.Offset/7+1, l.Text))
+				if l.Offset%7 != 0 {
+					t.Errorf("History(%d, %d): unexpected offset %d", tt.before, tt.maxLines, l.Offset)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.expectLines) || next != tt.expectNext {
+				t.Errorf("History(%d, %d): expected %v next %d, got %v next %d",
+					tt.before, tt.maxLines, tt.expectLines, tt.expectNext, got, next)
+			}
+		}
+	}
+	// smaller than one block
+	check(tail)
+
+	// spanning many blocks, smaller than a line
+	defer func(size int) { historyBlockSize = size }(historyBlockSize)
+	historyBlockSize = 3
+	check(tail)
+
+	// only the lines matching the patterns count
+	lines, next, _ := New(testFile, WithPattern("line [135]")).(*Tail).History(context.Background(), 100, 2)
+	if len(lines) != 2 || lines[0].Text != "line 3" || lines[1].Text != "line 5" || next != 14 {
+		t.Errorf("Unexpected filtered history %+v next %d", lines, next)
+	}
+}
+
+// TestTailHistoryWithoutNewline tests the backward scan of History
+// stops at maxLastLineSize in a file without newlines
+func TestTailHistoryWithoutNewline(t *testing.T) {
+	size := 3*maxLastLineSize + 10
+	testFile := createTestFile(t, "history.log", strings.Repeat("x", size))
+	tail := New(testFile).(*Tail)
+
+	lines, next, err := tail.History(context.Background(), math.MaxInt64, 5)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(lines) != 1 || lines[0].Offset != int64(size-maxLastLineSize) || lines[0].Text != strings.Repeat("x", maxLastLineSize) || next != 0 {
+		t.Errorf("Expected the last %d bytes of the line, got %d lines next %d", maxLastLineSize, len(lines), next)
+	}
+
+	// the lines after the long line are read as usual
+	appendToFile(t, testFile, "\nline 2\n")
+	lines, _, err = tail.History(context.Background(), math.MaxInt64, 5)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(lines) != 2 || lines[0].Offset != int64(size+1-maxLastLineSize) || lines[1] != (Line{Offset: int64(size + 1), Text: "line 2"}) {
+		t.Errorf("Expected the end of the long line and line 2, got %d lines", len(lines))
+	}
+}
+
+func TestTailLatest(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldFile := filepath.Join(tmpDir, "app-1.log")
+	newFile := filepath.Join(tmpDir, "app-2.log")
+
+	if err := os.WriteFile(oldFile, []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	// not matching the pattern, must be ignored
+	if err := os.WriteFile(filepath.Join(tmpDir, "other.log"), []byte("other\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := NewLatest(tmpDir, "app-*.log", WithPollInterval(100*time.Millisecond))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer func() {
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	// the last line of the old file and the first of the new one
+	// are written before the switch is detected
+	appendToFile(t, oldFile, "line 2\n")
+	if err := os.WriteFile(newFile, []byte("line 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to create new file: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(newFile, future, future); err != nil {
+		t.Fatalf("Failed to change file time: %v", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	appendToFile(t, newFile, "line 4\n")
+
+	timeout := time.After(2 * time.Second)
+	lines := []string{}
+	for i := 0; i < 4; i++ {
+		select {
+		case line := <-tail.Lines():
+			lines = append(lines, line)
+		case <-timeout:
+			t.Fatalf("Timeout waiting for lines, got %d lines: %v", len(lines), lines)
+		}
+	}
+
+	for i, line := range lines {
+		expected := fmt.Sprintf("line %d", i+1)
+		if line != expected {
+			t.Errorf("Expected '%s', got '%s'", expected, line)
+		}
+	}
+}
+
+// TestTailLatestTouchOlder tests a file switched from is not followed again
+// when it becomes the most recently modified one
+func TestTailLatestTouchOlder(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldFile := filepath.Join(tmpDir, "app-1.log")
+	newFile := filepath.Join(tmpDir, "app-2.log")
+
+	if err := os.WriteFile(oldFile, []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	tail := NewLatest(tmpDir, "app-*.log", WithPollInterval(50*time.Millisecond))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case line := <-tail.Lines():
+			if line != want {
+				t.Errorf("Expected '%s', got '%s'", want, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for '%s'", want)
+		}
+	}
+	expect("line 1")
+
+	if err := os.WriteFile(newFile, []byte("line 2\n"), 0644); err != nil {
+		t.Fatalf("Failed to create new file: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(newFile, future, future); err != nil {
+		t.Fatalf("Failed to change file time: %v", err)
+	}
+	expect("line 2")
+
+	// the old file touched after the new one is not read again
+	later := future.Add(time.Minute)
+	if err := os.Chtimes(oldFile, later, later); err != nil {
+		t.Fatalf("Failed to change file time: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	appendToFile(t, newFile, "line 3\n")
+	expect("line 3")
+}
+
+// TestKubePodLog tests the log of a pod followed across a restart of its container
+func TestKubePodLog(t *testing.T) {
+	cri := func(msg string) string {
+		return "2026-10-14T12:00:00.000000001Z stdout F " + msg + "\n"
+	}
+	defer func(dir string) { kubePodsDir = dir }(kubePodsDir)
+	kubePodsDir = t.TempDir()
+	dir := filepath.Join(kubePodsDir, "default_web-0_3f2a", "app")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create the pod directory: %v", err)
+	}
+	// another container of the pod
+	if err := os.MkdirAll(filepath.Join(kubePodsDir, "default_web-0_3f2a", "sidecar"), 0755); err != nil {
+		t.Fatalf("Failed to create the pod directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(kubePodsDir, "default_web-0_3f2a", "sidecar", "0.log"), []byte(cri("sidecar")), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "0.log"), []byte(cri("line 1")), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := NewKubePodLog("default", "web-0", "app", WithPollInterval(50*time.Millisecond))
+	if label := tail.(*Tail).label; label != "default/web-0/app" {
+		t.Errorf("Unexpected label %q", label)
+	}
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	// the container restarts into 1.log
+	appendToFile(t, filepath.Join(dir, "0.log"), cri("line 2"))
+	if err := os.WriteFile(filepath.Join(dir, "1.log"), []byte(cri("line 3")), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "1.log"), future, future); err != nil {
+		t.Fatalf("Failed to change file time: %v", err)
+	}
+
+	var lines []string
+	timeout := time.After(2 * time.Second)
+	for len(lines) < 3 {
+		select {
+		case line := <-tail.Lines():
+			lines = append(lines, line)
+		case <-timeout:
+			t.Fatalf("Timeout waiting for lines, got %q", lines)
+		}
+	}
+	if expected := []string{"line 1", "line 2", "line 3"}; !slices.Equal(lines, expected) {
+		t.Errorf("Expected %q, got %q", expected, lines)
+	}
+
+	if err := NewKubePodLog("default", "missing", "app").Start(); err == nil {
+		t.Error("Expected an error for a pod without logs")
+	}
+}
+
+func TestCRIMessage(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"2026-10-14T12:00:00.000000001Z stdout F listening on :8080", "listening on :8080"},
+		{"2026-10-14T12:00:00.000000001Z stderr P part of a long line", "part of a long line"},
+		{"not a CRI line", "not a CRI line"},
+	}
+	for _, tt := range tests {
+		if got := CRIMessage(tt.line); got != tt.expected {
+			t.Errorf("CRIMessage(%q) = %q, expected %q", tt.line, got, tt.expected)
+		}
+	}
+}
+
+func TestTailPermissionRecovery(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("File permissions are not enforced")
+	}
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	if err := os.WriteFile(testFile, []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile, WithPollInterval(50*time.Millisecond))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	next := func() string {
+		t.Helper()
+		select {
+		case line := <-tail.Lines():
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for line")
+			return ""
+		}
+	}
+	if line := next(); line != "line 1" {
+		t.Errorf("Expected 'line 1', got '%s'", line)
+	}
+
+	// rotated to a file the tail is not allowed to read
+	if err := os.Rename(testFile, testFile+".1"); err != nil {
+		t.Fatalf("Failed to rotate file: %v", err)
+	}
+	if err := os.WriteFile(testFile, []byte("line 2\n"), 0000); err != nil {
+		t.Fatalf("Failed to create new file: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(tail.(*Tail).Stats().Error, "permission denied") {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected a permission error, got %q", tail.(*Tail).Stats().Error)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// the stream resumes once the permissions are fixed
+	if err := os.Chmod(testFile, 0644); err != nil {
+		t.Fatalf("Failed to fix permissions: %v", err)
+	}
+	if line := next(); line != "line 2" {
+		t.Errorf("Expected 'line 2', got '%s'", line)
+	}
+	if err := tail.(*Tail).Stats().Error; err != "" {
+		t.Errorf("Expected no error after the recovery, got %q", err)
+	}
+}
+
+func TestTailLiveOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+	historyFile := filepath.Join(tmpDir, "test.log.1")
+
+	if err := os.WriteFile(historyFile, []byte("history\n"), 0644); err != nil {
+		t.Fatalf("Failed to create history file: %v", err)
+	}
+	if err := os.WriteFile(testFile, []byte("old 1\nold 2\nsecret in pro"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile,
+		WithPollInterval(50*time.Millisecond),
+		WithLast(10),
+		WithHistory(historyFile),
+		WithLiveOnly(),
+	)
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	f, err := os.OpenFile(testFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	fmt.Fprintln(f, "gress")
+	fmt.Fprintln(f, "new 1")
+	f.Close()
+
+	select {
+	case line := <-tail.Lines():
+		if line != "new 1" {
+			t.Errorf("Expected 'new 1', got '%s'", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for line")
+	}
+
+	// the lines of a new file are all written after Start
+	if err := os.WriteFile(testFile, []byte("rotated\n"), 0644); err != nil {
+		t.Fatalf("Failed to truncate test file: %v", err)
+	}
+	select {
+	case line := <-tail.Lines():
+		if line != "rotated" {
+			t.Errorf("Expected 'rotated', got '%s'", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for line")
+	}
+}
+
+func TestTailIsActive(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	if err := os.WriteFile(testFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile, WithPollInterval(50*time.Millisecond), WithActiveWindow(300*time.Millisecond))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer func() {
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	if tail.(*Tail).IsActive() {
+		t.Error("Expected idle before any line")
+	}
+	appendToFile(t, testFile, "line 1\n")
+	<-tail.Lines()
+	if !tail.(*Tail).IsActive() {
+		t.Error("Expected active after a line")
+	}
+	time.Sleep(400 * time.Millisecond)
+	if tail.(*Tail).IsActive() {
+		t.Error("Expected idle after the window")
+	}
+}
+
+func TestRateMeter(t *testing.T) {
+	var m rateMeter
+	now := time.Unix(1000, 0)
+	for i := 0; i < 10; i++ {
+		m.add(now)
+	}
+	m.add(now.Add(time.Second))
+	if r := m.rate(now.Add(time.Second)); r != 11.0/rateWindow {
+		t.Errorf("Expected rate %v, got %v", 11.0/rateWindow, r)
+	}
+	// the first bucket falls out of the window
+	if r := m.rate(now.Add(rateWindow * time.Second)); r != 1.0/rateWindow {
+		t.Errorf("Expected rate %v, got %v", 1.0/rateWindow, r)
+	}
+	if r := m.rate(now.Add(time.Hour)); r != 0 {
+		t.Errorf("Expected rate 0, got %v", r)
+	}
+}
+
+func TestTruncateAnsi(t *testing.T) {
+	red := func(s string) string { return ColorRed + s + ColorReset }
+	tests := []struct {
+		line     string
+		cols     int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"this line is too long", 10, "this line…"},
+		{red("ERROR") + " disk full", 5, ColorRed + "ERRO…" + ColorReset},
+		{red("ERROR") + " disk full", 7, red("ERROR") + " …" + ColorReset},
+		// the cut does not split the escape sequence in the middle of the line
+		{"ab" + red("ERROR") + " disk full", 4, "ab" + ColorRed + "E…" + ColorReset},
+		{"日本語のログ行", 4, "日本語…"},
+		{"no limit", 0, "no limit"},
+	}
+	for _, tt := range tests {
+		if got := TruncateAnsi(tt.line, tt.cols); got != tt.expected {
+			t.Errorf("TruncateAnsi(%q, %d) = %q, expected %q", tt.line, tt.cols, got, tt.expected)
+		}
+	}
+}
+
+func TestThresholdColoring(t *testing.T) {
+	plugin := NewThresholdColoring(regexp.MustCompile(`latency=(\S+)ms`),
+		Threshold{Min: 500, Color: ColorRed},
+		Threshold{Min: 0, Color: ColorGreen},
+		Threshold{Min: 100, Color: ColorYellow},
+	)
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"GET / latency=12ms", "GET / latency=" + ColorGreen + "12" + ColorReset + "ms"},
+		{"GET / latency=100ms", "GET / latency=" + ColorYellow + "100" + ColorReset + "ms"},
+		{"GET / latency=750.5ms", "GET / latency=" + ColorRed + "750.5" + ColorReset + "ms"},
+		// below every threshold, malformed or not matching
+		{"GET / latency=-1ms", "GET / latency=-1ms"},
+		{"GET / latency=fastms", "GET / latency=fastms"},
+		{"GET / 200 OK", "GET / 200 OK"},
+	}
+	for _, tt := range tests {
+		got, ok := plugin.Apply(tt.line)
+		if !ok {
+			t.Errorf("Apply(%q) dropped the line", tt.line)
+		}
+		if got != tt.expected {
+			t.Errorf("Apply(%q) = %q, expected %q", tt.line, got, tt.expected)
+		}
+	}
+}
+
+func TestDetectProfile(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"json", []string{`{"level":"INFO","msg":"started"}`, `{"level":"ERROR","msg":"failed"}`, ""}, "json"},
+		{"syslog", []string{
+			"Oct  5 12:00:00 host sshd[42]: Accepted publickey",
+			"2026-10-15T12:00:00.123+00:00 host systemd[1]: Started",
+			"Oct  5 12:00:01 host kernel: eth0 up",
+		}, "syslog"},
+		{"access", []string{
+			`127.0.0.1 - - [15/Oct/2026:12:00:00 +0000] "GET / HTTP/1.1" 200 512`,
+			`10.0.0.2 - bob [15/Oct/2026:12:00:01 +0000] "POST /login HTTP/1.1" 302 0 "-" "curl/8.0"`,
+		}, "access"},
+		{"slog-text", []string{
+			`time=2026-10-15T12:00:00Z level=INFO msg="started" port=8080`,
+			`time=2026-10-15T12:00:01Z level=WARN msg="slow" ms=900`,
+		}, "slog-text"},
+		{"plain", []string{"starting the server", "ERROR failed to bind"}, "level"},
+		{"mixed", []string{`{"msg":"a"}`, "plain text", "Oct  5 12:00:00 host sshd[42]: x", "more text"}, "level"},
+		{"empty", nil, "level"},
+	}
+	for _, tt := range tests {
+		if got := DetectProfile(tt.lines); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	line := `127.0.0.1 - - [15/Oct/2026:12:00:00 +0000] "GET / HTTP/1.1" 503 0`
+	expected := ColorCyan + "127.0.0.1" + ColorReset + " - - " + ColorBlue + "[15/Oct/2026:12:00:00 +0000]" + ColorReset +
+		` "` + ColorYellow + "GET" + ColorReset + ` / HTTP/1.1" ` + ColorRed + "503" + ColorReset + " 0"
+	if got := ColorAccessLog(line); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestTailAutoColoring(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+	if err := os.WriteFile(testFile, []byte(`{"level":"INFO","msg":"started"}`+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"detected", []Option{WithAutoColoring(), WithSyntaxHighlighting("level")}, ColorSlogJSON(`{"level":"INFO","msg":"started"}`)},
+		{"profile first", []Option{WithAutoColoring(), WithColoringProfile(NoColoring)}, `{"level":"INFO","msg":"started"}`},
+	}
+	for _, tt := range tests {
+		tail := New(testFile, tt.opts...)
+		if err := tail.Start(); err != nil {
+			t.Fatalf("%s: failed to start tail: %v", tt.name, err)
+		}
+		select {
+		case line := <-tail.Lines():
+			if line != tt.want {
+				t.Errorf("%s: expected %q, got %q", tt.name, tt.want, line)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s: timeout waiting for the line", tt.name)
+		}
+		if profile := tail.(*Tail).autoProfile; profile != "json" {
+			t.Errorf("%s: expected the json profile detected, got %q", tt.name, profile)
+		}
+		tail.Stop()
+	}
+}
+
+func TestColorRuleGroups(t *testing.T) {
+	plugin := NewColorRuleGroups(regexp.MustCompile(`^(?P<time>\S+) (?P<level>\w+) (?:req=(?P<req>\w+) )?`), map[string]string{
+		"time":  ColorDarkGray,
+		"level": ColorByLevel,
+		"req":   ColorCyan,
+	})
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"12:00:01 warn req=a1b2 slow query", ColorDarkGray + "12:00:01" + ColorReset + " " + ColorYellow + "warn" + ColorReset + " req=" + ColorCyan + "a1b2" + ColorReset + " slow query"},
+		// a group not matched, or not a level, is left as it is
+		{"12:00:02 ERROR failed", ColorDarkGray + "12:00:02" + ColorReset + " " + ColorRed + "ERROR" + ColorReset + " failed"},
+		{"12:00:03 started up", ColorDarkGray + "12:00:03" + ColorReset + " started up"},
+		{"no match", "no match"},
+	}
+	for _, tt := range tests {
+		got, ok := plugin.Apply(tt.line)
+		if !ok {
+			t.Errorf("Apply(%q) dropped the line", tt.line)
+		}
+		if got != tt.expected {
+			t.Errorf("Apply(%q) = %q, expected %q", tt.line, got, tt.expected)
+		}
+	}
+
+	// a group nested in a colored one is not colored again, every match is colored
+	nested := NewColorRuleGroups(regexp.MustCompile(`(?P<kv>(?P<key>\w+)=\w+)`), map[string]string{
+		"kv":  ColorBlue,
+		"key": ColorRed,
+	})
+	got, _ := nested.Apply("a=1 b=2")
+	if expected := ColorBlue + "a=1" + ColorReset + " " + ColorBlue + "b=2" + ColorReset; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if spans := Spans(got); len(spans) != 3 || StripAnsiCodes(got) != "a=1 b=2" {
+		t.Errorf("Expected valid ANSI output, got spans %v", spans)
+	}
+}
+
+func TestColorizeLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		profiles []Profile
+		expected []Span
+	}{
+		{"INFO started", []Profile{ColorLevels}, []Span{{"INFO", ColorGreen}, {" started", ""}}},
+		{`msg="a b" n=1`, []Profile{ColorSlogText}, []Span{
+			{"msg", ColorCyan}, {"=", ""}, {`"a b"`, ColorBlue}, {" ", ""}, {"n", ColorCyan}, {"=", ""}, {"1", ColorBlue},
+		}},
+		{"2026-10-14T12:00:00Z host sshd[42]: #033[1mbold", []Profile{ColorSyslog}, []Span{
+			{"2026-10-14T12:00:00Z", ColorBlue}, {" ", ""}, {"host", ColorCyan}, {" ", ""}, {"sshd[42]", ColorYellow}, {": ", ""}, {"bold", "\033[1m"},
+		}},
+		// the profiles compose in order, a custom one included
+		{"WARN disk", []Profile{ColorLevels, func(line string) string {
+			return strings.ReplaceAll(line, "disk", Colorize("disk", ColorOrange))
+		}}, []Span{{"WARN", ColorYellow}, {" ", ""}, {"disk", ColorOrange}}},
+		{"plain", nil, []Span{{"plain", ""}}},
+	}
+	for _, tt := range tests {
+		colored := ColorizeLine(tt.line, tt.profiles...)
+		if got := Spans(colored); !slices.Equal(got, tt.expected) {
+			t.Errorf("ColorizeLine(%q) = %q, expected spans %q", tt.line, got, tt.expected)
+		}
+	}
+
+	// a profile is a plugin, the same as the syntax names of WithSyntaxHighlighting
+	got, _ := Profile(ColorLevels).Apply("ERROR x")
+	if expected, _ := NewWithSyntaxHighlighting("levels").Apply("ERROR x"); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestColorPipeline(t *testing.T) {
+	latency := NewThresholdColoring(regexp.MustCompile(`latency=(\d+)`), Threshold{Min: 1000, Color: ColorRed})
+	pipeline := ColorPipeline{Profile(ColorLevels), latency, Profile(ColorSlogText), Profile(ColorLevels)}
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		// each pass colors only what the passes before it left in the default color,
+		// the second level pass and the slog pass do not wrap ERROR again
+		{"level=ERROR latency=1500 msg=done",
+			"level=" + Colorize("ERROR", ColorRed) + " latency=" + Colorize("1500", ColorRed) + " " +
+				Colorize("msg", ColorCyan) + "=" + Colorize("done", ColorBlue)},
+		// the colors of the line itself are kept
+		{ColorMagenta + "INFO" + ColorReset + " INFO", Colorize("INFO", ColorMagenta) + " " + Colorize("INFO", ColorGreen)},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		got, ok := pipeline.Apply(tt.line)
+		if !ok || got != tt.expected {
+			t.Errorf("Apply(%q) = %q, %v, expected %q", tt.line, got, ok, tt.expected)
+		}
+	}
+
+	// applied one after the other the same profiles nest the codes
+	if nested, _ := NewWithSyntaxHighlighting("level", "slog-text").Apply("level=ERROR"); !strings.Contains(nested, ColorBlue+ColorRed) {
+		t.Errorf("Expected nested codes, got %q", nested)
+	}
+	if colored, _ := (ColorPipeline{Profile(ColorLevels), Profile(ColorSlogText)}).Apply("level=ERROR"); colored != "level="+Colorize("ERROR", ColorRed) {
+		t.Errorf("Unexpected pipeline colors %q", colored)
+	}
+	if name := pluginName(0, pipeline); name != "colors:plugin[0],threshold:latency=(\\d+),plugin[2],plugin[3]" {
+		t.Errorf("Unexpected name %q", name)
+	}
+}
+
+// dropPlugin drops the lines containing a word
+type dropPlugin string
+
+func (p dropPlugin) Apply(line string) (string, bool) {
+	return line, !strings.Contains(line, string(p))
+}
+
+func TestWithColoringProfile(t *testing.T) {
+	opts := []Option{WithSyntaxHighlighting("level"), WithPlugins(dropPlugin("skip")), WithJSONLevelField("level")}
+	tests := []struct {
+		coloring string
+		expected []string
+	}{
+		{"", []string{"syntax:level", "plugin[1]", "json-level:level"}},
+		{"none", []string{"plugin[0]"}},
+		{"SLOG-TEXT", []string{"plugin[0]", "plugin[1]"}},
+		{"unknown", []string{"syntax:level", "plugin[1]", "json-level:level"}},
+	}
+	for _, tt := range tests {
+		tail := New("test.log", append(slices.Clone(opts), WithColoringProfile(tt.coloring))...).(*Tail)
+		var names []string
+		for i, p := range tail.plugins {
+			names = append(names, pluginName(i, p))
+		}
+		if !slices.Equal(names, tt.expected) {
+			t.Errorf("%q: expected plugins %q, got %q", tt.coloring, tt.expected, names)
+		}
+	}
+
+	// the plugins of UpdateOptions are recolored too
+	tail := New("test.log", WithColoringProfile("none")).(*Tail)
+	tail.UpdateOptions(WithSyntaxHighlighting("level"), WithPlugins(dropPlugin("skip")))
+	if _, plugins := tail.rules(); len(plugins) != 1 {
+		t.Errorf("Expected the coloring removed, got %d plugins", len(plugins))
+	}
+	if line, ok := tail.process("ERROR skip"); ok {
+		t.Errorf("Expected the line dropped, got %q", line)
+	}
+}
+
+func TestDetectJSONLevel(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{`{"severity":"Warning","msg":"slow"}`, LevelWarn},
+		{`{"severity":"critical"}`, LevelError},
+		{`{"severity":30}`, LevelInfo},
+		{`{"severity":10}`, LevelTrace},
+		{`app | {"severity":"debug","msg":"INFO keyword ignored"}`, LevelDebug},
+		// not JSON or no known level, the keywords apply
+		{`{"severity":"verbose","msg":"ERROR"}`, LevelError},
+		{`{"level":"debug"} INFO`, LevelInfo},
+		{`INFO {broken`, LevelInfo},
+		{`{"msg":"plain"}`, ""},
+	}
+	for _, tt := range tests {
+		if got := DetectJSONLevel(tt.line, "severity"); got != tt.expected {
+			t.Errorf("DetectJSONLevel(%q) = %q, expected %q", tt.line, got, tt.expected)
+		}
+	}
+}
+
+func TestColorLogfmt(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{`level=error msg="dial failed" dur=12ms`,
+			ColorCyan + "level" + ColorReset + "=" + ColorRed + "error" + ColorReset + " " +
+				ColorCyan + "msg" + ColorReset + "=" + ColorOrange + `"dial failed"` + ColorReset + " " +
+				ColorCyan + "dur" + ColorReset + "=12ms"},
+		// escaped quotes stay in the value, the level may be quoted
+		{`lvl="warn" msg="said \"hi there\"" user.id=7`,
+			ColorCyan + "lvl" + ColorReset + "=" + ColorYellow + `"warn"` + ColorReset + " " +
+				ColorCyan + "msg" + ColorReset + "=" + ColorOrange + `"said \"hi there\""` + ColorReset + " " +
+				ColorCyan + "user.id" + ColorReset + "=7"},
+		{`ts=1 empty= level=verbose`,
+			ColorCyan + "ts" + ColorReset + "=1 " + ColorCyan + "empty" + ColorReset + "= " +
+				ColorCyan + "level" + ColorReset + "=verbose"},
+		// not logfmt
+		{"GET /?a=b HTTP/1.1", "GET /?a=b HTTP/1.1"},
+		{"plain text", "plain text"},
+	}
+	for _, tt := range tests {
+		if got := ColorLogfmt(tt.line); got != tt.expected {
+			t.Errorf("ColorLogfmt(%q) = %q, expected %q", tt.line, got, tt.expected)
+		}
+	}
+
+	// the level of logfmt lines feeds the level detection
+	levels := []struct {
+		line     string
+		expected string
+	}{
+		{`level=warning msg="ERROR in the message"`, LevelWarn},
+		{`lvl=crit msg=x`, LevelError},
+		{`level="debug" msg=x`, LevelDebug},
+		{`level=verbose msg="INFO"`, LevelInfo},
+		{`msg="no level" ERROR`, LevelError},
+		{`sublevel=info msg=x`, ""},
+	}
+	for _, tt := range levels {
+		if got := DetectLevel(tt.line); got != tt.expected {
+			t.Errorf("DetectLevel(%q) = %q, expected %q", tt.line, got, tt.expected)
+		}
+	}
+
+	tail := New("test.log", WithSyntaxColoring("logfmt")).(*Tail)
+	if line, _ := tail.process("level=info msg=ok"); !strings.Contains(line, ColorGreen+"info"+ColorReset) {
+		t.Errorf("Expected the logfmt profile to color the level, got %q", line)
+	}
+}
+
+func TestSanitizeControl(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"plain line", "plain line"},
+		{ColorRed + "ERROR" + ColorReset + " kept", ColorRed + "ERROR" + ColorReset + " kept"},
+		{"\x1b[1;38;5;196mbold\x1b[0m", "\x1b[1;38;5;196mbold\x1b[0m"},
+		// clear screen, cursor home and moves
+		{"before\x1b[2J\x1b[Hafter", "beforeafter"},
+		{"a\x1b[10;20Hb\x1b[3Ac\x1b[Kd", "abcd"},
+		{"progress 50%\rprogress 100%", "progress 50%progress 100%"},
+		// window title (OSC) and a C1 CSI
+		{"x\x1b]0;pwned\ay", "xy"},
+		{"x\x1b]2;pwned\x1b\\y", "xy"},
+		{"x\u009b2Jy", "x2Jy"},
+		{"tab\tvt\vff\fbell\a", "tab\tvt ff bell"},
+		{"multi\nline", "multi\nline"},
+		{"日本語 ok", "日本語 ok"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeControl(tt.line); got != tt.expected {
+			t.Errorf("SanitizeControl(%q) = %q, expected %q", tt.line, got, tt.expected)
+		}
+	}
+
+	tail := New("test.log", WithSanitizeControl(), WithSyntaxHighlighting("level")).(*Tail)
+	if got, _ := tail.process("\x1b[2JERROR\x1b[1;1H gone"); got != ColorRed+"ERROR"+ColorReset+" gone" {
+		t.Errorf("Expected the sequences removed before the coloring, got %q", got)
+	}
+}
+
+func TestTailTransform(t *testing.T) {
+	reformat := func(line string) string {
+		var entry struct {
+			Level string `json:"level"`
+			Time  string `json:"time"`
+			Msg   string `json:"msg"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return ""
+		}
+		return entry.Level + " | " + entry.Time + " | " + entry.Msg
+	}
+	tail := New("test.log",
+		WithTransform(reformat),
+		WithTransform(strings.ToUpper),
+		WithPattern("^ERROR|^INFO"),
+		WithSyntaxHighlighting("level"),
+	).(*Tail)
+
+	tests := []struct {
+		line     string
+		expected string
+		ok       bool
+	}{
+		{`{"level":"error","time":"12:00","msg":"disk full"}`, ColorRed + "ERROR" + ColorReset + " | 12:00 | DISK FULL", true},
+		// the filter matches the transformed line
+		{`{"level":"debug","time":"12:01","msg":"ERROR in message"}`, "", false},
+		// dropped by the transform
+		{"not json ERROR", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := tail.process(tt.line)
+		if ok != tt.ok || (ok && got != tt.expected) {
+			t.Errorf("process(%q) = %q, %v, expected %q, %v", tt.line, got, ok, tt.expected, tt.ok)
+		}
+	}
+
+	_, rules, _ := tail.processRules(`{"level":"info","time":"12:02","msg":"ok"}`, "", true)
+	if expected := []string{"transform[0]", "transform[1]", "pattern:^ERROR|^INFO", "syntax:level"}; !slices.Equal(rules, expected) {
+		t.Errorf("Expected rules %v, got %v", expected, rules)
+	}
+}
+
+func TestTailNormalizeNewlines(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.log")
+	// a leading UTF-8 BOM, CRLF, LF and a lone CR
+	if err := os.WriteFile(testFile, []byte("\uFEFFfirst\r\nsecond\nthird\rfourth\r\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile, WithPollInterval(50*time.Millisecond), WithNormalizeNewlines(), WithByteOffset())
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	f, err := os.OpenFile(testFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	f.WriteString("fifth\r\r\nsixth \uFEFFseventh\n")
+	f.Close()
+
+	for _, expected := range []string{"0:first", "10:second", "17:third", "23:fourth", "31:fifth", "39:sixth seventh"} {
+		select {
+		case line := <-tail.Lines():
+			if line != expected {
+				t.Errorf("Expected %q, got %q", expected, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for %q", expected)
+		}
+	}
+}
+
+func TestTailCollapseBlankLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"collapsed", []Option{WithCollapseBlankLines()}, []string{"first", "", "second", "", "third"}},
+		{"default", nil, []string{"first", "", "", "second", " \t", "", "", "third"}},
+	}
+	for _, tt := range tests {
+		testFile := filepath.Join(tmpDir, tt.name+".log")
+		if err := os.WriteFile(testFile, []byte("start\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		tail := New(testFile, append([]Option{WithPollInterval(50 * time.Millisecond), WithLast(0)}, tt.opts...)...)
+		if err := tail.Start(); err != nil {
+			t.Fatalf("%s: failed to start tail: %v", tt.name, err)
+		}
+		appendToFile(t, testFile, "first\n\n\nsecond\n \t\n\n")
+		time.Sleep(150 * time.Millisecond)
+		// the run continues across the polls
+		appendToFile(t, testFile, "\nthird\n")
+
+		for _, expected := range tt.want {
+			select {
+			case line := <-tail.Lines():
+				if line != expected {
+					t.Errorf("%s: expected %q, got %q", tt.name, expected, line)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("%s: timeout waiting for %q", tt.name, expected)
+			}
+		}
+		tail.Stop()
+	}
+}
+
+func TestTailMultiline(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	if err := os.WriteFile(testFile, []byte("2024 INFO start\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile,
+		WithPollInterval(100*time.Millisecond),
+		WithMultilinePattern(regexp.MustCompile(`^\d{4} `)),
+		WithPattern("ERROR|INFO"),
+	)
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer func() {
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	appendToFile(t, testFile, "2024 ERROR boom\n\tat main.go:10\n")
+	time.Sleep(50 * time.Millisecond)
+	appendToFile(t, testFile, "\tat main.go:20\n2024 INFO done\n")
+
+	expected := []string{
+		"2024 INFO start",
+		"2024 ERROR boom\n\tat main.go:10\n\tat main.go:20",
+		// the trailing record is completed when no more lines arrive
+		"2024 INFO done",
+	}
+
+	timeout := time.After(2 * time.Second)
+	for _, exp := range expected {
+		select {
+		case line := <-tail.Lines():
+			if line != exp {
+				t.Errorf("Expected %q, got %q", exp, line)
+			}
+		case <-timeout:
+			t.Fatalf("Timeout waiting for %q", exp)
+		}
+	}
+}
+
+func TestTailFollowSymlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	target1 := filepath.Join(tmpDir, "app-1.log")
+	target2 := filepath.Join(tmpDir, "app-2.log")
+	link := filepath.Join(tmpDir, "current")
+
+	if err := os.WriteFile(target1, []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(target2, []byte("line 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.Symlink(target1, link); err != nil {
+		t.Skipf("Symlinks are not supported: %v", err)
+	}
+
+	tail := New(link, WithPollInterval(100*time.Millisecond), WithFollowSymlink())
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer func() {
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	// written to the old target right before the symlink flips
+	appendToFile(t, target1, "line 2\n")
+
+	// flip the symlink atomically
+	tmpLink := link + ".tmp"
+	if err := os.Symlink(target2, tmpLink); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Rename(tmpLink, link); err != nil {
+		t.Fatalf("Failed to flip symlink: %v", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	appendToFile(t, target2, "line 4\n")
+	// the old target is not followed anymore
+	appendToFile(t, target1, "stale line\n")
+
+	timeout := time.After(2 * time.Second)
+	lines := []string{}
+	for i := 0; i < 4; i++ {
+		select {
+		case line := <-tail.Lines():
+			lines = append(lines, line)
+		case <-timeout:
+			t.Fatalf("Timeout waiting for lines, got %d lines: %v", len(lines), lines)
+		}
+	}
+
+	for i, line := range lines {
+		expected := fmt.Sprintf("line %d", i+1)
+		if line != expected {
+			t.Errorf("Expected '%s', got '%s'", expected, line)
+		}
+	}
+
+	select {
+	case line := <-tail.Lines():
+		t.Errorf("Unexpected line '%s'", line)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestRotationSchemes(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "app.log")
+	for _, name := range []string{
+		"app.log", "app.log.1", "app.log.2.gz", "app.log-20261014.gz", "app.log-20261015", "app.log.bak",
+		"app.1.log", "app.2.log.gz", "app.10.log", "app-2026-10-14.log", "app-2026-10-15.log",
+	} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), nil, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	join := func(names ...string) []string {
+		for i, name := range names {
+			names[i] = filepath.Join(tmpDir, name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name     string
+		scheme   RotationScheme
+		path     string
+		live     string
+		archives []string
+	}{
+		{"logrotate", LogrotateScheme(), path, path,
+			join("app.log-20261014.gz", "app.log-20261015", "app.log.2.gz", "app.log.1")},
+		{"numbered lowest", NumberedScheme(LowestIsLive), path, path,
+			join("app.10.log", "app.2.log.gz", "app.1.log")},
+		{"numbered highest", NumberedScheme(HighestIsLive), filepath.Join(tmpDir, "app.txt"), filepath.Join(tmpDir, "app.txt"), nil},
+		{"regexp highest", RegexpScheme(regexp.MustCompile(`^app\.(\d+)\.log(\.gz)?$`), HighestIsLive), path,
+			filepath.Join(tmpDir, "app.10.log"), join("app.1.log", "app.2.log.gz")},
+		{"regexp dates", RegexpScheme(regexp.MustCompile(`^app-([\d-]+)\.log$`), HighestIsLive), path,
+			filepath.Join(tmpDir, "app-2026-10-15.log"), join("app-2026-10-14.log")},
+	}
+	for _, tt := range tests {
+		live, archives := tt.scheme.Files(tt.path)
+		if live != tt.live || !slices.Equal(archives, tt.archives) {
+			t.Errorf("%s: expected %s after %v, got %s after %v", tt.name, tt.live, tt.archives, live, archives)
+		}
+	}
+}
+
+func TestTailRotationPattern(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "app.log")
+	if err := os.WriteFile(filepath.Join(tmpDir, "app.1.log"), []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "app.2.log"), []byte("line 2\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	scheme := NumberedScheme(HighestIsLive)
+	_, archives := scheme.Files(path)
+	tail := New(path, WithPollInterval(100*time.Millisecond), WithRotationPattern(scheme), WithHistory(archives...))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer func() {
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	// written to the live file right before the next one starts
+	appendToFile(t, filepath.Join(tmpDir, "app.2.log"), "line 3\n")
+	if err := os.WriteFile(filepath.Join(tmpDir, "app.3.log"), []byte("line 4\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	timeout := time.After(2 * time.Second)
+	lines := []string{}
+	for i := 0; i < 4; i++ {
+		select {
+		case line := <-tail.Lines():
+			lines = append(lines, line)
+		case <-timeout:
+			t.Fatalf("Timeout waiting for lines, got %d lines: %v", len(lines), lines)
+		}
+	}
+
+	for i, line := range lines {
+		expected := fmt.Sprintf("line %d", i+1)
+		if line != expected {
+			t.Errorf("Expected '%s', got '%s'", expected, line)
+		}
+	}
+}
+
+// TestTailStartWhileWriting tests no line completed after Start is lost
+// or split while a writer appends continuously, in pieces, during Start
+func TestTailStartWhileWriting(t *testing.T) {
+	for _, last := range []int{0, 5} {
+		for run := range 10 {
+			testFile := filepath.Join(t.TempDir(), "test.log")
+			if err := os.WriteFile(testFile, []byte("head\n"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			f, err := os.OpenFile(testFile, os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				t.Fatalf("Failed to open test file: %v", err)
+			}
+
+			var written atomic.Int64 // lines completely written
+			stopWriter := make(chan struct{})
+			writerDone := make(chan struct{})
+			go func() {
+				defer close(writerDone)
+				for i := int64(0); ; i++ {
+					select {
+					case <-stopWriter:
+						return
+					default:
+					}
+					// a line in two writes, the reader may see half of it
+					fmt.Fprintf(f, "line %06d ", i)
+					fmt.Fprintln(f, strings.Repeat("x", int(i%50)))
+					written.Store(i + 1)
+				}
+			}()
+			time.Sleep(time.Millisecond)
+
+			tail := New(testFile, WithPollInterval(10*time.Millisecond), WithLast(last), WithBufferSize(100000))
+			if err := tail.Start(); err != nil {
+				t.Fatalf("Failed to start tail: %v", err)
+			}
+			// the lines after these were written after the snapshot of Start,
+			// but the next one may be complete in the file and not counted yet
+			after := written.Load() + 1
+			time.Sleep(20 * time.Millisecond)
+			close(stopWriter)
+			<-writerDone
+			f.Close()
+			total := written.Load()
+
+			var got []int64
+			timeout := time.After(5 * time.Second)
+			for len(got) == 0 || got[len(got)-1] < total-1 {
+				select {
+				case line := <-tail.Lines():
+					if line == "head" && len(got) == 0 && last > 0 {
+						// the seed line is one of the last lines when
+						// fewer lines were written before Start
+						continue
+					}
+					var n int64
+					if _, err := fmt.Sscanf(line, "line %d ", &n); err != nil || !strings.HasSuffix(line, strings.Repeat("x", int(n%50))) {
+						t.Fatalf("last=%d run %d: split line %q", last, run, line)
+					}
+					if len(got) > 0 && n != got[len(got)-1]+1 {
+						t.Fatalf("last=%d run %d: line %d after %d", last, run, n, got[len(got)-1])
+					}
+					got = append(got, n)
+				case <-timeout:
+					t.Fatalf("last=%d run %d: timeout, got up to %v of %d", last, run, got[max(0, len(got)-1):], total)
+				}
+			}
+			if got[0] > after {
+				t.Fatalf("last=%d run %d: lost lines %d to %d written after Start", last, run, after, got[0]-1)
+			}
+			tail.Stop()
+		}
+	}
+}
+
+func TestTailResumeAfter(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(testFile, []byte("line 1\nline 2\nline 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// resumes after "line 2" at offset 7
+	tail := New(testFile, WithPollInterval(50*time.Millisecond), WithLast(10), WithResumeAfter(7))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	f, err := os.OpenFile(testFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+	fmt.Fprintln(f, "line 4")
+	f.Close()
+
+	for _, expected := range []string{"line 3", "line 4"} {
+		select {
+		case line := <-tail.Lines():
+			if line != expected {
+				t.Errorf("Expected '%s', got '%s'", expected, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for '%s'", expected)
+		}
+	}
+
+	// an offset beyond the end of a truncated file starts with the last lines
+	tail2 := New(testFile, WithPollInterval(50*time.Millisecond), WithLast(1), WithResumeAfter(1000))
+	if err := tail2.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail2.Stop()
+
+	select {
+	case line := <-tail2.Lines():
+		if line != "line 4" {
+			t.Errorf("Expected 'line 4', got '%s'", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for line")
+	}
+}
+
+func TestEstimateLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	small := filepath.Join(tmpDir, "small.log")
+	if err := os.WriteFile(small, []byte("one\ntwo\nthree"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	lines, estimated, err := EstimateLines(small)
+	if err != nil || lines != 3 || estimated {
+		t.Errorf("Expected 3 exact lines, got %d (estimated %v, err %v)", lines, estimated, err)
+	}
+
+	var sb strings.Builder
+	for i := range 20000 {
+		fmt.Fprintf(&sb, "%06d %s\n", i, strings.Repeat("x", i%40))
+	}
+	large := filepath.Join(tmpDir, "large.log")
+	if err := os.WriteFile(large, []byte(sb.String()), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	lines, estimated, err = EstimateLines(large)
+	if err != nil || !estimated {
+		t.Fatalf("Expected an estimate, got %d (estimated %v, err %v)", lines, estimated, err)
+	}
+	if lines < 19000 || lines > 21000 {
+		t.Errorf("Expected about 20000 lines, got %d", lines)
+	}
+	if lines, err := CountLines(large); err != nil || lines != 20000 {
+		t.Errorf("Expected 20000 lines, got %d (err %v)", lines, err)
+	}
+}
+
+func TestTailByteOffset(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	if err := os.WriteFile(testFile, []byte("line 1\nline 2\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile, WithPollInterval(100*time.Millisecond), WithByteOffset())
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer func() {
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	appendToFile(t, testFile, "line 3\nline 4\n")
+
+	expected := []string{"0:line 1", "7:line 2", "14:line 3", "21:line 4"}
+	timeout := time.After(2 * time.Second)
+	for _, exp := range expected {
+		select {
+		case line := <-tail.Lines():
+			if line != exp {
+				t.Errorf("Expected %q, got %q", exp, line)
+			}
+		case <-timeout:
+			t.Fatalf("Timeout waiting for %q", exp)
+		}
+	}
+}
+
+func TestTailByteOffsetWithoutOffset(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	// an archived line that looks like an offset prefix
+	if err := os.WriteFile(testFile+".1", []byte("12:30:45 archived\n"), 0644); err != nil {
+		t.Fatalf("Failed to create rotated file: %v", err)
+	}
+	if err := os.WriteFile(testFile, []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile, WithPollInterval(50*time.Millisecond), WithHistory(testFile+".1"), WithByteOffset())
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+
+	expected := []string{"-1:12:30:45 archived", "0:line 1"}
+	timeout := time.After(2 * time.Second)
+	for _, exp := range expected {
+		select {
+		case line := <-tail.Lines():
+			if line != exp {
+				t.Errorf("Expected %q, got %q", exp, line)
+			}
+			offset, rest, ok := cutOffset(line)
+			if !ok || fmt.Sprintf("%d:%s", offset, rest) != exp {
+				t.Errorf("cutOffset(%q) = %d, %q, %v", line, offset, rest, ok)
+			}
+		case <-timeout:
+			t.Fatalf("Timeout waiting for %q", exp)
+		}
+	}
+}
+
+func TestTailBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		n        int64
+		start    int64
+		expected []string
+	}{
+		{"snaps to the next line", "line 1\nline 2\nline 3\n", 10, 14, []string{"14:line 3", "21:line 4"}},
+		{"at a line boundary", "line 1\nline 2\nline 3\n", 14, 7, []string{"7:line 2", "14:line 3", "21:line 4"}},
+		{"larger than the file", "line 1\nline 2\nline 3\n", 100, 0, []string{"0:line 1", "7:line 2", "14:line 3", "21:line 4"}},
+		{"no line boundary", "line 1\nabcdefgh", 4, 11, []string{"11:efghline 4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "test.log")
+			if err := os.WriteFile(testFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			tail := New(testFile, WithPollInterval(50*time.Millisecond), WithTailBytes(tt.n), WithByteOffset())
+			if err := tail.Start(); err != nil {
+				t.Fatalf("Failed to start tail: %v", err)
+			}
+			defer func() {
+				tail.Stop()
+				// Give time for file handles to close on Windows
+				time.Sleep(50 * time.Millisecond)
+			}()
+			if start := tail.(*Tail).Stats().StartOffset; start != tt.start {
+				t.Errorf("Expected start offset %d, got %d", tt.start, start)
+			}
+
+			appendToFile(t, testFile, "line 4\n")
+			timeout := time.After(2 * time.Second)
+			for _, exp := range tt.expected {
+				select {
+				case line := <-tail.Lines():
+					if line != exp {
+						t.Errorf("Expected %q, got %q", exp, line)
+					}
+				case <-timeout:
+					t.Fatalf("Timeout waiting for %q", exp)
+				}
+			}
+		})
+	}
+}
+
+func TestTailUpdateOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	if err := os.WriteFile(testFile, []byte("INFO first\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile, WithPollInterval(50*time.Millisecond), WithPattern("INFO")).(*Tail)
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer func() {
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	if line := <-tail.Lines(); line != "INFO first" {
+		t.Fatalf("Expected %q, got %q", "INFO first", line)
+	}
+
+	tail.UpdateOptions(WithPattern("WARN"), WithSyntaxHighlighting("level"))
+	appendToFile(t, testFile, "INFO second\nWARN third\n")
+
+	select {
+	case line := <-tail.Lines():
+		if line != ColorYellow+"WARN"+ColorReset+" third" {
+			t.Errorf("Expected the line matching the new rules, got %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for line")
+	}
+}
+
+func TestTailServerTimestamp(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	if err := os.WriteFile(testFile, []byte("ERROR boom\n\tat main.go:10\nINFO ok\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile,
+		WithPollInterval(100*time.Millisecond),
+		WithMultilinePattern(regexp.MustCompile(`^[A-Z]+ `)),
+		WithServerTimestamp(time.DateOnly),
+		WithSyntaxHighlighting("level"),
+	)
+	before := time.Now().Format(time.DateOnly)
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer func() {
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	expected := []string{
+		// the record is stamped once, then colored
+		before + " " + ColorRed + "ERROR" + ColorReset + " boom\n\tat main.go:10",
+		before + " " + ColorGreen + "INFO" + ColorReset + " ok",
+	}
+	timeout := time.After(2 * time.Second)
+	for _, exp := range expected {
+		select {
+		case line := <-tail.Lines():
+			if line != exp {
+				t.Errorf("Expected %q, got %q", exp, line)
+			}
+		case <-timeout:
+			t.Fatalf("Timeout waiting for %q", exp)
+		}
+	}
+}
+
+func TestTailRuleDebug(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	if err := os.WriteFile(testFile, []byte("ERROR disk full\nINFO started\nDEBUG skipped\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile,
+		WithPollInterval(100*time.Millisecond),
+		WithPattern("ERROR"),
+		WithPattern("disk"),
+		WithPattern("INFO"),
+		WithSyntaxHighlighting("level"),
+		WithRuleDebug(),
+	)
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer func() {
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	expected := []RuleFrame{
+		{Line: ColorRed + "ERROR" + ColorReset + " disk full", Rules: []string{"pattern:ERROR", "pattern:disk", "syntax:level"}},
+		{Line: ColorGreen + "INFO" + ColorReset + " started", Rules: []string{"pattern:INFO", "syntax:level"}},
+	}
+	timeout := time.After(2 * time.Second)
+	for _, exp := range expected {
+		select {
+		case line := <-tail.Lines():
+			var frame RuleFrame
+			if err := json.Unmarshal([]byte(line), &frame); err != nil {
+				t.Fatalf("Expected JSON frame, got %q: %v", line, err)
+			}
+			if frame.Line != exp.Line || !slices.Equal(frame.Rules, exp.Rules) {
+				t.Errorf("Expected %+v, got %+v", exp, frame)
+			}
+		case <-timeout:
+			t.Fatalf("Timeout waiting for %q", exp.Line)
+		}
+	}
+	select {
+	case line := <-tail.Lines():
+		t.Errorf("Unexpected line %q", line)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestTailPartialLine(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	if err := os.WriteFile(testFile, []byte("line 1\npart"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile, WithPollInterval(50*time.Millisecond), WithByteOffset())
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer func() {
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	expect := func(exp string) {
+		t.Helper()
+		select {
+		case line := <-tail.Lines():
+			if line != exp {
+				t.Errorf("Expected %q, got %q", exp, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for %q", exp)
+		}
+	}
+	expectNothing := func() {
+		t.Helper()
+		select {
+		case line := <-tail.Lines():
+			t.Errorf("Unexpected line %q", line)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	// the partial line of the initial content waits for its newline
+	expect("0:line 1")
+	expectNothing()
+
+	// append completes the previously partial line, growing over several polls
+	appendToFile(t, testFile, "ial")
+	expectNothing()
+	appendToFile(t, testFile, " line\nline 3\n")
+	expect("7:partial line")
+	expect("20:line 3")
+	expectNothing()
+}
+
+func TestTailPartialLineOnStop(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+
+	if err := os.WriteFile(testFile, []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tail := New(testFile, WithPollInterval(50*time.Millisecond))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	if line := <-tail.Lines(); line != "line 1" {
+		t.Fatalf("Expected %q, got %q", "line 1", line)
+	}
+
+	appendToFile(t, testFile, "no newline")
+	// let the poll read the partial line
+	time.Sleep(200 * time.Millisecond)
+	tail.Stop()
+
+	var lines []string
+	for line := range tail.Lines() {
+		lines = append(lines, line)
+	}
+	if !slices.Equal(lines, []string{"no newline"}) {
+		t.Errorf("Expected the partial line once on stop, got %q", lines)
+	}
+	// Give time for file handles to close on Windows
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestTailCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skipf("sh is not available: %v", err)
+	}
+	receive := func(t *testing.T, tail ITail) string {
+		t.Helper()
+		select {
+		case line := <-tail.Lines():
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for a line")
+		}
+		return ""
+	}
+
+	t.Run("combined output", func(t *testing.T) {
+		tail := NewCommand("sh", "-c", "echo out; echo err >&2; printf last")
+		if err := tail.Start(); err != nil {
+			t.Fatalf("Failed to start tail: %v", err)
+		}
+		defer tail.Stop()
+		for _, exp := range []string{"out", "err", "last"} {
+			if line := receive(t, tail); line != exp {
+				t.Errorf("Expected %q, got %q", exp, line)
+			}
+		}
+	})
+
+	t.Run("restart on failure", func(t *testing.T) {
+		tail := New("sh", WithCommand("sh", "-c", "echo run; exit 3"), WithRestart(RestartOnFailure, 20*time.Millisecond))
+		if err := tail.Start(); err != nil {
+			t.Fatalf("Failed to start tail: %v", err)
+		}
+		defer tail.Stop()
+		for range 3 {
+			if line := receive(t, tail); line != "run" {
+				t.Errorf("Expected %q, got %q", "run", line)
+			}
+		}
+		if st := tail.(*Tail).Stats(); st.Error != "exit status 3" {
+			t.Errorf("Expected the exit status in the stats, got %q", st.Error)
+		}
+	})
+
+	t.Run("stop kills the command", func(t *testing.T) {
+		tail := New("sh", WithCommand("sh", "-c", "echo started; sleep 60 & wait"), WithRestart(RestartAlways, 0))
+		if err := tail.Start(); err != nil {
+			t.Fatalf("Failed to start tail: %v", err)
+		}
+		receive(t, tail)
+		start := time.Now()
+		tail.Stop()
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("Stop took %v", d)
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		tail := New("sh", WithCommandContext(ctx, "sh", "-c", "echo started; sleep 60"), WithRestart(RestartAlways, 10*time.Millisecond))
+		if err := tail.Start(); err != nil {
+			t.Fatalf("Failed to start tail: %v", err)
+		}
+		defer tail.Stop()
+		receive(t, tail)
+		cancel()
+		select {
+		case line := <-tail.Lines():
+			t.Errorf("Expected no restart after the context is done, got %q", line)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	if err := NewCommand("no-such-command-for-tailer").Start(); err == nil {
+		t.Error("Expected an error for an unknown command")
+	}
+}
+
+func TestTailFIFO(t *testing.T) {
+	tmpDir := t.TempDir()
+	pipe := filepath.Join(tmpDir, "test.pipe")
+	if err := exec.Command("mkfifo", pipe).Run(); err != nil {
+		t.Skipf("mkfifo is not available: %v", err)
+	}
+
+	tail := New(pipe, WithPollInterval(50*time.Millisecond))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+
+	expect := func(exp string) {
+		t.Helper()
+		select {
+		case line := <-tail.Lines():
+			if line != exp {
+				t.Errorf("Expected %q, got %q", exp, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for %q", exp)
+		}
+	}
+
+	write := func(content string) {
+		t.Helper()
+		w, err := os.OpenFile(pipe, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatalf("Failed to open pipe: %v", err)
+		}
+		fmt.Fprint(w, content)
+		w.Close()
+	}
+
+	// the partial last line is complete when the writer closes the pipe
+	write("line 1\nline 2\nno newline")
+	expect("line 1")
+	expect("line 2")
+	expect("no newline")
+	// then the next writer is followed
+	write("line 3\n")
+	expect("line 3")
+
+	// Stop unblocks the reader waiting for the next writer
+	done := make(chan struct{})
+	go func() {
+		tail.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop blocked on the pipe")
+	}
+}
+
+func TestTailCompressedPoll(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log.gz")
+
+	writeGzip := func(lines ...string) {
+		t.Helper()
+		tmpFile := testFile + ".tmp"
+		f, err := os.Create(tmpFile)
+		if err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		gz := gzip.NewWriter(f)
+		for _, line := range lines {
+			fmt.Fprintln(gz, line)
+		}
+		gz.Close()
+		f.Close()
+		if err := os.Rename(tmpFile, testFile); err != nil {
+			t.Fatalf("Failed to replace file: %v", err)
+		}
+	}
+
+	writeGzip("line 1", "line 2")
+
+	tail := New(testFile, WithPollInterval(100*time.Millisecond), WithCompressedPoll())
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer func() {
+		tail.Stop()
+		// Give time for file handles to close on Windows
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	writeGzip("line 1", "line 2", "line 3")
+	time.Sleep(200 * time.Millisecond)
+	writeGzip("line 1", "line 2", "line 3", "line 4")
+
+	timeout := time.After(2 * time.Second)
+	lines := []string{}
+	for i := 0; i < 4; i++ {
+		select {
+		case line := <-tail.Lines():
+			lines = append(lines, line)
+		case <-timeout:
+			t.Fatalf("Timeout waiting for lines, got %d lines: %v", len(lines), lines)
+		}
+	}
+	for i, line := range lines {
+		expected := fmt.Sprintf("line %d", i+1)
+		if line != expected {
+			t.Errorf("Expected '%s', got '%s'", expected, line)
+		}
+	}
+
+	select {
+	case line := <-tail.Lines():
+		t.Errorf("Unexpected duplicated line '%s'", line)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestParseLineTime(t *testing.T) {
+	tests := []struct {
+		line string
+		want time.Time
+		ok   bool
+	}{
+		{"2026-10-15T12:00:00Z INFO started", time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC), true},
+		{"2026-10-15T21:00:00.250+09:00 INFO started", time.Date(2026, 10, 15, 12, 0, 0, 250e6, time.UTC), true},
+		{"2026/10/15 21:00:00 +0900 started", time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC), true},
+		{"2026-10-15 12:00:00,500 INFO started", time.Date(2026, 10, 15, 12, 0, 0, 500e6, time.Local), true},
+		{`{"level":"INFO","time":"2026-10-15T12:00:00Z","msg":"started"}`, time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC), true},
+		{"\x1b[90m2026-10-15T12:00:00Z\x1b[0m INFO started", time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC), true},
+		{"    at com.example.Main.run(Main.java:42)", time.Time{}, false},
+		{strings.Repeat("x", 80) + " 2026-10-15T12:00:00Z", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseLineTime(tt.line)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("ParseLineTime(%q) = %v, %v, expected %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// a syslog time is of the current year
+	now := time.Now()
+	got, ok := ParseLineTime(now.Add(-time.Minute).Format(time.Stamp) + " host sshd[42]: Accepted")
+	if !ok || got.Year() != now.Add(-time.Minute).Year() || now.Sub(got) > 2*time.Minute {
+		t.Errorf("Expected the syslog time of a minute ago, got %v, %v", got, ok)
+	}
+}
+
+func TestTailReplayDuration(t *testing.T) {
+	tmpDir := t.TempDir()
+	stamp := func(ago time.Duration) string {
+		return time.Now().Add(-ago).UTC().Format(time.RFC3339)
+	}
+
+	testFile := filepath.Join(tmpDir, "test.log")
+	content := stamp(time.Hour) + " INFO old 1\n" +
+		stamp(20*time.Minute) + " INFO old 2\n" +
+		stamp(4*time.Minute) + " ERROR recent 1\n" +
+		"    at main.go:42\n" +
+		stamp(time.Minute) + " INFO recent 2\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	readAll := func(tail ITail) []string {
+		t.Helper()
+		var lines []string
+		for {
+			select {
+			case line := <-tail.Lines():
+				lines = append(lines, line)
+			case <-time.After(300 * time.Millisecond):
+				return lines
+			}
+		}
+	}
+
+	tail := New(testFile, WithLast(1), WithReplayDuration(5*time.Minute))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	lines := readAll(tail)
+	tail.Stop()
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], "recent 1") || lines[1] != "    at main.go:42" || !strings.HasSuffix(lines[2], "recent 2") {
+		t.Errorf("Expected the lines of the last 5 minutes, got %q", lines)
+	}
+
+	// without timestamps, the last lines of WithLast
+	plainFile := filepath.Join(tmpDir, "plain.log")
+	if err := os.WriteFile(plainFile, []byte("line 1\nline 2\nline 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	tail = New(plainFile, WithLast(2), WithReplayDuration(5*time.Minute))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	lines = readAll(tail)
+	tail.Stop()
+	if strings.Join(lines, ",") != "line 2,line 3" {
+		t.Errorf("Expected the last 2 lines, got %q", lines)
+	}
+}
+
+// fakeClock is a clock advanced by the tests, its tickers fire on Advance
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+	timers  []*fakeTicker // fire once
+}
+
+type fakeTicker struct {
+	clock   *fakeClock
+	c       chan time.Time
+	d       time.Duration
+	next    time.Time
+	stopped bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ft := &fakeTicker{clock: c, c: make(chan time.Time, 1), d: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, ft)
+	return ft
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ft := &fakeTicker{clock: c, c: make(chan time.Time, 1), next: c.now.Add(d)}
+	c.timers = append(c.timers, ft)
+	return ft.c
+}
+
+// Advance moves the time forward, firing the tickers and timers due,
+// a ticker once however many of its periods passed like time.Ticker
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for _, ft := range c.tickers {
+		if ft.stopped || ft.next.After(c.now) {
+			continue
+		}
+		select {
+		case ft.c <- c.now:
+		default:
+		}
+		for !ft.next.After(c.now) {
+			ft.next = ft.next.Add(ft.d)
+		}
+	}
+	c.timers = slices.DeleteFunc(c.timers, func(ft *fakeTicker) bool {
+		if ft.next.After(c.now) {
+			return false
+		}
+		ft.c <- c.now
+		return true
+	})
+}
+
+// waitTickers waits for n tickers started, e.g. by the goroutine of a tail
+func (c *fakeClock) waitTickers(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c.mu.Lock()
+		started := len(c.tickers)
+		c.mu.Unlock()
+		if started >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d tickers, got %d", n, started)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitTicked waits for the ticks fired by Advance to be received, so that the
+// goroutines handle them before the next Advance
+func (c *fakeClock) waitTicked(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c.mu.Lock()
+		pending := slices.ContainsFunc(c.tickers, func(ft *fakeTicker) bool { return !ft.stopped && len(ft.c) > 0 })
+		c.mu.Unlock()
+		if !pending {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the ticks to be received")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (ft *fakeTicker) C() <-chan time.Time {
+	return ft.c
+}
+
+func (ft *fakeTicker) Stop() {
+	ft.clock.mu.Lock()
+	defer ft.clock.mu.Unlock()
+	ft.stopped = true
+}
+
+func TestTailClock(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.log")
+	if err := os.WriteFile(testFile, []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	clk := newFakeClock()
+	tail := New(testFile, WithLast(0), WithPollInterval(time.Hour), withClock(clk))
+	if err := tail.Start(); err != nil {
+		t.Fatalf("Failed to start tail: %v", err)
+	}
+	defer tail.Stop()
+	clk.waitTickers(t, 1)
+
+	f, err := os.OpenFile(testFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	fmt.Fprintln(f, "line 2")
+	f.Close()
+
+	// the line is read by the poll of the next hour, without waiting for it
+	clk.Advance(time.Hour)
+	select {
+	case line := <-tail.Lines():
+		if line != "line 2" {
+			t.Errorf("Expected 'line 2', got %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the line of the poll")
+	}
+
+	if stats := tail.(*Tail).Stats(); !stats.LastLine.Equal(clk.Now()) {
+		t.Errorf("Expected the last line at %v, got %v", clk.Now(), stats.LastLine)
+	}
+	if !tail.(*Tail).IsActive() {
+		t.Error("Expected the tail active after a line")
+	}
+	clk.Advance(DefaultActiveWindow)
+	if tail.(*Tail).IsActive() {
+		t.Error("Expected the tail inactive after its active window")
+	}
+}
+
+func BenchmarkTailLastLines(b *testing.B) {
+	var content strings.Builder
+	content.WriteString("\n") // end the hole before the lines
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&content, "2024-06-01T12:00:00Z INFO request id=%d method=GET path=/api/v1/items status=200\n", i)
+	}
+	for _, size := range []int64{1 << 20, 1 << 30, 64 << 30} {
+		b.Run(fmt.Sprintf("File%dM", size>>20), func(b *testing.B) {
+			testFile := createSparseLog(b, size, content.String())
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tail := New(testFile, WithLast(1000), WithBufferSize(1000)).(*Tail)
+				if err := tail.openFile(); err != nil {
+					b.Fatalf("Failed to open file: %v", err)
+				}
+				if err := tail.readLastLines(1000); err != nil {
+					b.Fatalf("Failed to read last lines: %v", err)
+				}
+				for n := 0; n < 1000; n++ {
+					<-tail.c
+				}
+				tail.file.Close()
+			}
+		})
+	}
+}
+
+func BenchmarkTail(b *testing.B) {
+	tmpDir := b.TempDir()
+	testFile := filepath.Join(tmpDir, "bench.log")
+
+	f, err := os.Create(testFile)
+	if err != nil {
+		b.Fatalf("Failed to create bench file: %v", err)
+	}
+	const numLines = 100000
+	levels := []string{"DEBUG", "INFO", "WARN", "ERROR"}
+	for i := 0; i < numLines; i++ {
+		fmt.Fprintf(f, "2024-06-01T12:00:00Z %s request id=%d method=GET path=/api/v1/items status=200\n", levels[i%len(levels)], i)
+	}
+	stat, _ := f.Stat()
+	f.Close()
+
+	for _, size := range []int{4 * 1024, 32 * 1024, 256 * 1024} {
+		b.Run(fmt.Sprintf("ReadBuffer%dK", size/1024), func(b *testing.B) {
+			b.SetBytes(stat.Size())
+			for i := 0; i < b.N; i++ {
+				tail := New(testFile,
+					WithReadBufferSize(size),
+					WithBufferSize(1024),
+					WithSyntaxHighlighting("level", "slog-text"),
+				).(*Tail)
+				if err := tail.openFile(); err != nil {
+					b.Fatalf("Failed to open file: %v", err)
+				}
+
+				done := make(chan struct{})
+				go func() {
+					for n := 0; n < numLines; n++ {
+						<-tail.c
+					}
+					close(done)
+				}()
+				tail.readLines()
+				<-done
+				tail.file.Close()
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package tailer
 
 import (
 	"os"
+	"os/exec"
 	"syscall"
 )
 
@@ -16,8 +17,32 @@ func getInode(stat os.FileInfo) uint64 {
 	return 0
 }
 
+// inodeOf returns the inode of a file reported by FileInfo
+func inodeOf(stat os.FileInfo) uint64 {
+	return getInode(stat)
+}
+
 // openFileShared opens a file on Unix systems
 // On Unix, files can be renamed/deleted while open by default
 func openFileShared(filepath string) (*os.File, error) {
 	return os.Open(filepath)
 }
+
+// setProcessGroup runs the command in its own process group,
+// so that killProcess reaches the processes it starts too
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcess kills the process group of a command started with setProcessGroup
+func killProcess(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// unblockFIFO opens and closes the named pipe for writing without blocking,
+// to release a reader blocked opening it
+func unblockFIFO(path string) {
+	if f, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0); err == nil {
+		f.Close()
+	}
+}
@@ -5,6 +5,7 @@ package tailer
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"syscall"
 )
 
@@ -19,6 +20,12 @@ func getInode(stat os.FileInfo) uint64 {
 	return 0
 }
 
+// inodeOf returns the inode of a file reported by FileInfo,
+// none on Windows
+func inodeOf(stat os.FileInfo) uint64 {
+	return 0
+}
+
 // getFileID returns the actual file ID on Windows
 func getFileID(f *os.File) (uint64, error) {
 	var info syscall.ByHandleFileInformation
@@ -56,3 +63,15 @@ func openFileShared(filepath string) (*os.File, error) {
 
 	return os.NewFile(uintptr(handle), filepath), nil
 }
+
+// unblockFIFO is a no-op, named pipes are not files on Windows
+func unblockFIFO(path string) {}
+
+// setProcessGroup is a no-op on Windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcess kills the process of the command,
+// the processes it started keep running on Windows
+func killProcess(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
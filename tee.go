@@ -0,0 +1,69 @@
+package tailer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTeeMaxSize is the size at which the tee file is rotated
+const DefaultTeeMaxSize = 10 * 1024 * 1024
+
+// teeWriter mirrors the streamed lines of all clients of a terminal
+// into a local file, which is rotated to path+".1" when it exceeds maxSize.
+type teeWriter struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+	closed  bool
+}
+
+// writeLine appends a line seen by the client, prefixed with the time and the client address
+func (tw *teeWriter) writeLine(client string, line string) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.closed {
+		return nil
+	}
+
+	record := fmt.Sprintf("%s [%s] %s\n", time.Now().Format(time.RFC3339Nano), client, strings.ReplaceAll(line, "\n", "\n\t"))
+	if tw.file != nil && tw.size > 0 && tw.size+int64(len(record)) > tw.maxSize {
+		tw.file.Close()
+		tw.file = nil
+		if err := os.Rename(tw.path, tw.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate tee file: %w", err)
+		}
+	}
+	if tw.file == nil {
+		file, err := os.OpenFile(tw.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open tee file: %w", err)
+		}
+		stat, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to stat tee file: %w", err)
+		}
+		tw.file = file
+		tw.size = stat.Size()
+	}
+	n, err := tw.file.WriteString(record)
+	tw.size += int64(n)
+	return err
+}
+
+func (tw *teeWriter) close() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.closed = true
+	if tw.file == nil {
+		return nil
+	}
+	err := tw.file.Close()
+	tw.file = nil
+	return err
+}
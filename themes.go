@@ -1,5 +1,7 @@
 package tailer
 
+import "slices"
+
 // Predefined terminal themes
 
 // ThemeSolarizedDark provides the Solarized Dark color scheme
@@ -176,3 +178,24 @@ var ThemeNordic = TerminalTheme{
 	BrightCyan:          "#8fbcbb",
 	BrightWhite:         "#eceff4",
 }
+
+// Themes are the predefined terminal themes by name
+var Themes = map[string]TerminalTheme{
+	"default":         ThemeDefault,
+	"solarized-dark":  ThemeSolarizedDark,
+	"solarized-light": ThemeSolarizedLight,
+	"molokai":         ThemeMolokai,
+	"ubuntu":          ThemeUbuntu,
+	"dracula":         ThemeDracula,
+	"nordic":          ThemeNordic,
+}
+
+// ThemeNames returns the names of Themes, sorted
+func ThemeNames() []string {
+	names := make([]string, 0, len(Themes))
+	for name := range Themes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,71 @@
+package tailer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// ServerOption is a functional option for ListenAndServe and ListenAndServeTLS
+type ServerOption func(*serverConfig)
+
+type serverConfig struct {
+	clientCAFiles []string
+	iface         string // see WithInterface
+}
+
+// WithClientCA requires clients to present a certificate signed by
+// one of the CAs in the given PEM files (mutual TLS).
+func WithClientCA(caFiles ...string) ServerOption {
+	return func(c *serverConfig) {
+		c.clientCAFiles = append(c.clientCAFiles, caFiles...)
+	}
+}
+
+// ListenAndServeTLS serves the terminal at the root path over HTTPS
+// with the certificate and key in the given PEM files.
+// HTTP/2 is negotiated with the clients that support it.
+// The address is bound as by Listen.
+// Like http.ListenAndServeTLS it always returns a non-nil error.
+func ListenAndServeTLS(addr, certFile, keyFile string, t *Terminal, opts ...ServerOption) error {
+	server, err := newTLSServer(addr, t, opts...)
+	if err != nil {
+		return err
+	}
+	ln, err := Listen(addr, opts...)
+	if err != nil {
+		return err
+	}
+	return server.ServeTLS(ln, certFile, keyFile)
+}
+
+func newTLSServer(addr string, t *Terminal, opts ...ServerOption) (*http.Server, error) {
+	cfg := &serverConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(cfg.clientCAFiles) > 0 {
+		pool := x509.NewCertPool()
+		for _, file := range cfg.clientCAFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read client CA: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in client CA %s", file)
+			}
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return &http.Server{
+		Addr:      addr,
+		Handler:   t.Handler("/"),
+		TLSConfig: tlsConfig,
+	}, nil
+}
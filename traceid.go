@@ -0,0 +1,42 @@
+package tailer
+
+import "regexp"
+
+// WithTraceIDPattern detects the trace or request ID of a line with the regular
+// expression, its first group or else its match, e.g. `trace_id=([0-9a-f]+)`.
+// The NDJSON stream reports the ID of each line as traceId, and the web terminal
+// underlines the IDs: clicking one filters the lines carrying it, to follow
+// a single request across the interleaved lines. The pattern is matched
+// in the web terminal too, so it must also be valid in JavaScript.
+// An invalid pattern is ignored.
+func WithTraceIDPattern(pattern string) TerminalOption {
+	return func(to *Terminal) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return
+		}
+		to.TraceIDPattern = pattern
+		to.traceID = re
+	}
+}
+
+// WithTraceIDField detects the trace ID of JSON lines as the string value of the key,
+// e.g. "trace_id", see WithTraceIDPattern
+func WithTraceIDField(key string) TerminalOption {
+	return WithTraceIDPattern(`"` + regexp.QuoteMeta(key) + `"\s*:\s*"([^"\\]+)"`)
+}
+
+// traceIDOf returns the trace ID of the line, empty if none, see WithTraceIDPattern
+func (t Terminal) traceIDOf(line string) string {
+	if t.traceID == nil {
+		return ""
+	}
+	m := t.traceID.FindStringSubmatch(StripAnsiCodes(line))
+	switch {
+	case m == nil:
+		return ""
+	case len(m) > 1:
+		return m[1]
+	}
+	return m[0]
+}
@@ -0,0 +1,160 @@
+package tailer
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Transport streams the lines of a terminal over a protocol, like the event
+// stream of the web terminal (watch.stream), NDJSON (stream.ndjson) or plain
+// text (stream.txt).
+// The handler serves a request with the first transport matching it,
+// those of WithTransport before the built-in ones.
+type Transport interface {
+	// Match reports whether the transport serves the request,
+	// e.g. by the end of its path or by its Accept header.
+	Match(r *http.Request) bool
+	// ServeTransport serves the request, usually with the lines of h.OpenStream.
+	ServeTransport(h Handler, w http.ResponseWriter, r *http.Request)
+}
+
+// WithTransport adds a transport serving the requests it matches, in precedence
+// over the built-in transports and the other handlers, e.g. a protocol of your own.
+// The transports are matched in the order they are added.
+func WithTransport(transport Transport) TerminalOption {
+	return func(t *Terminal) {
+		t.transports = append(t.transports, transport)
+	}
+}
+
+// NewPathTransport returns a transport serving the requests whose path ends with suffix
+func NewPathTransport(suffix string, serve func(h Handler, w http.ResponseWriter, r *http.Request)) Transport {
+	return pathTransport{suffix: suffix, serve: serve}
+}
+
+type pathTransport struct {
+	suffix string
+	serve  func(h Handler, w http.ResponseWriter, r *http.Request)
+}
+
+func (pt pathTransport) Match(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, pt.suffix)
+}
+
+func (pt pathTransport) ServeTransport(h Handler, w http.ResponseWriter, r *http.Request) {
+	pt.serve(h, w, r)
+}
+
+// builtinTransports are the streams served by the handler
+var builtinTransports = []Transport{
+	NewPathTransport("watch.stream", Handler.serveWatcher),
+	NewPathTransport("stream.ndjson", Handler.serveNDJSON),
+	NewPathTransport("stream.txt", Handler.serveText),
+}
+
+// transport returns the transport serving the request, nil if none
+func (h Handler) transport(r *http.Request) Transport {
+	for _, transports := range [][]Transport{h.Terminal.transports, builtinTransports} {
+		for _, transport := range transports {
+			if transport.Match(r) {
+				return transport
+			}
+		}
+	}
+	return nil
+}
+
+// Stream is the tail of the files selected by a request, opened by a Transport
+// with Handler.OpenStream
+type Stream struct {
+	w       http.ResponseWriter
+	r       *http.Request
+	rc      *http.ResponseController
+	tail    ITail
+	offsets bool
+	ctx     context.Context
+	closeCh chan struct{}
+	stop    func()
+	release func()
+	header  func(w http.ResponseWriter) // sets the headers of WithResponseHeaders
+}
+
+// OpenStream starts the tail of the files selected by the request, with the
+// query parameters of the built-in streams: file, filter, mode, since, coloring
+// and lastEventId. It takes a slot of WithMaxClients and the stream must be closed.
+// On failure it responds the error and returns nil.
+func (h Handler) OpenStream(w http.ResponseWriter, r *http.Request) *Stream {
+	release, ok := h.acquireClient(w)
+	if !ok {
+		return nil
+	}
+	tail, offsets, stop := h.startTail(w, r, true)
+	if tail == nil {
+		release()
+		return nil
+	}
+	return &Stream{
+		w:       w,
+		r:       r,
+		rc:      http.NewResponseController(w),
+		tail:    tail,
+		offsets: offsets,
+		ctx:     r.Context(),
+		closeCh: h.closeCh,
+		stop:    stop,
+		release: release,
+		header:  h.setStreamHeaders,
+	}
+}
+
+// Start sends the headers of the streaming response with the content type,
+// and the headers of WithResponseHeaders. If the response can not be flushed
+// it responds the error and returns false.
+func (s *Stream) Start(contentType string) bool {
+	s.w.Header().Set("Content-Type", contentType)
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.header(s.w)
+	return startStream(s.w, s.r, s.rc)
+}
+
+// Next waits for the next line. The offset of the line is -1 unless a single
+// file is selected, and for the lines without a known offset, like those of
+// the history archives. It returns false once the request is done, or once the
+// terminal is closed and the lines already read are returned.
+func (s *Stream) Next() (Line, bool) {
+	select {
+	case text := <-s.tail.Lines():
+		return s.line(text), true
+	case <-s.ctx.Done():
+		return Line{}, false
+	case <-s.closeCh:
+		select {
+		case text := <-s.tail.Lines():
+			return s.line(text), true
+		default:
+			return Line{}, false
+		}
+	}
+}
+
+func (s *Stream) line(text string) Line {
+	line := Line{Offset: -1, Text: text}
+	if s.offsets {
+		if offset, rest, ok := cutOffset(text); ok {
+			line.Offset, line.Text = offset, rest
+		}
+	}
+	return line
+}
+
+// Flush sends the lines written to the client
+func (s *Stream) Flush() error {
+	return s.rc.Flush()
+}
+
+// Close stops the tail and releases the slot of WithMaxClients
+func (s *Stream) Close() {
+	s.stop()
+	s.release()
+}
@@ -1,12 +1,21 @@
 package tailer
 
 import (
+	"compress/gzip"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"io/fs"
+	"log"
+	"math"
+	"mime"
 	"net/http"
 	"path/filepath"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -16,61 +25,209 @@ type Handler struct {
 	CutPrefix string
 	Terminal  Terminal
 
-	fsServer http.Handler
-	closeCh  chan struct{}
+	fsServer  http.Handler
+	tmplIndex *template.Template // WithTemplate or index.html of the static overlay, nil for the embedded one
+	closeCh   chan struct{}
 }
 
 var _ http.Handler = Handler{}
 
 func (to Terminal) Handler(cutPrefix string) Handler {
+	var static fs.FS = staticFS
+	var tmpl *template.Template
+	if to.staticOverlay != nil {
+		static = overlayFS{upper: to.staticOverlay, lower: staticFS}
+		// fall back to the embedded index.html if the overlay one is missing or broken
+		if b, err := fs.ReadFile(to.staticOverlay, "index.html"); err == nil {
+			if t, err := template.New("index").Parse(string(b)); err == nil {
+				tmpl = t
+			}
+		}
+	}
+	if to.template != nil {
+		tmpl = to.template
+	}
 	return Handler{
 		CutPrefix: cutPrefix,
 		Terminal:  to,
-		fsServer:  http.FileServerFS(staticFS),
+		fsServer:  http.FileServerFS(static),
+		tmplIndex: tmpl,
 		closeCh:   to.closeCh,
 	}
 }
 
+// overlayFS serves the files of upper in precedence over lower.
+// upper holds the contents of the static directory at its root.
+type overlayFS struct {
+	upper fs.FS
+	lower fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if rel, ok := strings.CutPrefix(name, "static/"); ok {
+		if f, err := o.upper.Open(rel); err == nil {
+			return f, nil
+		}
+	}
+	return o.lower.Open(name)
+}
+
 func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if strings.HasSuffix(r.URL.Path, "watch.stream") {
-		h.serveWatcher(w, r)
+	if transport := h.transport(r); transport != nil {
+		transport.ServeTransport(h, w, r)
+	} else if strings.HasSuffix(r.URL.Path, "watch.range") {
+		h.serveRange(w, r)
+	} else if strings.HasSuffix(r.URL.Path, "watch.history") {
+		h.serveHistory(w, r)
+	} else if strings.HasSuffix(r.URL.Path, "watch.snapshot") {
+		h.serveSnapshot(w, r)
 	} else {
 		h.serveStatic(w, r)
 	}
 }
 
-func (h Handler) serveWatcher(w http.ResponseWriter, r *http.Request) {
-	// TODO: use array instead of map to keep order
-	selectedTails := map[string][]Option{}
+// defaultTailOptions are the options of the tails started by the handler and Subscribe,
+// first so that the options of each tail (poll interval, coloring...) take precedence
+var defaultTailOptions = []Option{
+	WithPollInterval(500 * time.Millisecond),
+	WithBufferSize(1000),
+}
+
+// startTail starts the tail of the files selected by the request, filtered by its filter.
+// If offsets is true and a single file is selected the lines are prefixed
+// with their byte offsets, the returned bool reports it.
+// The returned function stops the tail.
+// On failure it responds the error and returns a nil tail.
+func (h Handler) startTail(w http.ResponseWriter, r *http.Request, offsets bool) (ITail, bool, func()) {
+	var selectedTails []TailOption
 	if len(h.Terminal.tails) == 1 {
-		to := h.Terminal.tails[0]
-		selectedTails[to.Filename] = to.Options
+		selectedTails = h.Terminal.tails
 	} else if h.Terminal.controlBar.Hide {
 		// select all tails if control bar is not visible
-		for _, to := range h.Terminal.tails {
-			selectedTails[to.Filename] = to.Options
-		}
+		selectedTails = h.Terminal.tails
 	} else {
 		fileParams := r.URL.Query()["file"]
 		for _, to := range h.Terminal.tails {
 			if slices.Contains(fileParams, to.Alias) {
-				selectedTails[to.Filename] = to.Options
+				selectedTails = append(selectedTails, to)
 			}
 		}
 	}
 
 	if len(selectedTails) == 0 {
 		http.Error(w, "no logs selected", http.StatusBadRequest)
-		return
+		return nil, false, nil
+	}
+
+	filterOpts := filterOptions(r)
+
+	// mode=live sends only the lines written after the request, no last lines or history
+	live := false
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "":
+	case "live":
+		live = true
+	default:
+		http.Error(w, fmt.Sprintf("unknown mode %q", mode), http.StatusBadRequest)
+		return nil, false, nil
+	}
+
+	// since=5m replays the lines of the last 5 minutes instead of the last lines
+	var since time.Duration
+	if param := r.URL.Query().Get("since"); param != "" {
+		d, err := time.ParseDuration(param)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("invalid since %q", param), http.StatusBadRequest)
+			return nil, false, nil
+		}
+		since = d
 	}
 
-	opts := []Option{
-		WithPollInterval(500 * time.Millisecond),
-		WithBufferSize(1000),
+	coloring := requestColoring(r)
+
+	// the offsets of the lines are available for a single file
+	offsets = offsets && len(selectedTails) == 1
+
+	// a reconnecting client resumes after the offset of the last line it received,
+	// sent by EventSource as Last-Event-ID or as the lastEventId parameter
+	resumeAfter := int64(-1)
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	if lastEventID != "" {
+		n, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, fmt.Sprintf("invalid last event id %q", lastEventID), http.StatusBadRequest)
+			return nil, false, nil
+		}
+		resumeAfter = n
 	}
 
-	filterParam := r.URL.Query().Get("filter")
-	filters := strings.Split(filterParam, "||")
+	var releases []func()
+	newTail := func(to TailOption) ITail {
+		opts := slices.Concat(defaultTailOptions, to.Options, filterOpts)
+		if offsets {
+			opts = append(opts, WithByteOffset())
+		}
+		if live {
+			opts = append(opts, WithLiveOnly())
+		}
+		if coloring != "" {
+			opts = append(opts, WithColoringProfile(coloring))
+		}
+		if since > 0 {
+			opts = append(opts, WithReplayDuration(since))
+		}
+		if h.Terminal.clock != nil {
+			opts = append(opts, withClock(h.Terminal.clock))
+		}
+		if offsets && resumeAfter >= 0 {
+			opts = append(opts, WithResumeAfter(resumeAfter))
+		}
+		tail := New(to.Filename, opts...)
+		if h.Terminal.rules != nil {
+			// rules updated by UpdateTail replace the configured ones
+			releases = append(releases, h.Terminal.rules.register(tail.(*Tail), to.Alias, filterOpts))
+		}
+		return tail
+	}
+	release := func() {
+		for _, fn := range releases {
+			fn()
+		}
+	}
+
+	var tail ITail
+	if len(selectedTails) == 1 {
+		tail = newTail(selectedTails[0])
+	} else {
+		var tails []ITail
+		for _, to := range selectedTails {
+			tails = append(tails, newTail(to))
+		}
+		tail = NewMultiTail(tails...)
+	}
+	if err := tail.Start(); err != nil {
+		release()
+		if errors.Is(err, ErrBinaryFile) {
+			rejectBinary(w, r)
+			return nil, false, nil
+		}
+		http.Error(w, "Failed to start watcher", http.StatusInternalServerError)
+		return nil, false, nil
+	}
+	return tail, offsets, func() {
+		release()
+		tail.Stop()
+	}
+}
+
+// filterOptions returns the patterns of the filter query parameter:
+// the groups separated by "||" are OR'ed, the patterns of a group separated by "&&" AND'ed
+func filterOptions(r *http.Request) []Option {
+	var filterOpts []Option
+	filters := strings.Split(r.URL.Query().Get("filter"), "||")
 	for _, filter := range filters {
 		splits := strings.Split(filter, "&&")
 		toks := make([]string, 0, len(splits))
@@ -81,50 +238,661 @@ func (h Handler) serveWatcher(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		if len(toks) > 0 {
-			opts = append(opts, WithPattern(toks...))
+			filterOpts = append(filterOpts, WithPattern(toks...))
 		}
 	}
+	return filterOpts
+}
 
-	var tail ITail
-	if len(selectedTails) == 1 {
-		for filename, opts := range selectedTails {
-			tail = New(filename, opts...)
+// requestColoring returns the profile of the coloring query parameter:
+// coloring=<profile> or coloring=none replaces the coloring of the tails for this
+// client only, an unknown profile keeps the configured one and is returned empty
+func requestColoring(r *http.Request) string {
+	coloring := strings.ToLower(r.URL.Query().Get("coloring"))
+	if coloring != NoColoring && Profiles[coloring] == nil {
+		return ""
+	}
+	return coloring
+}
+
+// rejectBinary responds that a selected file is not a text file, see WithBinaryMode.
+// An event stream gets an "event: error" the web terminal shows without retrying,
+// the other requests 415 Unsupported Media Type.
+func rejectBinary(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, ErrBinaryFile.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	writeEvent(w, "error", map[string]any{"message": ErrBinaryFile.Error()})
+}
+
+// cutOffset splits the byte offset prefix added by WithByteOffset from the line,
+// the offset is -1 for the lines without a known offset
+func cutOffset(line string) (offset int64, rest string, ok bool) {
+	prefix, rest, ok := strings.Cut(line, ":")
+	if !ok {
+		return -1, line, false
+	}
+	offset, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || offset < -1 {
+		return -1, line, false
+	}
+	return offset, rest, true
+}
+
+// maxClientsRetryAfter is the Retry-After, in seconds, of a stream rejected by WithMaxClients
+const maxClientsRetryAfter = 5
+
+// acquireClient takes a slot of WithMaxClients for a stream, the returned function
+// releases it. If all the slots are taken it responds 503 and returns false.
+func (h Handler) acquireClient(w http.ResponseWriter) (func(), bool) {
+	if h.Terminal.clients == nil {
+		return func() {}, true
+	}
+	select {
+	case h.Terminal.clients <- struct{}{}:
+		return func() { <-h.Terminal.clients }, true
+	default:
+		w.Header().Set("Retry-After", strconv.Itoa(maxClientsRetryAfter))
+		http.Error(w, "server at capacity, retry later", http.StatusServiceUnavailable)
+		return nil, false
+	}
+}
+
+func (h Handler) serveWatcher(w http.ResponseWriter, r *http.Request) {
+	release, ok := h.acquireClient(w)
+	if !ok {
+		return
+	}
+	defer release()
+
+	// follow=false sends the last lines and completes, like tail without -f
+	if param := r.URL.Query().Get("follow"); param != "" {
+		follow, err := strconv.ParseBool(param)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid follow %q", param), http.StatusBadRequest)
+			return
 		}
-	} else {
-		var tails []ITail
-		for filename, tailOpts := range selectedTails {
-			t := New(filename, append(tailOpts, opts...)...)
-			tails = append(tails, t)
+		if !follow {
+			h.serveLastLines(w, r)
+			return
 		}
-		tail = NewMultiTail(tails...)
 	}
-	if err := tail.Start(); err != nil {
-		http.Error(w, "Failed to start watcher", http.StatusInternalServerError)
+
+	// permalinks and the last visit marker need the offsets of the lines
+	tail, permalinks, stop := h.startTail(w, r, h.Terminal.permalinks || h.Terminal.LastVisit)
+	if tail == nil {
 		return
 	}
-	defer tail.Stop()
+	defer stop()
+	// the activity, stall and adaptive flush need the statistics of the tail
+	stats, _ := tail.(StatsProvider)
 
 	rc := http.NewResponseController(w)
+	if !h.startEventStream(w, r, rc) {
+		return
+	}
+	// the files open, the ready event is sent again when they change
+	opens := fileOpens(tail)
+	if h.Terminal.readyEvent {
+		writeEvent(w, "ready", map[string]any{"files": fileInfos(tail, h.Terminal.readyLines)})
+		if rc.Flush() != nil {
+			return
+		}
+	}
+
+	flushInterval := 1 * time.Second
+	if h.Terminal.adaptiveFlush {
+		flushInterval = adaptiveFlushInterval
+	}
+	var summary *levelSummary
+	var lastSummary time.Time
+	if h.Terminal.summaryWindow > 0 {
+		summary = newLevelSummary(h.Terminal.summaryWindow, h.levelDetector())
+	}
+	clk := h.clock()
+	connected := clk.Now()
+	stalled := false
+	// the activity is sent on the first tick and on every change
+	active, activitySent := false, false
+	// unflushed reports whether anything was written since the last flush
+	unflushed := false
+	// writeLine writes a line of the tail as an event
+	writeLine := func(line string) {
+		if summary != nil {
+			summary.add(clk.Now(), line)
+		}
+		if stalled {
+			stalled = false
+			writeEvent(w, "resume", map[string]any{"at": clk.Now()})
+		}
+		id := ""
+		if permalinks {
+			// the byte offset becomes the event id
+			if offset, rest, ok := cutOffset(line); ok {
+				line = rest
+				if offset >= 0 {
+					id = strconv.FormatInt(offset, 10)
+				}
+			}
+		}
+		h.writeLineEvent(w, r, id, line)
+	}
+
+	flushTicker := clk.NewTicker(flushInterval)
+	defer flushTicker.Stop()
+	for {
+		select {
+		case <-flushTicker.C():
+			if stats != nil {
+				if isActive := stats.IsActive(); !activitySent || isActive != active {
+					active, activitySent = isActive, true
+					writeEvent(w, "activity", map[string]any{"active": active})
+					unflushed = true
+				}
+			}
+			if n := fileOpens(tail); h.Terminal.readyEvent && n != opens {
+				// a file was rotated or switched
+				opens = n
+				writeEvent(w, "ready", map[string]any{"files": fileInfos(tail, h.Terminal.readyLines)})
+				unflushed = true
+			}
+			if h.Terminal.stallTimeout > 0 && !stalled && stats != nil {
+				last := stats.Stats().LastLine
+				if last.IsZero() {
+					last = connected
+				}
+				if clk.Now().Sub(last) >= h.Terminal.stallTimeout {
+					stalled = true
+					writeEvent(w, "stall", map[string]any{"since": last})
+					unflushed = true
+				}
+			}
+			if summary != nil && clk.Now().Sub(lastSummary) >= time.Second {
+				lastSummary = clk.Now()
+				writeEvent(w, "summary", map[string]any{
+					"window": summary.window.Seconds(),
+					"counts": summary.counts(lastSummary),
+				})
+				unflushed = true
+			}
+			if h.Terminal.flushOnlyOnData && !unflushed {
+				continue
+			}
+			if rc.Flush() != nil {
+				return
+			}
+			unflushed = false
+		case line := <-tail.Lines():
+			writeLine(line)
+			unflushed = true
+			// without statistics every line is flushed
+			if h.Terminal.adaptiveFlush && (stats == nil || stats.Stats().LinesPerSec < adaptiveFlushRate) {
+				if rc.Flush() != nil {
+					return
+				}
+				unflushed = false
+			}
+		case <-r.Context().Done():
+			return
+		case <-h.closeCh:
+			// the lines already read are sent before the stream ends
+			drain(tail, writeLine)
+			writeEvent(w, "shutdown", map[string]any{"message": *h.Terminal.closeMessage})
+			rc.Flush()
+			return
+		}
+	}
+}
+
+// startEventStream sends the headers of an event stream, see startStream
+func (h Handler) startEventStream(w http.ResponseWriter, r *http.Request, rc *http.ResponseController) bool {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
+	if r.ProtoMajor == 1 {
+		// connection-specific headers are not allowed in HTTP/2
+		w.Header().Set("Connection", "keep-alive")
+	}
 	w.Header().Set("Access-Control-Allow-Origin", "*")
+	h.setStreamHeaders(w)
+	return startStream(w, r, rc)
+}
+
+// writeLineEvent writes a line as an event, with the id if not empty
+func (h Handler) writeLineEvent(w http.ResponseWriter, r *http.Request, id string, line string) {
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	if h.Terminal.tee != nil {
+		h.Terminal.tee.writeLine(r.RemoteAddr, line)
+	}
+	if h.Terminal.maxDisplayWidth > 0 {
+		line = truncateRecord(line, h.Terminal.maxDisplayWidth)
+	}
+	if h.Terminal.eventClassifier != nil {
+		if event := eventType(h.Terminal.eventClassifier(line)); event != "" {
+			fmt.Fprintf(w, "event: %s\n", event)
+		}
+	}
+	// multiline records are sent as multiple data fields of one event
+	fmt.Fprintf(w, "data: %s\n\n", strings.ReplaceAll(line, "\n", "\ndata: "))
+}
+
+// serveLastLines sends the last lines of a file as an event stream that
+// completes with an "event: complete" of the number of lines, for follow=false.
+// Query parameters: file (alias, optional when there is a single tail),
+// lines (default 100), filter and coloring as for the live stream.
+// The lines are read backward in blocks, see Tail.History.
+func (h Handler) serveLastLines(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	selected := h.selectedTail(query.Get("file"))
+	if selected == nil {
+		http.Error(w, "no logs selected", http.StatusBadRequest)
+		return
+	}
+	maxLines := 100
+	if v := query.Get("lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid lines", http.StatusBadRequest)
+			return
+		}
+		maxLines = min(n, maxHistoryLines)
+	}
+
+	opts := slices.Concat(defaultTailOptions, selected.Options, filterOptions(r))
+	if coloring := requestColoring(r); coloring != "" {
+		opts = append(opts, WithColoringProfile(coloring))
+	}
+	tail := New(selected.Filename, opts...).(*Tail)
+	lines, _, err := tail.History(r.Context(), math.MaxInt64, maxLines)
+	if err != nil {
+		http.Error(w, "Failed to read the last lines", http.StatusInternalServerError)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	if !h.startEventStream(w, r, rc) {
+		return
+	}
+	for _, line := range lines {
+		id := ""
+		if h.Terminal.permalinks || h.Terminal.LastVisit {
+			id = strconv.FormatInt(line.Offset, 10)
+		}
+		h.writeLineEvent(w, r, id, line.Text)
+	}
+	writeEvent(w, "complete", map[string]any{"lines": len(lines)})
 	rc.Flush()
+}
 
-	flushTicker := time.NewTicker(1 * time.Second)
-	defer flushTicker.Stop()
+// drain passes the lines buffered by the tail to fn, without waiting for more
+func drain(tail ITail, fn func(line string)) {
 	for {
 		select {
-		case <-flushTicker.C:
-			rc.Flush()
 		case line := <-tail.Lines():
-			fmt.Fprintf(w, "data: %s\n\n", line)
-		case <-r.Context().Done():
+			fn(line)
+		default:
 			return
-		case <-h.closeCh:
+		}
+	}
+}
+
+// reservedEvents are the SSE event types of the stream itself
+var reservedEvents = []string{"message", "stall", "resume", "summary", "activity", "ready", "shutdown", "complete"}
+
+// eventType returns the SSE event type of a line classified as event,
+// empty for the default message event; the types of the stream itself
+// and names that would break the event framing are sent as messages.
+func eventType(event string) string {
+	if slices.Contains(reservedEvents, event) || strings.ContainsAny(event, "\r\n") {
+		return ""
+	}
+	return event
+}
+
+// LevelEventType is a classifier for WithEventTypeClassifier sending the lines
+// as events of their lower-cased level, see DetectLevel, e.g. "error";
+// lines without a level stay messages.
+func LevelEventType(line string) string {
+	return strings.ToLower(DetectLevel(line))
+}
+
+// JSONLevelEventType returns a classifier for WithEventTypeClassifier like
+// LevelEventType, reading the level of JSON lines from the key, see DetectJSONLevel.
+func JSONLevelEventType(key string) func(line string) string {
+	return func(line string) string {
+		return strings.ToLower(DetectJSONLevel(line, key))
+	}
+}
+
+// levelDetector returns the level detection of the lines of the tails,
+// from the JSON fields of WithJSONLevelField if any, DetectLevel otherwise
+func (h Handler) levelDetector() func(line string) string {
+	var keys []string
+	for _, to := range h.Terminal.tails {
+		probe := &Tail{}
+		for _, opt := range to.Options {
+			opt(probe)
+		}
+		if probe.levelField != "" && !slices.Contains(keys, probe.levelField) {
+			keys = append(keys, probe.levelField)
+		}
+	}
+	if len(keys) == 0 {
+		return DetectLevel
+	}
+	return func(line string) string {
+		stripped := StripAnsiCodes(line)
+		for _, key := range keys {
+			if level, ok := jsonLevel(stripped, key); ok {
+				return level
+			}
+		}
+		return DetectLevel(line)
+	}
+}
+
+// setStreamHeaders sets the headers of the streams for proxies, then those of WithResponseHeaders
+func (h Handler) setStreamHeaders(w http.ResponseWriter) {
+	// nginx buffers the responses of proxy_pass unless told otherwise
+	w.Header().Set("X-Accel-Buffering", "no")
+	for key, value := range h.Terminal.responseHeaders {
+		if value == "" {
+			w.Header().Del(key)
+		} else {
+			w.Header().Set(key, value)
+		}
+	}
+}
+
+// startStream sends the headers of a streaming response. If the response can not be
+// flushed, e.g. behind a middleware wrapping the ResponseWriter without Unwrap,
+// the lines would be buffered and never appear, so it logs the error,
+// responds with it and returns false.
+func startStream(w http.ResponseWriter, r *http.Request, rc *http.ResponseController) bool {
+	// a server WriteTimeout would end the stream
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("tailer: failed to clear the write deadline of %s: %v", r.URL.Path, err)
+	}
+	if err := rc.Flush(); err != nil {
+		log.Printf("tailer: failed to stream %s to %s: %v", r.URL.Path, r.RemoteAddr, err)
+		if errors.Is(err, http.ErrNotSupported) {
+			http.Error(w, "Streaming is not supported, the response can not be flushed", http.StatusInternalServerError)
+		}
+		return false
+	}
+	return true
+}
+
+// serveText streams the lines as plain text, one per line with their color codes
+// and without SSE framing, so that curl renders them like tail -f in a terminal.
+// It takes the same query parameters as the event stream.
+func (h Handler) serveText(w http.ResponseWriter, r *http.Request) {
+	stream := h.OpenStream(w, r)
+	if stream == nil {
+		return
+	}
+	defer stream.Close()
+	// browsers would wait for enough of the stream to sniff its type
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if !stream.Start("text/plain; charset=utf-8") {
+		return
+	}
+
+	for {
+		// the lines already read are sent before the stream ends
+		line, ok := stream.Next()
+		if !ok {
+			return
+		}
+		if h.Terminal.tee != nil {
+			h.Terminal.tee.writeLine(r.RemoteAddr, line.Text)
+		}
+		if _, err := fmt.Fprintln(w, line.Text); err != nil || stream.Flush() != nil {
+			return
+		}
+	}
+}
+
+// LineFrame is the JSON object written for each line by the stream.ndjson endpoint
+type LineFrame struct {
+	Time    time.Time `json:"time"`
+	Line    string    `json:"line"`
+	Level   string    `json:"level,omitempty"`
+	TraceID string    `json:"traceId,omitempty"` // see WithTraceIDPattern
+	Offset  *int64    `json:"offset,omitempty"`
+}
+
+// serveNDJSON streams the lines as newline-delimited JSON objects,
+// one LineFrame per line flushed as it arrives, for tools like jq.
+// It takes the same file and filter query parameters as the event stream.
+func (h Handler) serveNDJSON(w http.ResponseWriter, r *http.Request) {
+	stream := h.OpenStream(w, r)
+	if stream == nil {
+		return
+	}
+	defer stream.Close()
+	if !stream.Start("application/x-ndjson") {
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	detectLevel := h.levelDetector()
+	for {
+		// the lines already read are sent before the stream ends
+		line, ok := stream.Next()
+		if !ok {
+			return
+		}
+		if h.Terminal.tee != nil {
+			h.Terminal.tee.writeLine(r.RemoteAddr, line.Text)
+		}
+		frame := LineFrame{Time: h.clock().Now(), Line: line.Text, Level: detectLevel(line.Text), TraceID: h.Terminal.traceIDOf(line.Text)}
+		if line.Offset >= 0 {
+			frame.Offset = &line.Offset
+		}
+		if enc.Encode(frame) != nil || stream.Flush() != nil {
+			return
+		}
+	}
+}
+
+// selectedTail returns the tail with the alias, or the only one, nil if none
+func (h Handler) selectedTail(alias string) *TailOption {
+	if len(h.Terminal.tails) == 1 {
+		return &h.Terminal.tails[0]
+	}
+	for i := range h.Terminal.tails {
+		if h.Terminal.tails[i].Alias == alias {
+			return &h.Terminal.tails[i]
+		}
+	}
+	return nil
+}
+
+// maxHistoryLines limits the lines of a history page requested from the web handler
+const maxHistoryLines = 10000
+
+// serveHistory responds a page of lines before an offset in JSON, see Tail.History.
+// Query parameters: file (alias, optional when there is a single tail),
+// before (byte offset, the end of the file if omitted) and lines (default 100).
+func (h Handler) serveHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	selected := h.selectedTail(query.Get("file"))
+	if selected == nil {
+		http.Error(w, "no logs selected", http.StatusBadRequest)
+		return
+	}
+
+	before := int64(math.MaxInt64)
+	if v := query.Get("before"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid before", http.StatusBadRequest)
 			return
 		}
+		before = n
 	}
+	maxLines := 100
+	if v := query.Get("lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid lines", http.StatusBadRequest)
+			return
+		}
+		maxLines = min(n, maxHistoryLines)
+	}
+
+	tail := New(selected.Filename, selected.Options...).(*Tail)
+	lines, next, err := tail.History(r.Context(), before, maxLines)
+	if err != nil {
+		http.Error(w, "Failed to read history", http.StatusInternalServerError)
+		return
+	}
+	if lines == nil {
+		lines = []Line{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	json.NewEncoder(w).Encode(map[string]any{"lines": lines, "next": next})
+}
+
+// truncateRecord truncates every line of the record to cols visible characters
+func truncateRecord(record string, cols int) string {
+	lines := strings.Split(record, "\n")
+	for i, line := range lines {
+		lines[i] = TruncateAnsi(line, cols)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeEvent writes a named SSE event with JSON encoded data
+func writeEvent(w http.ResponseWriter, event string, data any) {
+	b, _ := json.Marshal(data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+}
+
+// serveRange responds a byte range of a file as complete lines in JSON.
+// Query parameters: file (alias, optional when there is a single tail),
+// start and end (byte offsets).
+func (h Handler) serveRange(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	selected := h.selectedTail(query.Get("file"))
+	if selected == nil {
+		http.Error(w, "no logs selected", http.StatusBadRequest)
+		return
+	}
+
+	start, err := strconv.ParseInt(query.Get("start"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid start", http.StatusBadRequest)
+		return
+	}
+	end, err := strconv.ParseInt(query.Get("end"), 10, 64)
+	if err != nil || end < start {
+		http.Error(w, "invalid end", http.StatusBadRequest)
+		return
+	}
+	if end-start > MaxRangeBytes {
+		end = start + MaxRangeBytes
+	}
+
+	tail := New(selected.Filename, selected.Options...).(*Tail)
+	rng, err := tail.ReadRange(r.Context(), start, end)
+	if err != nil {
+		http.Error(w, "Failed to read range", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	json.NewEncoder(w).Encode(rng)
+}
+
+// serveSnapshot responds a gzip compressed file of the last lines of a file,
+// or of a byte range, without coloring, to attach to a ticket.
+// Query parameters: file (alias, optional when there is a single tail),
+// lines (default 1000) or start and end (byte offsets, see serveRange).
+// The file is sent as application/gzip, not with a Content-Encoding,
+// so that it is saved compressed.
+func (h Handler) serveSnapshot(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	selected := h.selectedTail(query.Get("file"))
+	if selected == nil {
+		http.Error(w, "no logs selected", http.StatusBadRequest)
+		return
+	}
+	tail := New(selected.Filename, append(slices.Clone(selected.Options), WithColoringProfile(NoColoring))...).(*Tail)
+
+	var lines []string
+	var name string
+	if query.Has("start") || query.Has("end") {
+		start, err := strconv.ParseInt(query.Get("start"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid start", http.StatusBadRequest)
+			return
+		}
+		end, err := strconv.ParseInt(query.Get("end"), 10, 64)
+		if err != nil || end < start {
+			http.Error(w, "invalid end", http.StatusBadRequest)
+			return
+		}
+		end = min(end, start+MaxRangeBytes)
+		rng, err := tail.ReadRange(r.Context(), start, end)
+		if err != nil {
+			http.Error(w, "Failed to read range", http.StatusInternalServerError)
+			return
+		}
+		lines = rng.Lines
+		name = fmt.Sprintf("%s-%d-%d.log.gz", snapshotName(selected.Alias), rng.Start, rng.End)
+	} else {
+		maxLines := 1000
+		if v := query.Get("lines"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				http.Error(w, "invalid lines", http.StatusBadRequest)
+				return
+			}
+			maxLines = min(n, maxHistoryLines)
+		}
+		history, _, err := tail.History(r.Context(), math.MaxInt64, maxLines)
+		if err != nil {
+			http.Error(w, "Failed to read history", http.StatusInternalServerError)
+			return
+		}
+		for _, line := range history {
+			lines = append(lines, line.Text)
+		}
+		name = fmt.Sprintf("%s-%s.log.gz", snapshotName(selected.Alias), h.clock().Now().UTC().Format("20060102T150405Z"))
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.Header().Set("Cache-Control", "no-store")
+	gz := gzip.NewWriter(w)
+	for _, line := range lines {
+		if r.Context().Err() != nil {
+			return
+		}
+		gz.Write([]byte(line + "\n"))
+	}
+	gz.Close()
+}
+
+// snapshotName makes the alias of a tail a safe file name
+func snapshotName(alias string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, alias)
+	if name == "" {
+		return "snapshot"
+	}
+	return name
 }
 
 //go:embed static/*
@@ -133,7 +901,7 @@ var staticFS embed.FS
 var tmplIndex *template.Template
 
 func (h Handler) serveStatic(w http.ResponseWriter, r *http.Request) {
-	if tmplIndex == nil {
+	if h.tmplIndex == nil && tmplIndex == nil {
 		if b, err := staticFS.ReadFile("static/index.html"); err != nil {
 			http.Error(w, "Failed to read index.html", http.StatusInternalServerError)
 			return
@@ -141,9 +909,29 @@ func (h Handler) serveStatic(w http.ResponseWriter, r *http.Request) {
 			tmplIndex = template.Must(template.New("index").Parse(string(b)))
 		}
 	}
-	r.URL.Path = "static/" + strings.TrimPrefix(r.URL.Path, h.CutPrefix)
+	reqPath := r.URL.Path
+	rel := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, h.CutPrefix), "/")
+	// the path must stay within the static assets, of the overlay too
+	if rel != "" && (!fs.ValidPath(rel) || strings.ContainsAny(rel, `\:`)) {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	r.URL.Path = "static/" + rel
 	if r.URL.Path == "static/" {
-		err := tmplIndex.Execute(w, h.dataMap())
+		tmpl := h.tmplIndex
+		if tmpl == nil {
+			tmpl = tmplIndex
+		}
+		// preload hints let the browser fetch xterm while the page is parsed,
+		// the paths are resolved the same way the page resolves them
+		base := h.Terminal.BasePath
+		if base == "" {
+			base = reqPath[:strings.LastIndex(reqPath, "/")+1]
+		}
+		for _, asset := range preloadAssets {
+			w.Header().Add("Link", fmt.Sprintf("<%s%s>; rel=preload; as=%s", base, asset.name, asset.as))
+		}
+		err := tmpl.Execute(w, h.dataMap())
 		if err != nil {
 			http.Error(w, "Failed to render index.html", http.StatusInternalServerError)
 		}
@@ -152,6 +940,15 @@ func (h Handler) serveStatic(w http.ResponseWriter, r *http.Request) {
 	h.fsServer.ServeHTTP(w, r)
 }
 
+// preloadAssets are the static files the index page needs before the terminal is interactive
+var preloadAssets = []struct{ name, as string }{
+	{"xterm.css", "style"},
+	{"xterm.js", "script"},
+	{"addon-fit.min.js", "script"},
+	{"addon-webgl.min.js", "script"},
+	{"stream.js", "script"},
+}
+
 func (h Handler) dataMap() TemplateData {
 	files := []string{}
 	for _, to := range h.Terminal.tails {
@@ -164,17 +961,50 @@ func (h Handler) dataMap() TemplateData {
 	if ctrlBar.FontFamily == "" {
 		ctrlBar.FontFamily = h.Terminal.FontFamily
 	}
+	if h.Terminal.Minimal {
+		ctrlBar.Hide = true
+	}
+	streamURL := h.Terminal.streamURL
+	if streamURL == "" {
+		streamURL = "watch.stream"
+	}
 	return TemplateData{
 		Terminal:   h.Terminal,
 		ControlBar: ctrlBar,
 		Files:      files,
+		Permalinks: h.Terminal.permalinks && len(files) == 1,
+		StreamURL:  streamURL,
 	}
 }
 
+// TemplateData is the data the index page template is executed with,
+// the contract for the custom templates of WithTemplate.
 type TemplateData struct {
-	Terminal   Terminal
+	// Terminal is the terminal configuration, it encodes to the options of
+	// the xterm.js Terminal, e.g. {{ .JSON .Terminal }}. Its exported fields
+	// carry the page settings: Theme, FontSize, FontFamily, BasePath, ...
+	Terminal Terminal
+	// ControlBar is the control bar configuration,
+	// its font falls back to the one of the terminal.
 	ControlBar ControlBar
-	Files      []string
+	// Files are the aliases of the tails, in the order they were added,
+	// selectable with the files query parameter of the stream.
+	Files []string
+	// Permalinks reports whether the lines carry their byte offsets.
+	Permalinks bool
+	// StreamURL is the SSE stream of the lines relative to the page,
+	// resolve it against document.baseURI, or the URL of WithStreamURL.
+	StreamURL string
+}
+
+// JSON returns v encoded as a JSON value,
+// used to embed strings safely into the page script.
+func (td TemplateData) JSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "null"
+	}
+	return string(b)
 }
 
 func (td TemplateData) Localize(s string) string {
@@ -194,11 +1024,46 @@ type Terminal struct {
 	Scrollback          int           `json:"scrollback,omitempty"`
 	DisableStdin        bool          `json:"disableStdin"`
 	ConvertEol          bool          `json:"convertEol,omitempty"`
+	ScreenReaderMode    bool          `json:"screenReaderMode,omitempty"`
 
-	tails        []TailOption      `json:"-"`
-	controlBar   ControlBar        `json:"-"`
-	closeCh      chan struct{}     `json:"-"`
-	Localization map[string]string `json:"-"`
+	tails           []TailOption        `json:"-"`
+	controlBar      ControlBar          `json:"-"`
+	closeCh         chan struct{}       `json:"-"`
+	closeMessage    *string             `json:"-"` // the message of the shutdown event, see CloseWithMessage
+	rules           *ruleRegistry       `json:"-"`
+	stallTimeout    time.Duration       `json:"-"`
+	adaptiveFlush   bool                `json:"-"`
+	flushOnlyOnData bool                `json:"-"`
+	readyEvent      bool                `json:"-"`
+	clients         chan struct{}       `json:"-"` // a slot per streaming client, see WithMaxClients
+	responseHeaders map[string]string   `json:"-"`
+	readyLines      LineCount           `json:"-"`
+	summaryWindow   time.Duration       `json:"-"`
+	permalinks      bool                `json:"-"`
+	tee             *teeWriter          `json:"-"`
+	teeMaxSize      int64               `json:"-"`
+	maxDisplayWidth int                 `json:"-"`
+	eventClassifier func(string) string `json:"-"`
+	transports      []Transport         `json:"-"` // see WithTransport
+	staticOverlay   fs.FS               `json:"-"`
+	template        *template.Template  `json:"-"`
+	Localization    map[string]string   `json:"-"`
+	BreakOnMatch    string              `json:"-"`
+	TraceIDPattern  string              `json:"-"` // see WithTraceIDPattern
+	traceID         *regexp.Regexp      `json:"-"`
+	HTMLHeader      string              `json:"-"`
+	CustomCSS       string              `json:"-"`
+	BasePath        string              `json:"-"`
+	streamURL       string              `json:"-"` // see WithStreamURL
+	clock           clock               `json:"-"` // see withTerminalClock
+	Collapsed       bool                `json:"-"`
+	LastVisit       bool                `json:"-"` // see WithLastVisitMarker
+	Minimal         bool                `json:"-"` // see WithMinimalUI
+	FirstError      bool                `json:"-"` // see WithScrollToFirstError
+	DensityGutter   bool                `json:"-"` // see WithDensityGutter
+	InitialScroll   ScrollPosition      `json:"-"` // the position of the view after the replay, see WithInitialScroll
+	LevelLegend     []string            `json:"-"` // the levels of the legend, see WithLevelLegend
+	Accessible      string              `json:"-"` // the politeness of the live region, see WithAccessibleMode
 }
 
 type TailOption struct {
@@ -284,6 +1149,424 @@ func WithLocalization(localization map[string]string) TerminalOption {
 	}
 }
 
+// WithBreakOnMatch arms the web terminal to pause rendering
+// when a line matches the given regular expression (JavaScript syntax).
+// The matching line is highlighted and the stream stays paused
+// until the user clicks resume.
+func WithBreakOnMatch(pattern string) TerminalOption {
+	return func(to *Terminal) {
+		to.BreakOnMatch = pattern
+	}
+}
+
+// WithMaxDisplayWidth truncates the streamed lines to cols visible characters
+// with an ellipsis, keeping escape sequences and colors intact.
+// The tee file and the range endpoint keep the full lines.
+func WithMaxDisplayWidth(cols int) TerminalOption {
+	return func(to *Terminal) {
+		to.maxDisplayWidth = cols
+	}
+}
+
+// WithCollapsed starts the web terminal collapsed behind an expand button,
+// the stream is only connected while the terminal is expanded.
+// It saves resources across many viewers embedded in a dashboard.
+func WithCollapsed() TerminalOption {
+	return func(to *Terminal) {
+		to.Collapsed = true
+	}
+}
+
+// ScrollPosition is the position of the web terminal after the replay
+// of the lines already read on connect, see WithInitialScroll
+type ScrollPosition int
+
+const (
+	ScrollBottom ScrollPosition = iota // the newest line, following the new ones
+	ScrollTop                          // the first replayed line, to read them in order
+)
+
+func (sp ScrollPosition) String() string {
+	if sp == ScrollTop {
+		return "top"
+	}
+	return "bottom"
+}
+
+// WithInitialScroll sets where the web terminal is positioned once the lines
+// sent on connect, e.g. by WithLast or WithHistory, are rendered.
+// ScrollBottom, the default, shows the newest line and follows the new ones.
+// ScrollTop shows the first replayed line and stops auto-scroll,
+// which resumes when the user scrolls to the bottom.
+func WithInitialScroll(position ScrollPosition) TerminalOption {
+	return func(to *Terminal) {
+		to.InitialScroll = position
+	}
+}
+
+// WithScrollToFirstError positions the web terminal on the first ERROR line of
+// the lines sent on connect, e.g. of a CI or deploy log: once they are rendered
+// the line is highlighted and centered, and auto-scroll stops until the user
+// scrolls to the bottom. The level is detected like DetectLevel, FATAL and PANIC
+// are errors too. Without an error the position of WithInitialScroll applies.
+func WithScrollToFirstError() TerminalOption {
+	return func(to *Terminal) {
+		to.FirstError = true
+	}
+}
+
+// WithLevelLegend shows a legend of the level colors above the web terminal,
+// the levels reported by DetectLevel in that order, all of them if none is given.
+// Clicking a level hides the new lines of that level, a second click shows them again.
+// The colors are those of the terminal theme, as used by the "level" syntax.
+func WithLevelLegend(levels ...string) TerminalOption {
+	return func(to *Terminal) {
+		if len(levels) == 0 {
+			levels = []string{LevelTrace, LevelDebug, LevelInfo, LevelWarn, LevelError}
+		}
+		to.LevelLegend = nil
+		for _, level := range levels {
+			level = strings.ToUpper(level)
+			if _, ok := levelColors[level]; ok && !slices.Contains(to.LevelLegend, level) {
+				to.LevelLegend = append(to.LevelLegend, level)
+			}
+		}
+	}
+}
+
+// WithMinimalUI serves a compact page for embedding, e.g. as one of many small
+// live log tiles of a dashboard in iframes: the terminal viewport only, filling
+// the frame, without the control bar, the HTML header and the level legend.
+// The stream is the same as the one of the full page.
+func WithMinimalUI() TerminalOption {
+	return func(to *Terminal) {
+		to.Minimal = true
+	}
+}
+
+// WithDensityGutter shows a thin gutter above the web terminal with the volume
+// of the lines received in the last 5 minutes, a bar per 10 seconds colored by
+// the most frequent level of its lines, to spot a burst at a glance. It runs
+// in the browser only, and the lines replayed on connect are not counted.
+// Unlike the other controls it is kept by WithMinimalUI.
+func WithDensityGutter() TerminalOption {
+	return func(to *Terminal) {
+		to.DensityGutter = true
+	}
+}
+
+// WithAccessibleMode makes the web terminal usable with a screen reader:
+// the xterm.js screen reader mode is enabled, the new lines are announced
+// by a polite ARIA live region, at most 3 lines every 2 seconds with a count
+// of the others, and a Pause button joins the control bar.
+// See WithAnnouncePoliteness to interrupt the reader instead.
+func WithAccessibleMode() TerminalOption {
+	return func(to *Terminal) {
+		to.ScreenReaderMode = true
+		if to.Accessible == "" {
+			to.Accessible = "polite"
+		}
+	}
+}
+
+// WithAnnouncePoliteness sets the politeness of the live region of WithAccessibleMode,
+// "polite" or "assertive", and enables it. Other values are ignored.
+func WithAnnouncePoliteness(politeness string) TerminalOption {
+	return func(to *Terminal) {
+		if politeness == "polite" || politeness == "assertive" {
+			WithAccessibleMode()(to)
+			to.Accessible = politeness
+		}
+	}
+}
+
+// WithStallDetection makes the stream emit an "event: stall"
+// when no line has arrived within d, and an "event: resume"
+// when lines flow again. The web terminal shows a warning banner meanwhile.
+func WithStallDetection(d time.Duration) TerminalOption {
+	return func(to *Terminal) {
+		to.stallTimeout = d
+	}
+}
+
+// WithTee mirrors every line streamed to the clients,
+// after filtering and coloring, into the local file at path.
+// Each line is prefixed with the time and the address of the client.
+// The file is rotated to path+".1" when it exceeds DefaultTeeMaxSize,
+// see WithTeeMaxSize.
+func WithTee(path string) TerminalOption {
+	return func(to *Terminal) {
+		to.tee = &teeWriter{path: path, maxSize: DefaultTeeMaxSize}
+	}
+}
+
+// WithTeeMaxSize sets the size at which the tee file is rotated
+func WithTeeMaxSize(size int64) TerminalOption {
+	return func(to *Terminal) {
+		to.teeMaxSize = size
+	}
+}
+
+// WithPermalinks sends the byte offset of each line as the SSE event id,
+// so that clicking a line in the web terminal updates the page URL
+// to a permalink (#offset=N) that shows that part of the file when opened.
+// It applies to terminals streaming a single file.
+func WithPermalinks() TerminalOption {
+	return func(to *Terminal) {
+		to.permalinks = true
+	}
+}
+
+// WithEventTypeClassifier sends each line as an SSE event of the type classify
+// returns for it, e.g. "error", so that clients can listen to the categories
+// separately, see LevelEventType. An empty type keeps the line a message event,
+// the default for all lines. The line passed may contain ANSI color codes.
+// The embedded page renders the message events and the level types
+// trace, debug, info, warn and error.
+func WithEventTypeClassifier(classify func(line string) string) TerminalOption {
+	return func(to *Terminal) {
+		to.eventClassifier = classify
+	}
+}
+
+// WithLevelSummary makes the stream emit an "event: summary" every second
+// with the counts of the detected log levels (see DetectLevel)
+// of the lines streamed within the last window, e.g. time.Minute.
+// The web terminal displays the counts above the terminal.
+func WithLevelSummary(window time.Duration) TerminalOption {
+	return func(to *Terminal) {
+		to.summaryWindow = window
+	}
+}
+
+const (
+	// adaptiveFlushRate is the throughput (lines/sec) below which
+	// every line is flushed immediately in the adaptive flush mode
+	adaptiveFlushRate = 20
+	// adaptiveFlushInterval is the batching interval of the adaptive flush mode
+	// when the throughput is above adaptiveFlushRate
+	adaptiveFlushInterval = 200 * time.Millisecond
+)
+
+// WithAdaptiveFlush replaces the fixed 1 second flush of the stream
+// with a flush that adapts to the throughput.
+// While fewer than 20 lines/sec arrive, each line is flushed immediately
+// for low latency, above that lines are batched and flushed every 200ms.
+func WithAdaptiveFlush() TerminalOption {
+	return func(to *Terminal) {
+		to.adaptiveFlush = true
+	}
+}
+
+// WithFlushOnlyOnData skips the periodic flush of the stream when nothing was
+// written since the last one, for clients like log shippers that prefer fewer,
+// larger flushes; the lines and events written are still flushed every interval.
+func WithFlushOnlyOnData() TerminalOption {
+	return func(to *Terminal) {
+		to.flushOnlyOnData = true
+	}
+}
+
+// WithResponseHeaders sets headers of the stream responses (watch.stream and
+// stream.ndjson) for proxies that buffer them, replacing the defaults of the
+// same name, including Content-Type; an empty value removes the header.
+// The streams send "X-Accel-Buffering: no" by default, which disables the
+// buffering of nginx.
+//
+//	tailer.WithResponseHeaders(map[string]string{"Cache-Control": "no-cache, no-transform"})
+func WithResponseHeaders(headers map[string]string) TerminalOption {
+	return func(to *Terminal) {
+		if to.responseHeaders == nil {
+			to.responseHeaders = map[string]string{}
+		}
+		for key, value := range headers {
+			to.responseHeaders[key] = value
+		}
+	}
+}
+
+// WithMaxClients limits the streams (watch.stream and stream.ndjson) served at once
+// to n. A stream beyond it is rejected with 503 Service Unavailable and a
+// Retry-After header, the streams already open continue. It bounds the tails,
+// goroutines and memory of the handler on a small host.
+func WithMaxClients(n int) TerminalOption {
+	return func(to *Terminal) {
+		if n > 0 {
+			to.clients = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithReadyEvent makes the stream start with an "event: ready" describing
+// the followed files, their FileInfo with the absolute path, the size, the
+// modification time, the inode on Unix and the lines counted by count:
+//
+//	event: ready
+//	data: {"files":[{"file":"app.log","path":"/var/log/app.log","size":12902154,"mtime":"2026-10-15T12:00:00Z","inode":1835021,"lines":84210,"estimated":true}]}
+//
+// It is sent again when a file is rotated or switched, with the new file.
+// ExactLineCount reads the whole files on every connection before the first line is sent.
+func WithReadyEvent(count LineCount) TerminalOption {
+	return func(to *Terminal) {
+		to.readyEvent = true
+		to.readyLines = count
+	}
+}
+
+// WithBasePath sets the base URL of the served page,
+// so that the assets and the stream resolve correctly
+// when the handler is mounted under a prefix by a router (e.g. "/admin/logs/").
+func WithBasePath(path string) TerminalOption {
+	return func(to *Terminal) {
+		if !strings.HasSuffix(path, "/") {
+			path += "/"
+		}
+		to.BasePath = path
+	}
+}
+
+// WithLastVisitMarker marks where the user left off: the web terminal keeps the
+// offset of the last line seen in the localStorage of the browser, and on the next
+// visit the stream resumes after it with a "new since last visit" divider before
+// the new lines. It needs the offsets of the lines, available for a single file.
+func WithLastVisitMarker() TerminalOption {
+	return func(to *Terminal) {
+		to.LastVisit = true
+	}
+}
+
+// WithStreamURL sets the URL of the stream the page connects to, relative
+// to the page or absolute, e.g. "/internal/logs/watch.stream" for a proxy
+// that rewrites the paths of the stream apart from those of the page.
+// By default the page resolves "watch.stream" against its own URL.
+func WithStreamURL(url string) TerminalOption {
+	return func(to *Terminal) {
+		to.streamURL = url
+	}
+}
+
+// WithStaticOverlay serves the static assets of fsys in precedence
+// over the embedded ones, e.g. a tweaked index.html, a theme CSS or a logo.
+// The root of fsys corresponds to the static directory;
+// files not found in fsys are served from the embedded assets.
+func WithStaticOverlay(fsys fs.FS) TerminalOption {
+	return func(to *Terminal) {
+		to.staticOverlay = fsys
+	}
+}
+
+// WithTemplate renders the index page with tmpl instead of the embedded one,
+// for a fully customized layout. The template is executed with TemplateData;
+// it takes precedence over the index.html of WithStaticOverlay.
+func WithTemplate(tmpl *template.Template) TerminalOption {
+	return func(to *Terminal) {
+		to.template = tmpl
+	}
+}
+
+// WithHTMLHeader injects an HTML snippet above the terminal,
+// e.g. a logo, a link back to a dashboard or an environment banner.
+// Only the formatting elements, links and images are kept, with their
+// class, id, title and link attributes; the other markup is shown as text,
+// and the content of script, style and frame elements is removed.
+func WithHTMLHeader(header string) TerminalOption {
+	return func(to *Terminal) {
+		to.HTMLHeader = sanitizeHTML(header)
+	}
+}
+
+// WithCustomCSS appends the css to the page stylesheet.
+func WithCustomCSS(css string) TerminalOption {
+	return func(to *Terminal) {
+		to.CustomCSS = sanitizeCSS(css)
+	}
+}
+
+var (
+	unsafeElementsRegexp = regexp.MustCompile(`(?is)<(script|style|iframe|object|embed)\b.*?(</\s*(script|style|iframe|object|embed)\s*>|$)`)
+	htmlTagRegexp        = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9]*)((?:\s+[^\s"'>/=]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'=<>\x60]+))?)*)\s*/?>`)
+	htmlAttrRegexp       = regexp.MustCompile(`([^\s"'>/=]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>\x60]+)))?`)
+)
+
+// headerTags are the elements kept by sanitizeHTML with their attributes,
+// besides the headerAttrs of all elements
+var headerTags = map[string][]string{
+	"a": {"href", "target", "rel"}, "img": {"src", "alt", "width", "height"},
+	"abbr": nil, "b": nil, "br": nil, "code": nil, "div": nil, "em": nil, "hr": nil,
+	"i": nil, "p": nil, "small": nil, "span": nil, "strong": nil, "sub": nil, "sup": nil, "u": nil,
+}
+
+var headerAttrs = []string{"class", "id", "title"}
+
+// sanitizeHTML keeps the elements and attributes of headerTags, with the links
+// to http, https, mailto or relative URLs, and escapes the rest as text.
+// The kept tags are written again from their parsed names and values,
+// so nothing of the input reaches the page unescaped.
+func sanitizeHTML(header string) string {
+	header = unsafeElementsRegexp.ReplaceAllString(header, "")
+	var sb strings.Builder
+	for header != "" {
+		i := strings.IndexByte(header, '<')
+		if i < 0 {
+			i = len(header)
+		}
+		sb.WriteString(html.EscapeString(html.UnescapeString(header[:i])))
+		header = header[i:]
+		if header == "" {
+			break
+		}
+		m := htmlTagRegexp.FindStringSubmatch(header)
+		if m == nil {
+			sb.WriteString("&lt;")
+			header = header[1:]
+			continue
+		}
+		header = header[len(m[0]):]
+		name := strings.ToLower(m[2])
+		attrs, ok := headerTags[name]
+		if !ok {
+			// the element is dropped, its content is kept
+			continue
+		}
+		if m[1] == "/" {
+			sb.WriteString("</" + name + ">")
+			continue
+		}
+		sb.WriteString("<" + name)
+		for _, a := range htmlAttrRegexp.FindAllStringSubmatch(m[3], -1) {
+			key := strings.ToLower(a[1])
+			if !slices.Contains(attrs, key) && !slices.Contains(headerAttrs, key) {
+				continue
+			}
+			value := html.UnescapeString(a[2] + a[3] + a[4])
+			if (key == "href" || key == "src") && !safeURL(value) {
+				continue
+			}
+			sb.WriteString(" " + key + `="` + html.EscapeString(value) + `"`)
+		}
+		sb.WriteString(">")
+	}
+	return sb.String()
+}
+
+// safeURL reports whether the link is relative or to http, https or mailto
+func safeURL(link string) bool {
+	i := strings.IndexAny(link, ":/?#")
+	if i < 0 || link[i] != ':' {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(link[:i])) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func sanitizeCSS(css string) string {
+	// prevent closing the <style> element
+	return strings.ReplaceAll(css, "</", "<\\/")
+}
+
 func WithTail(filename string, opts ...Option) TerminalOption {
 	return WithTailLabel(filepath.Base(filename), filename, opts...)
 }
@@ -305,6 +1588,9 @@ func NewTerminal(opts ...TerminalOption) Terminal {
 	for _, opt := range opts {
 		opt(&to)
 	}
+	if to.tee != nil && to.teeMaxSize > 0 {
+		to.tee.maxSize = to.teeMaxSize
+	}
 	return to
 }
 
@@ -317,6 +1603,8 @@ func DefaultTerminal() Terminal {
 		Scrollback:   5000,
 		DisableStdin: true, // Terminal is read-only
 		closeCh:      make(chan struct{}),
+		closeMessage: new(string),
+		rules:        newRuleRegistry(),
 		Localization: map[string]string{},
 	}
 }
@@ -325,6 +1613,18 @@ func DefaultTerminal() Terminal {
 // and explicitly stop sse sessions.
 // the http server might be blocked on Shutdown()
 // if there are active watchers.
+// The sessions send the lines already read, then end with an "event: shutdown"
+// so that the web terminal reconnects slowly instead of retrying a server going down.
 func (t Terminal) Close() {
+	t.CloseWithMessage("")
+}
+
+// CloseWithMessage closes the terminal like Close, the shutdown event
+// carries the message, e.g. "restarting for an upgrade, back in a minute".
+func (t Terminal) CloseWithMessage(message string) {
+	*t.closeMessage = message
 	close(t.closeCh)
+	if t.tee != nil {
+		t.tee.close()
+	}
 }
@@ -1,13 +1,38 @@
 package tailer
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"testing/fstest"
+	"text/template"
 	"time"
 )
 
@@ -180,6 +205,251 @@ func TestHandler_serveWatcher_MultipleFiles(t *testing.T) {
 	}
 }
 
+// TestHandler_serveWatcher_PerFileColoring tests each file keeps its own coloring profile
+func TestHandler_serveWatcher_PerFileColoring(t *testing.T) {
+	tmpFile1 := createTestFile(t, "colored.log", "ERROR colored\n")
+	tmpFile2 := createTestFile(t, "plain.log", "ERROR plain\n")
+
+	terminal := NewTerminal(
+		WithTailLabel("file1", tmpFile1, WithSyntaxHighlighting("level")),
+		WithTailLabel("file2", tmpFile2),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream?file=file1&file=file2", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	result := rec.Body.String()
+	if !strings.Contains(result, "data: file1 "+ColorRed+"ERROR"+ColorReset+" colored\n") {
+		t.Errorf("Expected colored line from file1, got %q", result)
+	}
+	if !strings.Contains(result, "data: file2 ERROR plain\n") {
+		t.Errorf("Expected plain line from file2, got %q", result)
+	}
+}
+
+// TestHandler_serveWatcher_ColoringParam tests the coloring selected by a client
+func TestHandler_serveWatcher_ColoringParam(t *testing.T) {
+	tmpFile := createTestFile(t, "coloring.log", "ERROR user=bob\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithSyntaxHighlighting("level")),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "data: " + ColorRed + "ERROR" + ColorReset + " user=bob\n"},
+		{"?coloring=none", "data: ERROR user=bob\n"},
+		{"?coloring=slog-text", "data: ERROR " + ColorCyan + "user" + ColorReset + "=" + ColorBlue + "bob" + ColorReset + "\n"},
+		{"?coloring=unknown", "data: " + ColorRed + "ERROR" + ColorReset + " user=bob\n"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/watch.stream"+tt.query, nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req.WithContext(ctx))
+		cancel()
+		if !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%q: expected %q, got %q", tt.query, tt.want, rec.Body.String())
+		}
+	}
+}
+
+// TestHandler_serveWatcher_SinceParam tests the lines replayed for since
+func TestHandler_serveWatcher_SinceParam(t *testing.T) {
+	old := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	recent := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	tmpFile := createTestFile(t, "since.log", old+" INFO old\n"+recent+" INFO recent\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream?since=10m", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+	cancel()
+	if body := rec.Body.String(); !strings.Contains(body, "INFO recent") || strings.Contains(body, "INFO old") {
+		t.Errorf("Expected only the recent line, got %q", body)
+	}
+
+	for _, since := range []string{"yesterday", "-5m"} {
+		req := httptest.NewRequest(http.MethodGet, "/watch.stream?since="+since, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("since=%s: expected status %d, got %d", since, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+// acceptTransport is a transport for the requests accepting text/plain
+type acceptTransport struct{}
+
+func (acceptTransport) Match(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/plain")
+}
+
+func (acceptTransport) ServeTransport(h Handler, w http.ResponseWriter, r *http.Request) {
+	stream := h.OpenStream(w, r)
+	if stream == nil {
+		return
+	}
+	defer stream.Close()
+	if !stream.Start("text/plain") {
+		return
+	}
+	for {
+		line, ok := stream.Next()
+		if !ok {
+			return
+		}
+		fmt.Fprintf(w, "%d %s\n", line.Offset, line.Text)
+		stream.Flush()
+	}
+}
+
+// TestHandler_Transport tests the requests are routed to the transports of WithTransport
+func TestHandler_Transport(t *testing.T) {
+	tmpFile := createTestFile(t, "transport.log", "line 1\nline 2\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+		WithMaxClients(1),
+		WithTransport(acceptTransport{}),
+		WithTransport(NewPathTransport("watch.stream", func(h Handler, w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "replaced")
+		})),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	tests := []struct {
+		path   string
+		accept string
+		want   string
+	}{
+		{"/anything", "text/plain", "0 line 1\n7 line 2\n"},
+		{"/watch.stream", "", "replaced"},
+		{"/stream.ndjson", "", `"line":"line 2"`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req.WithContext(ctx))
+		cancel()
+		if !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.want, rec.Body.String())
+		}
+	}
+
+	// the slot of WithMaxClients is released when the stream is closed
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/plain")
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("Expected a text/plain stream, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+// TestHandler_TransportHistory tests the lines of history archives have no offset in a stream
+func TestHandler_TransportHistory(t *testing.T) {
+	tmpFile := createTestFile(t, "transport.log", "line 1\n")
+	archive := createTestFile(t, "transport.log.1", "12:30:45 archived\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithHistory(archive)),
+		WithTransport(acceptTransport{}),
+	)
+	defer terminal.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/plain")
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	terminal.Handler("/").ServeHTTP(rec, req.WithContext(ctx))
+	if want := "-1 12:30:45 archived\n0 line 1\n"; rec.Body.String() != want {
+		t.Errorf("Expected %q, got %q", want, rec.Body.String())
+	}
+}
+
+// TestHandler_serveWatcher_Binary tests a binary file is refused with an error event
+func TestHandler_serveWatcher_Binary(t *testing.T) {
+	tmpFile := createTestFile(t, "app.bin", "\x7fELF\x02\x01\x01\x00\x00\x00\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if expected := "event: error\ndata: {\"message\":\"not a text file\"}\n\n"; rec.Body.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream.ndjson", nil))
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status %d, got %d", http.StatusUnsupportedMediaType, rec.Code)
+	}
+}
+
+// TestHandler_serveWatcher_SingleFileFilter tests the filter applies to a single file terminal
+func TestHandler_serveWatcher_SingleFileFilter(t *testing.T) {
+	tmpFile := createTestFile(t, "singlefilter.log", "INFO skipped\nERROR kept\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream?filter=ERROR", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	result := rec.Body.String()
+	if strings.Contains(result, "skipped") || !strings.Contains(result, "data: ERROR kept") {
+		t.Errorf("Expected only the matching line, got %q", result)
+	}
+}
+
 // TestHandler_serveWatcher_NoFilesSelected tests error case when no files selected
 func TestHandler_serveWatcher_NoFilesSelected(t *testing.T) {
 	tmpFile1 := createTestFile(t, "noselect1.log", "")
@@ -315,19 +585,20 @@ func TestHandler_serveWatcher_TerminalClose(t *testing.T) {
 	}
 }
 
-// TestHandler_serveWatcher_SSEHeaders tests that proper SSE headers are set
-func TestHandler_serveWatcher_SSEHeaders(t *testing.T) {
-	tmpFile := createTestFile(t, "headers.log", "test\n")
+// TestHandler_serveWatcher_StallDetection tests stall and resume events
+func TestHandler_serveWatcher_StallDetection(t *testing.T) {
+	tmpFile := createTestFile(t, "stall.log", "initial\n")
 
 	terminal := NewTerminal(
-		WithTail(tmpFile),
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+		WithStallDetection(500*time.Millisecond),
 	)
 	defer terminal.Close()
 
 	handler := terminal.Handler("/")
 
 	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
-	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 	req = req.WithContext(ctx)
 
@@ -339,125 +610,2453 @@ func TestHandler_serveWatcher_SSEHeaders(t *testing.T) {
 		close(done)
 	}()
 
+	// the stall is detected on the first flush tick
+	time.Sleep(1300 * time.Millisecond)
+	appendToFile(t, tmpFile, "resumed line\n")
+
 	<-done
 
-	// Check SSE headers
-	expectedHeaders := map[string]string{
-		"Content-Type":                "text/event-stream",
-		"Cache-Control":               "no-cache",
-		"Connection":                  "keep-alive",
-		"Access-Control-Allow-Origin": "*",
+	result := rec.Body.String()
+	stallIdx := strings.Index(result, "event: stall\n")
+	resumeIdx := strings.Index(result, "event: resume\n")
+	lineIdx := strings.Index(result, "data: resumed line\n")
+	if stallIdx < 0 || resumeIdx < 0 || lineIdx < 0 {
+		t.Fatalf("Expected stall, resume and line events, got %q", result)
 	}
-
-	for header, expectedValue := range expectedHeaders {
-		actualValue := rec.Header().Get(header)
-		if actualValue != expectedValue {
-			t.Errorf("Header %s: expected %s, got %s", header, expectedValue, actualValue)
-		}
+	if !(stallIdx < resumeIdx && resumeIdx < lineIdx) {
+		t.Errorf("Expected stall, resume and line in order, got %q", result)
 	}
 }
 
-// TestHandler_serveStatic tests static file serving
-func TestHandler_serveStatic(t *testing.T) {
-	tmpFile := createTestFile(t, "static.log", "test\n")
+// TestHandler_serveWatcher_StallDetectionClock tests the stall and resume events
+// with the time advanced by the test instead of waiting for the stall timeout
+func TestHandler_serveWatcher_StallDetectionClock(t *testing.T) {
+	tmpFile := createTestFile(t, "stall.log", "initial\n")
 
+	clk := newFakeClock()
 	terminal := NewTerminal(
 		WithTail(tmpFile),
+		WithStallDetection(time.Minute),
+		withTerminalClock(clk),
 	)
 	defer terminal.Close()
 
-	handler := terminal.Handler("/app")
+	server := httptest.NewServer(terminal.Handler("/"))
+	defer server.Close()
 
-	tests := []struct {
-		name       string
-		path       string
-		expectCode int
-	}{
-		{
-			name:       "Index page",
-			path:       "/app/",
-			expectCode: http.StatusOK,
-		},
-		{
-			name:       "Static JS file",
-			path:       "/app/xterm.js",
-			expectCode: http.StatusOK,
-		},
-		{
-			name:       "Static CSS file",
-			path:       "/app/xterm.css",
-			expectCode: http.StatusOK,
-		},
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/watch.stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
 	}
+	defer resp.Body.Close()
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
-			rec := httptest.NewRecorder()
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	// waitFor returns the lines of the stream up to the line,
+	// the time is advanced by a flush interval while the stream is idle
+	waitFor := func(want string) []string {
+		t.Helper()
+		var got []string
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("Stream ended before %q, got %q", want, got)
+				}
+				got = append(got, line)
+				if line == want {
+					return got
+				}
+			case <-time.After(20 * time.Millisecond):
+				clk.Advance(time.Second)
+			case <-ctx.Done():
+				t.Fatalf("Timeout waiting for %q, got %q", want, got)
+			}
+		}
+	}
 
-			handler.ServeHTTP(rec, req)
+	// the poll of the tail and the flush of the stream
+	clk.waitTickers(t, 2)
+	got := waitFor("data: initial")
+	if slices.Contains(got, "event: stall") {
+		t.Fatalf("Unexpected stall before the timeout, got %q", got)
+	}
+	clk.Advance(time.Minute)
+	waitFor("event: stall")
 
-			if rec.Code != tt.expectCode {
-				t.Errorf("Expected status %d, got %d", tt.expectCode, rec.Code)
-			}
-		})
+	appendToFile(t, tmpFile, "resumed line\n")
+	got = waitFor("data: resumed line")
+	if !slices.Contains(got, "event: resume") {
+		t.Errorf("Expected a resume event before the line, got %q", got)
 	}
 }
 
-// TestHandler_serveStatic_IndexTemplate tests index page template rendering
-func TestHandler_serveStatic_IndexTemplate(t *testing.T) {
-	tmpFile := createTestFile(t, "index.log", "test\n")
+// TestHandler_serveRange tests reading a byte range as lines
+func TestHandler_serveRange(t *testing.T) {
+	tmpFile1 := createTestFile(t, "range1.log", "first\nsecond\nthird\n")
+	tmpFile2 := createTestFile(t, "range2.log", "other\n")
 
 	terminal := NewTerminal(
-		WithTailLabel("testfile", tmpFile),
-		WithLocalization(map[string]string{"Log Viewer": "Template Test"}),
+		WithTailLabel("file1", tmpFile1),
+		WithTailLabel("file2", tmpFile2),
 	)
 	defer terminal.Close()
 
 	handler := terminal.Handler("/")
 
-	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req := httptest.NewRequest(http.MethodGet, "/watch.range?file=file1&start=2&end=8", nil)
 	rec := httptest.NewRecorder()
-
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", rec.Code)
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var rng Range
+	if err := json.Unmarshal(rec.Body.Bytes(), &rng); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if rng.Start != 6 || rng.End != 13 || len(rng.Lines) != 1 || rng.Lines[0] != "second" {
+		t.Errorf("Unexpected range %+v", rng)
 	}
 
-	body := rec.Body.String()
-
-	// Check that template contains expected content
-	if !strings.Contains(body, "Template Test") {
-		t.Error("Index page should contain terminal title")
+	req = httptest.NewRequest(http.MethodGet, "/watch.range?file=unknown&start=0&end=8", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rec.Code)
 	}
 }
 
-// TestTerminal_Handler tests Handler creation
-func TestTerminal_Handler(t *testing.T) {
-	terminal := NewTerminal()
+// TestHandler_serveSnapshot tests the gzip compressed snapshots of the last lines and of a range
+func TestHandler_serveSnapshot(t *testing.T) {
+	tmpFile := createTestFile(t, "snapshot.log", "INFO first\nERROR second\nINFO third\n")
+
+	terminal := NewTerminal(
+		WithTailLabel("app log", tmpFile, WithSyntaxHighlighting("level")),
+	)
 	defer terminal.Close()
 
-	handler := terminal.Handler("/prefix")
+	handler := terminal.Handler("/")
 
-	if handler.CutPrefix != "/prefix" {
-		t.Errorf("Expected CutPrefix '/prefix', got '%s'", handler.CutPrefix)
+	tests := []struct {
+		query    string
+		expected string
+		filename string
+	}{
+		{"lines=2", "ERROR second\nINFO third\n", `attachment; filename=app_log-\d{8}T\d{6}Z\.log\.gz`},
+		{"", "INFO first\nERROR second\nINFO third\n", `attachment; filename=app_log-.*\.log\.gz`},
+		{"start=2&end=12", "ERROR second\n", `attachment; filename=app_log-11-24\.log\.gz`},
 	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/watch.snapshot?"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
 
-	if handler.fsServer == nil {
-		t.Error("fsServer should not be nil")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d", tt.query, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/gzip" {
+			t.Errorf("%q: unexpected Content-Type %q", tt.query, ct)
+		}
+		if cd := rec.Header().Get("Content-Disposition"); !regexp.MustCompile("^" + tt.filename + "$").MatchString(cd) {
+			t.Errorf("%q: unexpected Content-Disposition %q", tt.query, cd)
+		}
+		gz, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("%q: not gzip: %v", tt.query, err)
+		}
+		b, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatalf("%q: failed to decompress: %v", tt.query, err)
+		}
+		// without the coloring of the tail
+		if string(b) != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.query, tt.expected, b)
+		}
+	}
+
+	for _, query := range []string{"lines=0", "start=5", "start=5&end=2"} {
+		req := httptest.NewRequest(http.MethodGet, "/watch.snapshot?"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", query, rec.Code)
+		}
 	}
 }
 
-// TestTerminal_Close tests terminal close functionality
-func TestTerminal_Close(t *testing.T) {
-	terminal := NewTerminal()
+// TestHandler_serveSnapshotClock tests the snapshot is named after the time of the terminal clock
+func TestHandler_serveSnapshotClock(t *testing.T) {
+	tmpFile := createTestFile(t, "snapshot.log", "INFO first\n")
 
-	// Close should not panic
-	terminal.Close()
+	terminal := NewTerminal(WithTailLabel("app log", tmpFile), withTerminalClock(newFakeClock()))
+	defer terminal.Close()
 
-	// Verify close channel is closed
-	select {
+	rec := httptest.NewRecorder()
+	terminal.Handler("/").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/watch.snapshot", nil))
+	if cd := rec.Header().Get("Content-Disposition"); cd != "attachment; filename=app_log-20261015T120000Z.log.gz" {
+		t.Errorf("Unexpected Content-Disposition %q", cd)
+	}
+}
+
+// TestHandler_serveHistory tests paging backward through a file
+func TestHandler_serveHistory(t *testing.T) {
+	tmpFile := createTestFile(t, "history.log", "first\nsecond\nthird\n")
+
+	terminal := NewTerminal(WithTail(tmpFile))
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	var page struct {
+		Lines []Line `json:"lines"`
+		Next  int64  `json:"next"`
+	}
+	req := httptest.NewRequest(http.MethodGet, "/watch.history?lines=2", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(page.Lines) != 2 || page.Lines[0].Text != "second" || page.Lines[1].Text != "third" || page.Next != 6 {
+		t.Errorf("Unexpected page %+v", page)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/watch.history?lines=2&before=%d", page.Next), nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(page.Lines) != 1 || page.Lines[0].Text != "first" || page.Next != 0 {
+		t.Errorf("Unexpected page %+v", page)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/watch.history?before=-1", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rec.Code)
+	}
+}
+
+// flushRecorder records the body written at the time of the last flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed string
+}
+
+func (fr *flushRecorder) Flush() {
+	fr.flushed = fr.Body.String()
+	fr.ResponseRecorder.Flush()
+}
+
+// TestHandler_serveWatcher_AdaptiveFlush tests lines are flushed immediately at low throughput
+func TestHandler_serveWatcher_AdaptiveFlush(t *testing.T) {
+	tmpFile := createTestFile(t, "adaptive.log", "initial\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+		WithAdaptiveFlush(),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	// shorter than the fixed 1 second flush interval
+	ctx, cancel := context.WithTimeout(context.Background(), 700*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	appendToFile(t, tmpFile, "next line\n")
+
+	<-done
+
+	if !strings.Contains(rec.flushed, "data: next line\n") {
+		t.Errorf("Expected line to be flushed, got %q", rec.flushed)
+	}
+}
+
+// TestHandler_serveWatcher_Multiline tests multiline records are sent as one SSE event
+// flushCounter counts the flushes of the response
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes atomic.Int32
+}
+
+func (fc *flushCounter) Flush() {
+	fc.flushes.Add(1)
+	fc.ResponseRecorder.Flush()
+}
+
+func TestHandler_serveWatcher_FlushOnlyOnData(t *testing.T) {
+	tmpFile := createTestFile(t, "flushdata.log", "")
+
+	for _, tt := range []struct {
+		opts    []TerminalOption
+		flushes int32
+	}{
+		// the headers, then every tick
+		{nil, 4},
+		// the headers, then the tick with the activity event
+		{[]TerminalOption{WithFlushOnlyOnData()}, 2},
+	} {
+		clk := newFakeClock()
+		terminal := NewTerminal(append(tt.opts, WithTail(tmpFile), withTerminalClock(clk))...)
+
+		req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		rec := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			terminal.Handler("/").ServeHTTP(rec, req.WithContext(ctx))
+		}()
+		// the poll of the tail and the flush of the stream
+		clk.waitTickers(t, 2)
+		for range 3 {
+			clk.Advance(time.Second)
+			clk.waitTicked(t)
+		}
+		cancel()
+		<-done
+		terminal.Close()
+
+		if n := rec.flushes.Load(); n != tt.flushes {
+			t.Errorf("Expected %d flushes on an idle log, got %d", tt.flushes, n)
+		}
+		if !strings.Contains(rec.Body.String(), "event: activity") {
+			t.Errorf("Expected the activity event, got %q", rec.Body.String())
+		}
+	}
+}
+
+func TestHandler_serveWatcher_Multiline(t *testing.T) {
+	tmpFile := createTestFile(t, "multiline.log", "ERROR boom\n\tat main.go:10\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile,
+			WithPollInterval(100*time.Millisecond),
+			WithMultilinePattern(regexp.MustCompile(`^[A-Z]+ `)),
+		),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), "data: ERROR boom\ndata: \tat main.go:10\n\n") {
+		t.Errorf("Expected multiline event, got %q", rec.Body.String())
+	}
+}
+
+// TestHandler_serveWatcher_LevelSummary tests the per-level counts event
+func TestHandler_serveWatcher_LevelSummary(t *testing.T) {
+	tmpFile := createTestFile(t, "summary.log", "INFO one\nlevel=WARN two\n\x1b[31mERROR\x1b[0m three\nFATAL four\nno level\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+		WithLevelSummary(time.Minute),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	expect := "event: summary\n" + `data: {"counts":{"DEBUG":0,"ERROR":2,"INFO":1,"TRACE":0,"WARN":1},"window":60}`
+	if !strings.Contains(rec.Body.String(), expect) {
+		t.Errorf("Expected summary event, got %q", rec.Body.String())
+	}
+}
+
+// TestHandler_serveWatcher_Permalinks tests the byte offsets are sent as event ids
+func TestHandler_serveWatcher_EventTypes(t *testing.T) {
+	tmpFile := createTestFile(t, "eventtypes.log", "ERROR disk full\nplain line\nINFO started\nspoof\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond), WithSyntaxHighlighting("level")),
+		WithEventTypeClassifier(func(line string) string {
+			if line == "spoof" {
+				// the event types of the stream itself stay messages
+				return "stall"
+			}
+			return LevelEventType(line)
+		}),
+	)
+	defer terminal.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	terminal.Handler("/").ServeHTTP(rec, req)
+
+	body := StripAnsiCodes(rec.Body.String())
+	for _, expect := range []string{
+		"event: error\ndata: ERROR disk full\n\n",
+		"\n\ndata: plain line\n\n",
+		"event: info\ndata: INFO started\n\n",
+		"\n\ndata: spoof\n\n",
+	} {
+		if !strings.Contains(body, expect) {
+			t.Errorf("Expected %q in the stream, got %q", expect, body)
+		}
+	}
+	if strings.Contains(body, "event: stall") {
+		t.Errorf("Unexpected stall event in the stream, got %q", body)
+	}
+}
+
+func TestHandler_serveWatcher_LiveMode(t *testing.T) {
+	tmpFile := createTestFile(t, "live.log", "sensitive\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond), WithLast(10)),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream?mode=live", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 700*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		f, err := os.OpenFile(tmpFile, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return
+		}
+		fmt.Fprintln(f, "after connect")
+		f.Close()
+	}()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+
+	body := rec.Body.String()
+	if strings.Contains(body, "sensitive") {
+		t.Errorf("Expected no line written before the connection, got %q", body)
+	}
+	if !strings.Contains(body, "data: after connect\n\n") {
+		t.Errorf("Expected the line written after the connection, got %q", body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/watch.stream?mode=replay", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown mode, got %d", rec.Code)
+	}
+}
+
+func TestHandler_JSONLevelField(t *testing.T) {
+	content := `{"level":"info","msg":"one"}` + "\n" +
+		`{"level":"warning","msg":"two"}` + "\n" +
+		`{"level":50,"msg":"three"}` + "\n" +
+		`{"msg":"ERROR four"}` + "\n" +
+		"ERROR five, not JSON\n"
+	tmpFile := createTestFile(t, "json.log", content)
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond), WithJSONLevelField("level")),
+		WithLevelSummary(time.Minute),
+		WithEventTypeClassifier(JSONLevelEventType("level")),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+
+	body := rec.Body.String()
+	for _, expect := range []string{
+		"event: summary\n" + `data: {"counts":{"DEBUG":0,"ERROR":3,"INFO":1,"TRACE":0,"WARN":1},"window":60}`,
+		"event: warn\ndata: " + `{"level":` + ColorYellow + `"warning"` + ColorReset + `,"msg":"two"}`,
+		"event: error\ndata: " + `{"level":` + ColorRed + `50` + ColorReset + `,"msg":"three"}`,
+		"event: error\ndata: ERROR five, not JSON\n",
+	} {
+		if !strings.Contains(body, expect) {
+			t.Errorf("Expected %q in the stream, got %q", expect, body)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/stream.ndjson", nil)
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+
+	var levels []string
+	dec := json.NewDecoder(rec.Body)
+	for {
+		var frame LineFrame
+		if err := dec.Decode(&frame); err != nil {
+			break
+		}
+		levels = append(levels, frame.Level)
+	}
+	if expected := []string{"INFO", "WARN", "ERROR", "ERROR", "ERROR"}; !slices.Equal(levels, expected) {
+		t.Errorf("Expected levels %v, got %v", expected, levels)
+	}
+}
+
+func TestHandler_serveWatcher_Permalinks(t *testing.T) {
+	tmpFile := createTestFile(t, "permalink.log", "first\nsecond 1:2\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+		WithPermalinks(),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	expect := "id: 0\ndata: first\n\nid: 6\ndata: second 1:2\n\n"
+	if !strings.Contains(rec.Body.String(), expect) {
+		t.Errorf("Expected events with ids, got %q", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), "const permalinks = true;") {
+		t.Error("Index page should enable permalinks")
+	}
+}
+
+// TestHandler_serveWatcher_Resume tests a reconnecting stream continues after its last event id
+func TestHandler_serveWatcher_Resume(t *testing.T) {
+	tmpFile := createTestFile(t, "resume.log", "first\nsecond\nthird\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+		WithPermalinks(),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/watch.stream", nil),
+		httptest.NewRequest(http.MethodGet, "/watch.stream?lastEventId=6", nil),
+	} {
+		if req.URL.RawQuery == "" {
+			req.Header.Set("Last-Event-ID", "6")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req.WithContext(ctx))
+		cancel()
+
+		body := rec.Body.String()
+		if strings.Contains(body, "data: first") || strings.Contains(body, "data: second") {
+			t.Errorf("Expected no line up to the last event id, got %q", body)
+		}
+		if !strings.Contains(body, "id: 13\ndata: third\n\n") {
+			t.Errorf("Expected the line after the last event id, got %q", body)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream?lastEventId=abc", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid last event id, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/stream.js", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "class TailerStream") {
+		t.Errorf("Expected stream.js to be served, got %d", rec.Code)
+	}
+}
+
+// TestHandler_serveWatcher_NoFollow tests follow=false sends the last lines and completes
+func TestHandler_serveWatcher_NoFollow(t *testing.T) {
+	tmpFile := createTestFile(t, "nofollow.log", "line 1\nERROR 2\nline 3\nERROR 4\nline 5\n")
+
+	terminal := NewTerminal(WithTail(tmpFile, WithPollInterval(100*time.Millisecond)))
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"follow=false&lines=2", "data: ERROR 4\n\ndata: line 5\n\nevent: complete\ndata: {\"lines\":2}\n\n"},
+		{"follow=false&lines=5&filter=ERROR&coloring=none", "data: ERROR 2\n\ndata: ERROR 4\n\nevent: complete\ndata: {\"lines\":2}\n\n"},
+	}
+	for _, tt := range tests {
+		// the stream ends by itself
+		req := httptest.NewRequest(http.MethodGet, "/watch.stream?"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if body := rec.Body.String(); body != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.query, tt.want, body)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("%s: expected an event stream, got %q", tt.query, ct)
+		}
+	}
+
+	for _, query := range []string{"follow=nope", "follow=false&lines=0"} {
+		req := httptest.NewRequest(http.MethodGet, "/watch.stream?"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+// TestHandler_serveWatcher_LastVisit tests the marker of the last visit resumes after the offset of the page
+func TestHandler_serveWatcher_LastVisit(t *testing.T) {
+	tmpFile := createTestFile(t, "lastvisit.log", "first\nsecond\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+		WithLastVisitMarker(),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream?lastEventId=0", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+
+	body := rec.Body.String()
+	if strings.Contains(body, "data: first") || !strings.Contains(body, "id: 6\ndata: second\n\n") {
+		t.Errorf("Expected the lines after the last visit with their ids, got %q", body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), "const lastVisit = true;") {
+		t.Error("Index page should enable the last visit marker")
+	}
+}
+
+// TestHandler_serveWatcher_ReadyEvent tests the stream starts with the sizes and line counts of the files
+func TestHandler_serveWatcher_ReadyEvent(t *testing.T) {
+	first := createTestFile(t, "first.log", "line 1\nline 2\n")
+	second := createTestFile(t, "second.log", "x\n")
+
+	terminal := NewTerminal(
+		WithTail(first, WithPollInterval(100*time.Millisecond)),
+		WithTail(second, WithPollInterval(100*time.Millisecond)),
+		WithControlBar(ControlBar{Hide: true}),
+		WithReadyEvent(EstimatedLineCount),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+
+	if !strings.HasPrefix(rec.Body.String(), "event: ready\n") {
+		t.Fatalf("Expected the stream to start with the ready event, got %q", rec.Body.String())
+	}
+	files := readyEvents(t, rec.Body.String())[0]
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files, got %+v", files)
+	}
+	for i, tt := range []struct {
+		path  string
+		size  int64
+		lines int64
+	}{{first, 14, 2}, {second, 2, 1}} {
+		stat, err := os.Stat(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := files[i]
+		if file.File != filepath.Base(tt.path) || file.Size != tt.size || file.Lines != tt.lines || file.Estimated {
+			t.Errorf("Unexpected file %+v", file)
+		}
+		if file.Path != absPath(tt.path) || !filepath.IsAbs(file.Path) {
+			t.Errorf("Expected the absolute path %s, got %s", absPath(tt.path), file.Path)
+		}
+		if !file.ModTime.Equal(stat.ModTime()) || file.Inode != inodeOf(stat) {
+			t.Errorf("Expected the mtime %v and the inode %d, got %+v", stat.ModTime(), inodeOf(stat), file)
+		}
+	}
+}
+
+// readyEvents returns the files of the ready events of the stream
+func readyEvents(t *testing.T, body string) [][]FileInfo {
+	t.Helper()
+	var events [][]FileInfo
+	for _, event := range strings.Split(body, "\n\n") {
+		data, ok := strings.CutPrefix(event, "event: ready\ndata: ")
+		if !ok {
+			continue
+		}
+		var ready struct {
+			Files []FileInfo `json:"files"`
+		}
+		if err := json.Unmarshal([]byte(data), &ready); err != nil {
+			t.Fatalf("Invalid ready event %q: %v", data, err)
+		}
+		events = append(events, ready.Files)
+	}
+	return events
+}
+
+// TestHandler_serveWatcher_ReadyEventRotation tests the ready event is sent again
+// with the new file when the file is rotated
+func TestHandler_serveWatcher_ReadyEventRotation(t *testing.T) {
+	tmpFile := createTestFile(t, "rotated.log", "old\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(50*time.Millisecond)),
+		WithReadyEvent(NoLineCount),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		os.Rename(tmpFile, tmpFile+".1")
+		os.WriteFile(tmpFile, []byte("new line\n"), 0644)
+	}()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+
+	events := readyEvents(t, rec.Body.String())
+	if len(events) != 2 {
+		t.Fatalf("Expected the ready event again after the rotation, got %q", rec.Body.String())
+	}
+	if before, after := events[0][0], events[1][0]; before.Size != 4 || after.Size != 9 || after.Path != absPath(tmpFile) {
+		t.Errorf("Expected the new file in the second ready event, got %+v then %+v", before, after)
+	}
+}
+
+// TestHandler_serveWatcher_ReadyEventSwitch tests the ready event follows a tail
+// switching files, its path and profile are read while the tail switches
+func TestHandler_serveWatcher_ReadyEventSwitch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(filepath.Join(dir, "app.1.log"), []byte("line 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	terminal := NewTerminal(
+		WithTail(path, WithPollInterval(20*time.Millisecond), WithRotationPattern(NumberedScheme(HighestIsLive)), WithAutoColoring()),
+		WithReadyEvent(NoLineCount),
+		// the ready events are checked on the flushes every 200ms
+		WithAdaptiveFlush(),
+	)
+	defer terminal.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	go func() {
+		// switch on every poll around the flushes of the ready events
+		for i := 2; i <= 50; i++ {
+			time.Sleep(20 * time.Millisecond)
+			os.WriteFile(filepath.Join(dir, fmt.Sprintf("app.%d.log", i)), []byte(`{"level":"INFO"}`+"\n"), 0644)
+		}
+	}()
+	terminal.Handler("/").ServeHTTP(rec, req.WithContext(ctx))
+
+	events := readyEvents(t, rec.Body.String())
+	if len(events) < 2 {
+		t.Fatalf("Expected the ready event again after the switches, got %q", rec.Body.String())
+	}
+	last := events[len(events)-1][0]
+	if last.Path != absPath(filepath.Join(dir, "app.50.log")) || last.File != "app.log" {
+		t.Errorf("Expected the last file in the last ready event, got %+v", last)
+	}
+}
+
+// TestHandler_serveWatcher_ReadyEventColoring tests the ready event reports the profile of WithAutoColoring
+func TestHandler_serveWatcher_ReadyEventColoring(t *testing.T) {
+	tmpFile := createTestFile(t, "app.json", `{"level":"INFO","msg":"started"}`+"\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithAutoColoring()),
+		WithReadyEvent(NoLineCount),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+
+	if files := readyEvents(t, rec.Body.String()); len(files) != 1 || len(files[0]) != 1 ||
+		files[0][0].File != "app.json" || files[0][0].Size != 33 || files[0][0].Coloring != "json" {
+		t.Errorf("Expected the json coloring of app.json, got %q", rec.Body.String())
+	}
+}
+
+// TestHandler_DisconnectNoLeak tests the clients disconnecting with lines still
+// pending release their tails and goroutines, for the streams and the subscriptions
+func TestHandler_DisconnectNoLeak(t *testing.T) {
+	var content strings.Builder
+	for i := 0; i < 4000; i++ {
+		fmt.Fprintf(&content, "line %d %s\n", i, strings.Repeat("x", 1000))
+	}
+	first := createTestFile(t, "leak1.log", content.String())
+	second := createTestFile(t, "leak2.log", content.String())
+
+	// the last lines of both files overflow the channel of the multi-file tail
+	terminal := NewTerminal(
+		WithTail(first, WithBufferSize(5000), WithLast(4000)),
+		WithTail(second, WithBufferSize(5000), WithLast(4000)),
+	)
+	defer terminal.Close()
+	server := httptest.NewServer(terminal.Handler("/"))
+	defer server.Close()
+
+	// settled samples the goroutines until they are back to at most n
+	settled := func(n int) int {
+		var now int
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			if now = runtime.NumGoroutine(); now <= n {
+				break
+			}
+		}
+		return now
+	}
+	before := runtime.NumGoroutine()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/watch.stream?file=leak1.log&file=leak2.log", nil)
+			rsp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Errorf("Failed to connect: %v", err)
+				return
+			}
+			defer rsp.Body.Close()
+			// read a little, then go away with the rest of the lines pending
+			io.ReadFull(rsp.Body, make([]byte, 256))
+		}()
+	}
+	wg.Wait()
+	http.DefaultClient.CloseIdleConnections()
+	if after := settled(before); after > before {
+		t.Errorf("Expected the goroutines of the streams released, %d before, %d after", before, after)
+	}
+
+	// a subscriber going away without reading
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		if _, _, err := terminal.Subscribe(ctx, SubscribeBuffer(1)); err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}
+	if after := settled(before); after > before {
+		t.Errorf("Expected the goroutines of the subscriptions released, %d before, %d after", before, after)
+	}
+}
+
+// TestHandler_MaxClients tests the streams beyond the limit are rejected while the others continue
+func TestHandler_MaxClients(t *testing.T) {
+	tmpFile := createTestFile(t, "clients.log", "line\n")
+
+	const maxClients = 3
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+		WithMaxClients(maxClients),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	recs := make([]*httptest.ResponseRecorder, maxClients)
+	for i := range recs {
+		recs[i] = httptest.NewRecorder()
+		path := "/watch.stream"
+		if i == 0 {
+			path = "/stream.ndjson"
+		}
+		req := httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(recs[i], req)
+		}()
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	for _, path := range []string{"/watch.stream", "/stream.ndjson"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503 for %s beyond the limit, got %d", path, rec.Code)
+		}
+		if rec.Header().Get("Retry-After") == "" {
+			t.Errorf("Expected a Retry-After header for %s", path)
+		}
+	}
+	// the page and its assets are not limited
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for the index page, got %d", rec.Code)
+	}
+
+	cancel()
+	wg.Wait()
+	for i, rec := range recs {
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "line") {
+			t.Errorf("Expected client %d within the limit to be streamed, got %d %q", i, rec.Code, rec.Body.String())
+		}
+	}
+
+	// the slots of the closed streams are free again
+	reqCtx, reqCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer reqCancel()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/watch.stream", nil).WithContext(reqCtx))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after the streams closed, got %d", rec.Code)
+	}
+}
+
+// TestHandler_PerTailOptions tests the buffer size and poll interval of each tail
+// override the handler defaults for that tail only
+func TestHandler_PerTailOptions(t *testing.T) {
+	chatty := createTestFile(t, "chatty.log", "")
+	quiet := createTestFile(t, "quiet.log", "")
+
+	terminal := NewTerminal(
+		WithTail(chatty, WithBufferSize(2000), WithPollInterval(50*time.Millisecond)),
+		WithTail(quiet, WithBufferSize(3)),
+		WithControlBar(ControlBar{Hide: true}),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	rec := httptest.NewRecorder()
+	tail, _, stop := handler.startTail(rec, httptest.NewRequest(http.MethodGet, "/watch.stream", nil), false)
+	if tail == nil {
+		t.Fatalf("Failed to start the tails: %d %s", rec.Code, rec.Body.String())
+	}
+	defer stop()
+
+	mt := tail.(*MultiTail)
+	tails := []*Tail{mt.tails[0].(*Tail), mt.tails[1].(*Tail)}
+	if cap(tails[0].c) != 2000 || tails[0].pollInterval != 50*time.Millisecond {
+		t.Errorf("Expected the chatty tail with a buffer of 2000 polled every 50ms, got %d every %v", cap(tails[0].c), tails[0].pollInterval)
+	}
+	if cap(tails[1].c) != 3 || tails[1].pollInterval != 500*time.Millisecond {
+		t.Errorf("Expected the quiet tail with a buffer of 3 polled every 500ms, got %d every %v", cap(tails[1].c), tails[1].pollInterval)
+	}
+	if cap(mt.c) != 2000 {
+		t.Errorf("Expected the merged buffer of the largest tail, got %d", cap(mt.c))
+	}
+}
+
+// TestHandler_ResponseHeaders tests the proxy headers of the streams and their overrides
+func TestHandler_ResponseHeaders(t *testing.T) {
+	tmpFile := createTestFile(t, "headers.log", "line\n")
+
+	serve := func(handler Handler, path string) http.Header {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+		return rec.Header()
+	}
+
+	terminal := NewTerminal(WithTail(tmpFile, WithPollInterval(100*time.Millisecond)))
+	defer terminal.Close()
+	for _, path := range []string{"/watch.stream", "/stream.ndjson"} {
+		if got := serve(terminal.Handler("/"), path).Get("X-Accel-Buffering"); got != "no" {
+			t.Errorf("Expected X-Accel-Buffering: no for %s by default, got %q", path, got)
+		}
+	}
+
+	custom := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+		WithResponseHeaders(map[string]string{
+			"Cache-Control":     "no-cache, no-transform",
+			"X-Accel-Buffering": "",
+			"X-Proxy":           "stream",
+		}),
+	)
+	defer custom.Close()
+	header := serve(custom.Handler("/"), "/watch.stream")
+	if got := header.Get("Cache-Control"); got != "no-cache, no-transform" {
+		t.Errorf("Expected the Cache-Control replaced, got %q", got)
+	}
+	if _, ok := header["X-Accel-Buffering"]; ok {
+		t.Errorf("Expected X-Accel-Buffering removed, got %q", header.Get("X-Accel-Buffering"))
+	}
+	if got := header.Get("X-Proxy"); got != "stream" {
+		t.Errorf("Expected the X-Proxy header, got %q", got)
+	}
+	if got := header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Expected the Content-Type kept, got %q", got)
+	}
+	if got := serve(custom.Handler("/"), "/").Get("X-Proxy"); got != "" {
+		t.Errorf("Expected the page without the stream headers, got %q", got)
+	}
+}
+
+// TestHandler_serveWatcher_Tee tests the streamed lines are mirrored into a rotating file
+func TestHandler_serveWatcher_Tee(t *testing.T) {
+	tmpFile := createTestFile(t, "teesrc.log", "first line\nsecond line\n")
+	teeFile := filepath.Join(t.TempDir(), "tee.log")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond), WithPattern("line")),
+		WithTee(teeFile),
+		// each record exceeds half of the size
+		WithTeeMaxSize(80),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	rotated, err := os.ReadFile(teeFile + ".1")
+	if err != nil {
+		t.Fatalf("Expected rotated tee file: %v", err)
+	}
+	current, err := os.ReadFile(teeFile)
+	if err != nil {
+		t.Fatalf("Expected tee file: %v", err)
+	}
+	if !strings.HasSuffix(string(rotated), "] first line\n") || !strings.Contains(string(rotated), req.RemoteAddr) {
+		t.Errorf("Unexpected rotated tee content %q", rotated)
+	}
+	if !strings.HasSuffix(string(current), "] second line\n") {
+		t.Errorf("Unexpected tee content %q", current)
+	}
+}
+
+// TestHandler_serveWatcher_MaxDisplayWidth tests long lines are truncated in the stream only
+func TestHandler_serveWatcher_MaxDisplayWidth(t *testing.T) {
+	tmpFile := createTestFile(t, "wide.log", "ERROR "+strings.Repeat("x", 100)+"\nshort\n")
+	teeFile := filepath.Join(t.TempDir(), "tee.log")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond), WithSyntaxHighlighting("level")),
+		WithMaxDisplayWidth(10),
+		WithTee(teeFile),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "data: "+ColorRed+"ERROR"+ColorReset+" xxx…"+ColorReset+"\n\n") {
+		t.Errorf("Expected truncated line, got %q", body)
+	}
+	if !strings.Contains(body, "data: short\n\n") {
+		t.Errorf("Expected short line unchanged, got %q", body)
+	}
+	tee, _ := os.ReadFile(teeFile)
+	if !strings.Contains(string(tee), strings.Repeat("x", 100)) {
+		t.Errorf("Expected the full line in the tee file, got %q", tee)
+	}
+}
+
+// TestHandler_serveNDJSON tests the lines are streamed as newline-delimited JSON
+func TestHandler_serveNDJSON(t *testing.T) {
+	tmpFile := createTestFile(t, "ndjson.log", "INFO started\nERROR failed\nDEBUG noise\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/stream.ndjson?filter=INFO||ERROR", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Expected application/x-ndjson, got %s", ct)
+	}
+	var frames []LineFrame
+	dec := json.NewDecoder(rec.Body)
+	for dec.More() {
+		var frame LineFrame
+		if err := dec.Decode(&frame); err != nil {
+			t.Fatalf("Invalid frame: %v", err)
+		}
+		frames = append(frames, frame)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("Expected 2 frames, got %+v", frames)
+	}
+	if frames[0].Line != "INFO started" || frames[0].Level != LevelInfo || frames[0].Offset == nil || *frames[0].Offset != 0 {
+		t.Errorf("Unexpected first frame %+v", frames[0])
+	}
+	if frames[1].Line != "ERROR failed" || frames[1].Level != LevelError || frames[1].Offset == nil || *frames[1].Offset != 13 {
+		t.Errorf("Unexpected second frame %+v", frames[1])
+	}
+	if frames[0].Time.IsZero() {
+		t.Error("Expected the receive time in the frame")
+	}
+}
+
+// TestHandler_serveText tests the lines are streamed as plain text with their colors
+func TestHandler_serveText(t *testing.T) {
+	tmpFile := createTestFile(t, "text.log", "INFO started\nERROR failed\nDEBUG noise\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/stream.txt?filter=INFO||ERROR&coloring=levels", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Expected text/plain, got %s", ct)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "data:") {
+		t.Errorf("Expected no SSE framing, got %q", body)
+	}
+	if !strings.Contains(body, "\x1b[") {
+		t.Errorf("Expected the color codes of the coloring, got %q", body)
+	}
+	if expected := "INFO started\nERROR failed\n"; StripAnsiCodes(body) != expected {
+		t.Errorf("Expected %q, got %q", expected, StripAnsiCodes(body))
+	}
+}
+
+// TestHandler_serveNDJSON_TraceID tests the frames carry the trace ID of their line
+func TestHandler_serveNDJSON_TraceID(t *testing.T) {
+	tmpFile := createTestFile(t, "trace.log", `{"msg":"start","trace_id":"4bf92f35"}`+"\n"+
+		"INFO trace=77aa01 done\n"+
+		"INFO no trace\n")
+
+	tests := []struct {
+		name   string
+		option TerminalOption
+		want   []string
+	}{
+		{"field", WithTraceIDField("trace_id"), []string{"4bf92f35", "", ""}},
+		{"pattern", WithTraceIDPattern(`trace=([0-9a-f]+)`), []string{"", "77aa01", ""}},
+		{"invalid", WithTraceIDPattern(`trace=(`), []string{"", "", ""}},
+	}
+	for _, tt := range tests {
+		terminal := NewTerminal(WithTail(tmpFile), tt.option)
+		handler := terminal.Handler("/")
+
+		req := httptest.NewRequest(http.MethodGet, "/stream.ndjson", nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req.WithContext(ctx))
+		cancel()
+		terminal.Close()
+
+		var got []string
+		dec := json.NewDecoder(rec.Body)
+		for dec.More() {
+			var frame LineFrame
+			if err := dec.Decode(&frame); err != nil {
+				t.Fatalf("%s: invalid frame: %v", tt.name, err)
+			}
+			got = append(got, frame.TraceID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected trace IDs %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+// TestTerminal_UpdateTail tests the rules are swapped in the running streams
+func TestTerminal_UpdateTail(t *testing.T) {
+	tmpFile := createTestFile(t, "rules.log", "INFO db ready\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(50*time.Millisecond), WithPattern("INFO")),
+	)
+	defer terminal.Close()
+
+	if err := terminal.UpdateTail("unknown.log", WithPattern("x")); err == nil {
+		t.Error("Expected an error for an unknown tail")
+	}
+
+	server := httptest.NewServer(terminal.Handler("/"))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/watch.stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	next := func() string {
+		named := false
+		for scanner.Scan() {
+			text := scanner.Text()
+			switch {
+			case strings.HasPrefix(text, "event: "):
+				// named events are not lines
+				named = true
+			case text == "":
+				named = false
+			case !named && strings.HasPrefix(text, "data: "):
+				return strings.TrimPrefix(text, "data: ")
+			}
+		}
+		t.Fatalf("Stream ended: %v", scanner.Err())
+		return ""
+	}
+	if line := next(); line != "INFO db ready" {
+		t.Fatalf("Expected the initial line, got %q", line)
+	}
+
+	if err := terminal.UpdateTail("rules.log", WithPattern("ERROR"), WithSyntaxHighlighting("level")); err != nil {
+		t.Fatalf("UpdateTail failed: %v", err)
+	}
+	appendToFile(t, tmpFile, "INFO db slow\nERROR db down\n")
+
+	// the lines of the old pattern are dropped
+	if line := next(); line != ColorRed+"ERROR"+ColorReset+" db down" {
+		t.Errorf("Expected the line matching the new rules, got %q", line)
+	}
+}
+
+// TestTerminal_AdminHandler tests pausing and resuming a tail of the running streams
+func TestTerminal_AdminHandler(t *testing.T) {
+	tmpFile := createTestFile(t, "admin.log", "")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(50*time.Millisecond)),
+	)
+	defer terminal.Close()
+	admin := terminal.AdminHandler()
+
+	lines, cancel, err := terminal.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	post := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		return rec
+	}
+	if rec := post("/admin/pause?file=unknown.log"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown tail, got %d", rec.Code)
+	}
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/pause", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", rec.Code)
+	}
+
+	rec = post("/admin/pause?file=admin.log")
+	var states []TailState
+	if err := json.Unmarshal(rec.Body.Bytes(), &states); err != nil || len(states) != 1 || !states[0].Paused {
+		t.Fatalf("Expected the tail paused, got %q: %v", rec.Body.String(), err)
+	}
+	appendToFile(t, tmpFile, "written while paused\n")
+	select {
+	case line := <-lines:
+		t.Fatalf("Expected no line while paused, got %q", line.Text)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	if !terminal.TailPaused("admin.log") {
+		t.Error("Expected the tail to be reported paused")
+	}
+
+	post("/admin/resume")
+	select {
+	case line := <-lines:
+		if line.Text != "written while paused" {
+			t.Errorf("Expected the line written while paused, got %q", line.Text)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the line after resume")
+	}
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/tails", nil))
+	if expected := `[{"alias":"admin.log","paused":false}]`; strings.TrimSpace(rec.Body.String()) != expected {
+		t.Errorf("Expected %s, got %s", expected, rec.Body.String())
+	}
+}
+
+// TestHandler_serveWatcher_Activity tests the activity of the file is sent on the first tick
+func TestHandler_serveWatcher_Activity(t *testing.T) {
+	tmpFile := createTestFile(t, "activity.log", "line\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+		WithAdaptiveFlush(),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	// the initial line was just read
+	if n := strings.Count(rec.Body.String(), "event: activity\ndata: {\"active\":true}\n\n"); n != 1 {
+		t.Errorf("Expected one activity event, got %d in %q", n, rec.Body.String())
+	}
+}
+
+// TestHandler_serveWatcher_SSEHeaders tests that proper SSE headers are set
+func TestHandler_serveWatcher_SSEHeaders(t *testing.T) {
+	tmpFile := createTestFile(t, "headers.log", "test\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	<-done
+
+	// Check SSE headers
+	expectedHeaders := map[string]string{
+		"Content-Type":                "text/event-stream",
+		"Cache-Control":               "no-cache",
+		"Connection":                  "keep-alive",
+		"Access-Control-Allow-Origin": "*",
+	}
+
+	for header, expectedValue := range expectedHeaders {
+		actualValue := rec.Header().Get(header)
+		if actualValue != expectedValue {
+			t.Errorf("Header %s: expected %s, got %s", header, expectedValue, actualValue)
+		}
+	}
+}
+
+// TestHandler_serveWatcher_FlushNotSupported tests the streams fail clearly
+// behind a ResponseWriter that can not be flushed
+func TestHandler_serveWatcher_FlushNotSupported(t *testing.T) {
+	tmpFile := createTestFile(t, "noflush.log", "test\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	var logs strings.Builder
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	for _, path := range []string{"/watch.stream", "/stream.ndjson"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		// hides the Flush of the recorder, like a middleware without Unwrap
+		handler.ServeHTTP(struct{ http.ResponseWriter }{rec}, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("%s: expected status 500, got %d", path, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), "Streaming is not supported") {
+			t.Errorf("%s: expected a streaming error, got %q", path, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+			t.Errorf("%s: expected a text/plain error, got %s", path, ct)
+		}
+		if !strings.Contains(logs.String(), path) {
+			t.Errorf("%s: expected the error to be logged, got %q", path, logs.String())
+		}
+	}
+}
+
+// TestHandler_serveStatic tests static file serving
+func TestHandler_serveStatic(t *testing.T) {
+	tmpFile := createTestFile(t, "static.log", "test\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/app")
+
+	tests := []struct {
+		name       string
+		path       string
+		expectCode int
+	}{
+		{
+			name:       "Index page",
+			path:       "/app/",
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "Static JS file",
+			path:       "/app/xterm.js",
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "Static CSS file",
+			path:       "/app/xterm.css",
+			expectCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectCode {
+				t.Errorf("Expected status %d, got %d", tt.expectCode, rec.Code)
+			}
+		})
+	}
+}
+
+// TestHandler_serveStatic_IndexTemplate tests index page template rendering
+func TestHandler_serveStatic_IndexTemplate(t *testing.T) {
+	tmpFile := createTestFile(t, "index.log", "test\n")
+
+	terminal := NewTerminal(
+		WithTailLabel("testfile", tmpFile),
+		WithLocalization(map[string]string{"Log Viewer": "Template Test"}),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+
+	// Check that template contains expected content
+	if !strings.Contains(body, "Template Test") {
+		t.Error("Index page should contain terminal title")
+	}
+}
+
+// indexPage returns the index page served by a terminal of a test file
+func indexPage(t *testing.T) string {
+	t.Helper()
+	terminal := NewTerminal(WithTail(createTestFile(t, "index.log", "test\n")))
+	defer terminal.Close()
+
+	rec := httptest.NewRecorder()
+	terminal.Handler("/").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+// TestHandler_serveStatic_HelpOverlay tests the index page has the keyboard shortcuts help
+func TestHandler_serveStatic_HelpOverlay(t *testing.T) {
+	if !strings.Contains(indexPage(t), `id="help-overlay"`) {
+		t.Error("Index page should contain the keyboard shortcuts help")
+	}
+}
+
+// TestHandler_serveStatic_ErrorsToggle tests the index page has the errors only toggle
+func TestHandler_serveStatic_ErrorsToggle(t *testing.T) {
+	if !strings.Contains(indexPage(t), `id="errors-btn"`) {
+		t.Error("Index page should contain the errors only toggle")
+	}
+}
+
+// TestHandler_serveStatic_RangeBar tests the index page has the range selection bar
+func TestHandler_serveStatic_RangeBar(t *testing.T) {
+	if !strings.Contains(indexPage(t), `id="range-bar"`) {
+		t.Error("Index page should contain the range selection bar")
+	}
+}
+
+// writeTestCert writes a self-signed certificate valid for 127.0.0.1
+// as both server and client, and returns the PEM files.
+func writeTestCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tailer test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+// TestListenAndServeTLS_ClientCA tests mutual TLS and the stream over HTTP/2
+func TestListenAndServeTLS_ClientCA(t *testing.T) {
+	tmpFile := createTestFile(t, "tls.log", "tls line\n")
+	certFile, keyFile := writeTestCert(t)
+
+	terminal := NewTerminal(WithTail(tmpFile, WithPollInterval(100*time.Millisecond)))
+	defer terminal.Close()
+
+	server, err := newTLSServer("127.0.0.1:0", &terminal, WithClientCA(certFile))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	// the rejected handshake is expected
+	server.ErrorLog = log.New(io.Discard, "", 0)
+	go server.ServeTLS(ln, certFile, keyFile)
+	defer server.Close()
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load key pair: %v", err)
+	}
+	certPEM, _ := os.ReadFile(certFile)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	url := "https://" + ln.Addr().String() + "/watch.stream"
+
+	// without a client certificate the handshake fails
+	anonymous := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}}
+	if resp, err := anonymous.Get(url); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected the request without a client certificate to fail")
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}},
+		ForceAttemptHTTP2: true,
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2, got %s", resp.Proto)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if scanner.Text() == "data: tls line" {
+			return
+		}
+	}
+	t.Fatalf("Expected streamed line over TLS: %v", scanner.Err())
+}
+
+// TestListen tests the errors of binding an address are actionable
+func TestListen(t *testing.T) {
+	ln, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	_, err = Listen(ln.Addr().String())
+	if err == nil {
+		t.Error("Expected an address in use error")
+	} else if runtime.GOOS != "windows" && (!errors.Is(err, syscall.EADDRINUSE) || !strings.Contains(err.Error(), "already in use")) {
+		// Windows reports WSAEADDRINUSE, a generic error
+		t.Errorf("Expected an address in use error, got %v", err)
+	}
+	for _, addr := range []string{"8080", "127.0.0.1:http-x", "127.0.0.1:70000"} {
+		if _, err := Listen(addr); err == nil || !strings.HasPrefix(err.Error(), "invalid ") {
+			t.Errorf("%q: expected an invalid address error, got %v", addr, err)
+		}
+	}
+	if _, err := Listen(":0", WithInterface("no-such-if0")); err == nil || !strings.Contains(err.Error(), `unknown network interface "no-such-if0"`) {
+		t.Errorf("Expected an unknown interface error, got %v", err)
+	}
+
+	// the loopback interface by name
+	ifaces, _ := net.Interfaces()
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback == 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		ln, err := Listen(":0", WithInterface(iface.Name))
+		if err != nil {
+			t.Fatalf("Failed to listen on %s: %v", iface.Name, err)
+		}
+		defer ln.Close()
+		if ip := ln.Addr().(*net.TCPAddr).IP; !ip.IsLoopback() {
+			t.Errorf("Expected to listen on the loopback address of %s, got %s", iface.Name, ip)
+		}
+		break
+	}
+}
+
+// TestHandler_serveStatic_Preload tests the index page sends preload hints for the xterm assets
+func TestHandler_serveStatic_Preload(t *testing.T) {
+	tmpFile := createTestFile(t, "preload.log", "test\n")
+
+	terminal := NewTerminal(WithTail(tmpFile))
+	defer terminal.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle("/logs/", terminal.Handler("/logs/"))
+
+	req := httptest.NewRequest(http.MethodGet, "/logs/", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	links := rec.Header().Values("Link")
+	for _, expected := range []string{
+		"</logs/xterm.css>; rel=preload; as=style",
+		"</logs/xterm.js>; rel=preload; as=script",
+	} {
+		if !slices.Contains(links, expected) {
+			t.Errorf("Expected Link header %q, got %v", expected, links)
+		}
+	}
+
+	// static files do not carry the hints
+	req = httptest.NewRequest(http.MethodGet, "/logs/xterm.css", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if len(rec.Header().Values("Link")) != 0 {
+		t.Errorf("Unexpected Link header on static file: %v", rec.Header().Values("Link"))
+	}
+}
+
+// TestHandler_serveWatcher_HTTP2 tests the stream is flushed over HTTP/2
+func TestHandler_serveWatcher_HTTP2(t *testing.T) {
+	tmpFile := createTestFile(t, "h2.log", "h2 line\n")
+
+	terminal := NewTerminal(WithTail(tmpFile, WithPollInterval(100*time.Millisecond)))
+	defer terminal.Close()
+
+	server := httptest.NewUnstartedServer(terminal.Handler("/"))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/watch.stream", nil)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("Expected HTTP/2, got %s", resp.Proto)
+	}
+	if resp.Header.Get("Connection") != "" {
+		t.Errorf("Unexpected Connection header over HTTP/2: %q", resp.Header.Get("Connection"))
+	}
+	// the line arrives while the stream is still open
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if scanner.Text() == "data: h2 line" {
+			return
+		}
+	}
+	t.Fatalf("Expected streamed line over HTTP/2: %v", scanner.Err())
+}
+
+// TestHandler_serveStatic_BreakOnMatch tests the break pattern is embedded into the page
+func TestHandler_serveStatic_BreakOnMatch(t *testing.T) {
+	tmpFile := createTestFile(t, "break.log", "test\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+		WithBreakOnMatch(`ERROR|</script>`),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `let breakPattern = "ERROR|\u003c/script\u003e";`) {
+		t.Error("Index page should contain the escaped break pattern")
+	}
+}
+
+// TestHandler_serveStatic_TraceID tests the trace ID pattern is embedded into the page
+func TestHandler_serveStatic_TraceID(t *testing.T) {
+	tmpFile := createTestFile(t, "trace.log", "test\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+		WithTraceIDField("trace_id"),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), `const tracePattern = "\"trace_id\"\\s*:\\s*\"([^\"\\\\]+)\"";`) {
+		t.Error("Index page should contain the trace ID pattern")
+	}
+}
+
+// TestHandler_serveStatic_LevelLegend tests the legend lists the configured levels in order
+func TestHandler_serveStatic_LevelLegend(t *testing.T) {
+	tmpFile := createTestFile(t, "legend.log", "test\n")
+
+	for _, tt := range []struct {
+		opts     []TerminalOption
+		expected []string
+	}{
+		{nil, nil},
+		{[]TerminalOption{WithLevelLegend()}, []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR"}},
+		{[]TerminalOption{WithLevelLegend("error", "warn", "bogus", "ERROR")}, []string{"ERROR", "WARN"}},
+	} {
+		terminal := NewTerminal(append([]TerminalOption{WithTail(tmpFile)}, tt.opts...)...)
+		rec := httptest.NewRecorder()
+		terminal.Handler("/").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		terminal.Close()
+
+		var levels []string
+		for _, m := range regexp.MustCompile(`<button class="level-\w+" data-level="(\w+)"`).FindAllStringSubmatch(rec.Body.String(), -1) {
+			levels = append(levels, m[1])
+		}
+		if !slices.Equal(levels, tt.expected) {
+			t.Errorf("Expected the legend %q, got %q", tt.expected, levels)
+		}
+	}
+}
+
+// TestHandler_serveStatic_Accessible tests the live region and the screen reader mode
+func TestHandler_serveStatic_Accessible(t *testing.T) {
+	tmpFile := createTestFile(t, "a11y.log", "test\n")
+
+	for _, tt := range []struct {
+		opts       []TerminalOption
+		politeness string
+	}{
+		{nil, ""},
+		{[]TerminalOption{WithAccessibleMode()}, "polite"},
+		{[]TerminalOption{WithAnnouncePoliteness("assertive")}, "assertive"},
+		{[]TerminalOption{WithAnnouncePoliteness("rude")}, ""},
+	} {
+		terminal := NewTerminal(append([]TerminalOption{WithTail(tmpFile)}, tt.opts...)...)
+		rec := httptest.NewRecorder()
+		terminal.Handler("/").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		terminal.Close()
+
+		body := rec.Body.String()
+		if !strings.Contains(body, `<label for="filter-input" class="sr-only">`) {
+			t.Error("The filter should be labeled")
+		}
+		region := regexp.MustCompile(`id="a11y-live" class="sr-only" role="log" aria-live="(\w+)"`).FindStringSubmatch(body)
+		if tt.politeness == "" {
+			if region != nil || strings.Contains(body, `"screenReaderMode"`) || strings.Contains(body, `id="pause-btn"`) {
+				t.Errorf("Expected no accessible mode, got the region %q", region)
+			}
+			continue
+		}
+		if region == nil || region[1] != tt.politeness {
+			t.Errorf("Expected a %s live region, got %q", tt.politeness, region)
+		}
+		if !strings.Contains(body, `"screenReaderMode": true`) {
+			t.Error("Expected the screen reader mode of xterm.js")
+		}
+		if !strings.Contains(body, `id="pause-btn"`) {
+			t.Error("Expected a pause button")
+		}
+	}
+}
+
+// TestHandler_serveStatic_Collapsed tests the page starts collapsed without connecting
+func TestHandler_serveStatic_Collapsed(t *testing.T) {
+	tmpFile := createTestFile(t, "collapsed.log", "test\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+		WithCollapsed(),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `<div id="container" class="collapsed">`) {
+		t.Error("Index page should start collapsed")
+	}
+	if !strings.Contains(body, `id="collapse-btn"`) {
+		t.Error("Index page should contain the expand button")
+	}
+}
+
+// TestHandler_serveStatic_InitialScroll tests the initial scroll position is injected in the page
+func TestHandler_serveStatic_InitialScroll(t *testing.T) {
+	tmpFile := createTestFile(t, "scroll.log", "test\n")
+
+	for _, tt := range []struct {
+		opts     []TerminalOption
+		expected string
+	}{
+		{nil, "bottom"},
+		{[]TerminalOption{WithInitialScroll(ScrollTop)}, "top"},
+		{[]TerminalOption{WithInitialScroll(ScrollBottom)}, "bottom"},
+	} {
+		terminal := NewTerminal(append([]TerminalOption{WithTail(tmpFile)}, tt.opts...)...)
+		rec := httptest.NewRecorder()
+		terminal.Handler("/").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		terminal.Close()
+
+		if expected := fmt.Sprintf("const initialScroll = '%s';", tt.expected); !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("Expected %q in the page", expected)
+		}
+	}
+}
+
+// TestHandler_serveStatic_ScrollToFirstError tests the scroll to the first error is injected in the page
+func TestHandler_serveStatic_ScrollToFirstError(t *testing.T) {
+	tmpFile := createTestFile(t, "scroll.log", "test\n")
+
+	for _, tt := range []struct {
+		opts     []TerminalOption
+		expected bool
+	}{
+		{nil, false},
+		{[]TerminalOption{WithScrollToFirstError()}, true},
+		// the initial scroll applies when the replay has no error
+		{[]TerminalOption{WithScrollToFirstError(), WithInitialScroll(ScrollTop)}, true},
+	} {
+		terminal := NewTerminal(append([]TerminalOption{WithTail(tmpFile)}, tt.opts...)...)
+		rec := httptest.NewRecorder()
+		terminal.Handler("/").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		terminal.Close()
+
+		if expected := fmt.Sprintf("const scrollToFirstError = %v;", tt.expected); !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("Expected %q in the page", expected)
+		}
+	}
+}
+
+// TestHandler_serveStatic_CustomHeader tests the custom header and css are injected sanitized
+func TestHandler_serveStatic_CustomHeader(t *testing.T) {
+	tmpFile := createTestFile(t, "header.log", "test\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+		WithHTMLHeader(`<div class="env" onclick="alert(1)">PRODUCTION</div><script>alert(1)</script><a href="javascript:alert(1)">x</a>`),
+		WithCustomCSS(`.env { color: red; } </style><script>alert(1)</script>`),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `<div class="env">PRODUCTION</div><a>x</a>`) {
+		t.Error("Index page should contain the sanitized header")
+	}
+	if !strings.Contains(body, `.env { color: red; }`) {
+		t.Error("Index page should contain the custom css")
+	}
+	if strings.Contains(body, "alert(1)</script>") || strings.Contains(body, "onclick") {
+		t.Error("Index page should not contain unsanitized content")
+	}
+}
+
+// TestSanitizeHTML tests only the allowed elements and attributes of the header are kept
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{`<b>PRODUCTION</b>`, `<b>PRODUCTION</b>`},
+		{`<a href="https://example.com/?a=1&amp;b=2" target=_blank>home</a>`, `<a href="https://example.com/?a=1&amp;b=2" target="_blank">home</a>`},
+		{`<img src="/logo.png" alt='logo'/>`, `<img src="/logo.png" alt="logo">`},
+		{`<svg/onload=alert(1)>`, `&lt;svg/onload=alert(1)&gt;`},
+		{`<img/src=x/onerror=alert(1)>`, `&lt;img/src=x/onerror=alert(1)&gt;`},
+		{`<img src=x onerror=alert(1)>`, `<img src="x">`},
+		{`<svg onload="alert(1)"><b>x</b></svg>`, `<b>x</b>`},
+		{`<a href="java&#x09;script:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href=" JavaScript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<div title="a&quot; onmouseover=&quot;alert(1)">x</div>`, `<div title="a&#34; onmouseover=&#34;alert(1)">x</div>`},
+		{`<iframe src="https://example.com"></iframe>ok`, `ok`},
+		{`1 < 2 & 3 > 2`, `1 &lt; 2 &amp; 3 &gt; 2`},
+	}
+	for _, tt := range tests {
+		if got := sanitizeHTML(tt.header); got != tt.expected {
+			t.Errorf("sanitizeHTML(%q): expected %q, got %q", tt.header, tt.expected, got)
+		}
+	}
+}
+
+// TestHandler_serveStatic_Overlay tests overridden assets take precedence over the embedded ones
+func TestHandler_serveStatic_Overlay(t *testing.T) {
+	tmpFile := createTestFile(t, "overlay.log", "test\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+		WithLocalization(map[string]string{"Log Viewer": "Overlay Test"}),
+		WithStaticOverlay(fstest.MapFS{
+			"index.html": &fstest.MapFile{Data: []byte(`<title>{{ .Localize "Log Viewer" }}</title>`)},
+			"xterm.css":  &fstest.MapFile{Data: []byte(`/* custom */`)},
+			"logo.svg":   &fstest.MapFile{Data: []byte(`<svg></svg>`)},
+		}),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/app")
+
+	tests := []struct {
+		path   string
+		expect string
+	}{
+		{"/app/", "<title>Overlay Test</title>"},
+		{"/app/xterm.css", "/* custom */"},
+		{"/app/logo.svg", "<svg></svg>"},
+		{"/app/xterm.js", "Terminal"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d", tt.path, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), tt.expect) {
+			t.Errorf("%s: expected body to contain %q", tt.path, tt.expect)
+		}
+	}
+}
+
+// TestHandler_serveStatic_Template tests the index page is rendered with the custom template
+func TestHandler_serveStatic_Template(t *testing.T) {
+	tmpFile1 := createTestFile(t, "template1.log", "test\n")
+	tmpFile2 := createTestFile(t, "template2.log", "test\n")
+
+	tmpl := template.Must(template.New("custom").Parse(
+		`<main data-stream="{{ .StreamURL }}" data-font-size="{{ .Terminal.FontSize }}" data-bg="{{ .Terminal.Theme.Background }}">` +
+			`{{ range .Files }}<a>{{ . }}</a>{{ end }}</main>`))
+	terminal := NewTerminal(
+		WithTail(tmpFile1),
+		WithTail(tmpFile2),
+		WithFontSize(16),
+		WithTheme(ThemeDracula),
+		WithTemplate(tmpl),
+		// the custom template takes precedence over the overlay one
+		WithStaticOverlay(fstest.MapFS{
+			"index.html": &fstest.MapFile{Data: []byte(`overlay`)},
+		}),
+	)
+	defer terminal.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	terminal.Handler("/").ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	expected := `<main data-stream="watch.stream" data-font-size="16" data-bg="` + ThemeDracula.Background + `">` +
+		`<a>template1.log</a><a>template2.log</a></main>`
+	if body := rec.Body.String(); body != expected {
+		t.Errorf("Expected body %q, got %q", expected, body)
+	}
+}
+
+// TestHandler_PathTraversal tests paths escaping the static assets and unknown files are rejected
+func TestHandler_PathTraversal(t *testing.T) {
+	tmpFile := createTestFile(t, "traversal.log", "test\n")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	overlay := filepath.Join(dir, "static")
+	if err := os.Mkdir(overlay, 0755); err != nil {
+		t.Fatalf("Failed to create overlay: %v", err)
+	}
+
+	terminal := NewTerminal(
+		WithTail(tmpFile),
+		WithTail(createTestFile(t, "other.log", "other\n")),
+		WithStaticOverlay(os.DirFS(overlay)),
+	)
+	defer terminal.Close()
+
+	handler := terminal.Handler("/app")
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/app/../secret.txt", http.StatusBadRequest},
+		{"/app/../../etc/passwd", http.StatusBadRequest},
+		{"/app/%2e%2e/%2e%2e/etc/passwd", http.StatusBadRequest},
+		{"/app/static/../../watcher.go", http.StatusBadRequest},
+		{"/app/..%5c..%5csecret.txt", http.StatusBadRequest},
+		{"/app//etc/passwd", http.StatusBadRequest},
+		{"/app/C:/secret.txt", http.StatusBadRequest},
+		{"/app/secret.txt", http.StatusNotFound},
+		// only the configured files can be streamed
+		{"/app/watch.stream?file=../../etc/passwd", http.StatusBadRequest},
+		{"/app/watch.stream?file=" + url.QueryEscape(tmpFile), http.StatusBadRequest},
+		{"/app/xterm.css", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, rec.Code)
+		}
+		if strings.Contains(rec.Body.String(), "secret") || strings.Contains(rec.Body.String(), "root:") {
+			t.Errorf("%s: unexpected file contents served", tt.path)
+		}
+	}
+}
+
+// TestHandler_BasePath tests serving under a nested prefix with a base path
+func TestHandler_BasePath(t *testing.T) {
+	tmpFile := createTestFile(t, "basepath.log", "base line\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+		WithBasePath("/admin/logs"),
+	)
+	defer terminal.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle("/admin/logs/", terminal.Handler("/admin/logs/"))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/logs/", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `<base href="/admin/logs/">`) {
+		t.Error("Index page should contain the base href")
+	}
+	if !strings.Contains(body, `new URL("watch.stream", document.baseURI)`) {
+		t.Error("Index page should resolve the stream URL against the base href")
+	}
+
+	// the stream URL resolved against the base href
+	req = httptest.NewRequest(http.MethodGet, "/admin/logs/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req = req.WithContext(ctx)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected event stream, got %s", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "data: base line") {
+		t.Errorf("Expected streamed line, got %q", rec.Body.String())
+	}
+
+	// the assets resolved against the base href
+	req = httptest.NewRequest(http.MethodGet, "/admin/logs/xterm.js", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for asset, got %d", rec.Code)
+	}
+}
+
+// TestHandler_StreamURL tests the page resolves the stream against itself or the URL of WithStreamURL
+func TestHandler_StreamURL(t *testing.T) {
+	tmpFile := createTestFile(t, "streamurl.log", "line\n")
+
+	tests := []struct {
+		opts     []TerminalOption
+		want     string
+		redirect bool // the page moves to its directory when served without the trailing slash
+	}{
+		{nil, `new URL("watch.stream", document.baseURI)`, true},
+		{[]TerminalOption{WithStreamURL("/internal/logs/watch.stream")}, `new URL("/internal/logs/watch.stream", document.baseURI)`, true},
+		{[]TerminalOption{WithBasePath("/logs/")}, `new URL("watch.stream", document.baseURI)`, false},
+	}
+	for i, tt := range tests {
+		terminal := NewTerminal(append([]TerminalOption{WithTail(tmpFile)}, tt.opts...)...)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		terminal.Handler("/").ServeHTTP(rec, req)
+		terminal.Close()
+
+		body := rec.Body.String()
+		if !strings.Contains(body, tt.want) {
+			t.Errorf("%d: index page should contain %q", i, tt.want)
+		}
+		if redirect := strings.Contains(body, "location.replace(location.pathname + '/'"); redirect != tt.redirect {
+			t.Errorf("%d: expected the redirect to the directory %v, got %v", i, tt.redirect, redirect)
+		}
+	}
+}
+
+// TestHandler_MinimalUI tests the compact page has the viewport without the chrome
+func TestHandler_MinimalUI(t *testing.T) {
+	tmpFile := createTestFile(t, "minimal.log", "line\n")
+
+	for _, minimal := range []bool{false, true} {
+		opts := []TerminalOption{WithTail(tmpFile), WithHTMLHeader("<b>PRODUCTION</b>")}
+		if minimal {
+			opts = append(opts, WithMinimalUI())
+		}
+		terminal := NewTerminal(opts...)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		terminal.Handler("/").ServeHTTP(rec, req)
+		terminal.Close()
+
+		body := rec.Body.String()
+		if !strings.Contains(body, `<div id="terminal"`) {
+			t.Errorf("minimal %v: index page should contain the terminal", minimal)
+		}
+		for _, chrome := range []string{`<div id="filter-bar">`, `<div id="html-header">`} {
+			if strings.Contains(body, chrome) == minimal {
+				t.Errorf("minimal %v: unexpected presence of %q", minimal, chrome)
+			}
+		}
+		if strings.Contains(body, `<body class="minimal">`) != minimal {
+			t.Errorf("minimal %v: unexpected body class", minimal)
+		}
+	}
+}
+
+func TestHandler_DensityGutter(t *testing.T) {
+	tmpFile := createTestFile(t, "density.log", "line\n")
+
+	for _, density := range []bool{false, true} {
+		opts := []TerminalOption{WithTail(tmpFile), WithMinimalUI()}
+		if density {
+			opts = append(opts, WithDensityGutter())
+		}
+		terminal := NewTerminal(opts...)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		terminal.Handler("/").ServeHTTP(rec, req)
+		terminal.Close()
+
+		if strings.Contains(rec.Body.String(), `<canvas id="density-gutter"`) != density {
+			t.Errorf("density %v: unexpected presence of the gutter", density)
+		}
+	}
+}
+
+// TestTerminal_Handler tests Handler creation
+func TestTerminal_Handler(t *testing.T) {
+	terminal := NewTerminal()
+	defer terminal.Close()
+
+	handler := terminal.Handler("/prefix")
+
+	if handler.CutPrefix != "/prefix" {
+		t.Errorf("Expected CutPrefix '/prefix', got '%s'", handler.CutPrefix)
+	}
+
+	if handler.fsServer == nil {
+		t.Error("fsServer should not be nil")
+	}
+}
+
+// TestNewTerminalFromConfig tests the terminal options of a JSON config
+func TestNewTerminalFromConfig(t *testing.T) {
+	tmpFile := createTestFile(t, "config.log", "INFO ready\nDEBUG noise\n")
+
+	config := fmt.Sprintf(`{
+		"fontSize": 16,
+		"theme": "dracula",
+		"tails": [
+			{"path": %q, "label": "app", "coloring": ["level"], "patterns": [["INFO"]]}
+		]
+	}`, tmpFile)
+	terminal, err := NewTerminalFromConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	defer terminal.Close()
+
+	if terminal.FontSize != 16 || terminal.Theme != ThemeDracula {
+		t.Errorf("Expected font size 16 and the dracula theme, got %d and %+v", terminal.FontSize, terminal.Theme)
+	}
+	if len(terminal.tails) != 1 || terminal.tails[0].Alias != "app" || terminal.tails[0].Filename != tmpFile {
+		t.Fatalf("Unexpected tails %+v", terminal.tails)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	terminal.Handler("/").ServeHTTP(rec, req.WithContext(ctx))
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "data: "+ColorGreen+"INFO"+ColorReset+" ready") {
+		t.Errorf("Expected the colored INFO line, got %q", body)
+	}
+	if strings.Contains(body, "noise") {
+		t.Errorf("Expected the DEBUG line to be filtered, got %q", body)
+	}
+
+	for _, tt := range []struct {
+		config string
+		err    string
+	}{
+		{`{"theme": "neon", "tails": [{"path": "a.log"}]}`, `unknown theme "neon"`},
+		{`{"tails": [{"path": "a.log", "coloring": ["rainbow"]}]}`, `tails[0]: unknown coloring "rainbow"`},
+		{`{"tails": [{"path": "a.log", "patterns": [["("]]}]}`, `tails[0]: invalid pattern "("`},
+		{`{"tails": [{"label": "a"}]}`, `tails[0]: missing path`},
+		{`{"tails": []}`, `no tails configured`},
+		{`{"fontName": "mono", "tails": [{"path": "a.log"}]}`, `unknown field "fontName"`},
+	} {
+		if _, err := NewTerminalFromConfig(strings.NewReader(tt.config)); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: expected error %q, got %v", tt.config, tt.err, err)
+		}
+	}
+}
+
+// TestTerminal_Close tests terminal close functionality
+// TestHandler_serveWatcher_Shutdown tests the streams end with the shutdown event on close,
+// after the lines already read
+func TestHandler_serveWatcher_Shutdown(t *testing.T) {
+	tmpFile := createTestFile(t, "shutdown.log", "line 1\nline 2\n")
+
+	terminal := NewTerminal(
+		WithTail(tmpFile, WithPollInterval(100*time.Millisecond)),
+	)
+	handler := terminal.Handler("/")
+
+	recs := []*httptest.ResponseRecorder{httptest.NewRecorder(), httptest.NewRecorder()}
+	var wg sync.WaitGroup
+	for i, path := range []string{"/watch.stream", "/stream.ndjson"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(recs[i], httptest.NewRequest(http.MethodGet, path, nil))
+		}()
+	}
+	time.Sleep(300 * time.Millisecond)
+	terminal.CloseWithMessage("upgrading")
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the streams to end")
+	}
+
+	body := recs[0].Body.String()
+	shutdown := "event: shutdown\ndata: {\"message\":\"upgrading\"}\n\n"
+	if !strings.HasSuffix(body, shutdown) {
+		t.Errorf("Expected the stream to end with %q, got %q", shutdown, body)
+	}
+	if !strings.Contains(body, "data: line 2\n\n") {
+		t.Errorf("Expected the lines before the shutdown event, got %q", body)
+	}
+	if !strings.Contains(recs[1].Body.String(), `"line":"line 2"`) {
+		t.Errorf("Expected the NDJSON lines, got %q", recs[1].Body.String())
+	}
+
+	// the lines buffered by a tail are passed on without waiting for more
+	tail := New(tmpFile).(*Tail)
+	tail.c <- "buffered 1"
+	tail.c <- "buffered 2"
+	var drained []string
+	drain(tail, func(line string) { drained = append(drained, line) })
+	if expected := []string{"buffered 1", "buffered 2"}; !slices.Equal(drained, expected) {
+		t.Errorf("Expected drained lines %v, got %v", expected, drained)
+	}
+}
+
+func TestTerminal_Close(t *testing.T) {
+	terminal := NewTerminal()
+
+	// Close should not panic
+	terminal.Close()
+
+	// Verify close channel is closed
+	select {
 	case <-terminal.closeCh:
 		// Success - channel is closed
 	default:
@@ -467,6 +3066,210 @@ func TestTerminal_Close(t *testing.T) {
 
 // Helper functions
 
+func TestTerminal_Subscribe(t *testing.T) {
+	fileA := createTestFile(t, "a.log", "a 1\n")
+	fileB := createTestFile(t, "b.log", "b 1\n")
+	terminal := NewTerminal(
+		WithTail(fileA, WithPollInterval(50*time.Millisecond)),
+		WithTail(fileB, WithPollInterval(50*time.Millisecond)),
+	)
+
+	if _, _, err := terminal.Subscribe(context.Background(), SubscribeTo("c.log")); err == nil {
+		t.Error("Expected an error for an unknown tail")
+	}
+
+	lines, cancel, err := terminal.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	receive := func() Line {
+		t.Helper()
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for a line")
+		}
+		return Line{}
+	}
+	got := map[string]Line{}
+	for range 2 {
+		line := receive()
+		got[line.File] = line
+	}
+	if got["a.log"] != (Line{Offset: 0, Text: "a 1", File: "a.log"}) || got["b.log"] != (Line{Offset: 0, Text: "b 1", File: "b.log"}) {
+		t.Errorf("Unexpected last lines: %+v", got)
+	}
+
+	// the rules of UpdateTail apply to the subscriptions
+	if err := terminal.UpdateTail("a.log", WithPattern("keep")); err != nil {
+		t.Fatalf("Failed to update tail: %v", err)
+	}
+	appendToFile(t, fileA, "a drop\na keep\n")
+	if line := receive(); line != (Line{Offset: 11, Text: "a keep", File: "a.log"}) {
+		t.Errorf("Unexpected line: %+v", line)
+	}
+
+	cancel()
+	cancel()
+	select {
+	case _, ok := <-lines:
+		if ok {
+			t.Error("Expected no more lines after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the channel to be closed")
+	}
+
+	t.Run("drop when full", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		lines, _, err := terminal.Subscribe(ctx, SubscribeTo("b.log"), SubscribeBuffer(2), SubscribeDropWhenFull())
+		if err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+		appendToFile(t, fileB, "b 2\nb 3\nb 4\nb 5\n")
+		time.Sleep(300 * time.Millisecond)
+		cancel()
+		var texts []string
+		for line := range lines {
+			texts = append(texts, line.Text)
+		}
+		if !slices.Equal(texts, []string{"b 1", "b 2"}) {
+			t.Errorf("Expected the lines fitting in the buffer, got %q", texts)
+		}
+	})
+}
+
+// TestTerminal_SubscribeHistory tests the lines of history archives have no offset
+func TestTerminal_SubscribeHistory(t *testing.T) {
+	file := createTestFile(t, "app.log", "line 1\n")
+	archive := createTestFile(t, "app.log.1", "12:30:45 archived\n")
+	terminal := NewTerminal(WithTail(file, WithPollInterval(50*time.Millisecond), WithHistory(archive)))
+
+	lines, cancel, err := terminal.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer cancel()
+	expected := []Line{
+		{Offset: -1, Text: "12:30:45 archived", File: "app.log"},
+		{Offset: 0, Text: "line 1", File: "app.log"},
+	}
+	for _, exp := range expected {
+		select {
+		case line := <-lines:
+			if line != exp {
+				t.Errorf("Expected %+v, got %+v", exp, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for %+v", exp)
+		}
+	}
+}
+
+// TestMultiplexer tests the index page and the routing of the terminals
+func TestMultiplexer(t *testing.T) {
+	apiFile := createTestFile(t, "api.log", "api line\n")
+	workerFile := createTestFile(t, "worker.log", "worker line\n")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(workerFile, old, old); err != nil {
+		t.Fatalf("Failed to set the modification time: %v", err)
+	}
+
+	mux := NewMultiplexer()
+	defer mux.Close()
+	if err := mux.Add("api", NewTerminal(WithTail(apiFile, WithPollInterval(100*time.Millisecond)))); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := mux.Add("worker", NewTerminal(WithTail(workerFile, WithPollInterval(100*time.Millisecond)))); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := mux.Add("api", NewTerminal()); err == nil {
+		t.Error("Expected an error for a duplicate name")
+	}
+	if err := mux.Add("../etc", NewTerminal()); err == nil {
+		t.Error("Expected an error for an invalid name")
+	}
+
+	serveMux := http.NewServeMux()
+	serveMux.Handle("/logs/", mux.Handler("/logs/"))
+
+	// the index lists the terminals with their activity
+	req := httptest.NewRequest(http.MethodGet, "/logs/", nil)
+	rec := httptest.NewRecorder()
+	serveMux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`<a href="api/">api</a>`, `<a href="worker/">worker</a>`, "api.log", "worker.log"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Index page should contain %q", want)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/logs/terminals.json", nil)
+	rec = httptest.NewRecorder()
+	serveMux.ServeHTTP(rec, req)
+	var entries []MuxEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("Failed to decode the status: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name != "api" || !entries[0].Active || entries[1].Name != "worker" || entries[1].Active {
+		t.Errorf("Unexpected status %+v", entries)
+	}
+
+	// a terminal without the trailing slash is redirected to its directory
+	req = httptest.NewRequest(http.MethodGet, "/logs/api", nil)
+	rec = httptest.NewRecorder()
+	serveMux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/logs/api/" {
+		t.Errorf("Expected a redirect to /logs/api/, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/logs/unknown/", nil)
+	rec = httptest.NewRecorder()
+	serveMux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown terminal, got %d", rec.Code)
+	}
+
+	// each terminal streams its own file
+	req = httptest.NewRequest(http.MethodGet, "/logs/worker/watch.stream", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	rec = httptest.NewRecorder()
+	serveMux.ServeHTTP(rec, req.WithContext(ctx))
+	if got := rec.Body.String(); !strings.Contains(got, "data: worker line") || strings.Contains(got, "api line") {
+		t.Errorf("Expected the worker line only, got %q", got)
+	}
+}
+
+// TestMultiplexer_BasePath tests the base paths of the terminals behind a proxy
+func TestMultiplexer_BasePath(t *testing.T) {
+	mux := NewMultiplexer()
+	defer mux.Close()
+	mux.BasePath = "/portal"
+	if err := mux.Add("api", NewTerminal(WithTail(createTestFile(t, "api.log", "line\n")))); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	handler := mux.Handler("/")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), `<base href="/portal/">`) {
+		t.Error("Index page should contain the base href of the multiplexer")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), `<base href="/portal/api/">`) {
+		t.Error("Terminal page should contain the base href of the terminal")
+	}
+}
+
 func createTestFile(t *testing.T, name, content string) string {
 	t.Helper()
 	tmpDir := t.TempDir()